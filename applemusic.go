@@ -0,0 +1,223 @@
+// Apple Music API
+// With a MusicKit developer token (a JWT signed with a key from the Apple Developer
+// portal), catalog tracks are resolved through the Apple Music API instead of iTunes
+// Search: exact store IDs, canonical music.apple.com links, full-size artwork and the
+// genre, composer and release year Music leaves out for some streams. The "applemusic"
+// artwork provider uses the same catalog for album covers. A user token is optional and
+// only sent along, for storefront-specific results.
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	// appleMusicAPI - Apple Music API base URL
+	appleMusicAPI = "https://api.music.apple.com/v1"
+
+	// appleMusicArtworkSize - Pixel size substituted into artwork URL templates
+	appleMusicArtworkSize = "600"
+)
+
+// ProviderAppleMusic names the Apple Music API artwork provider
+const ProviderAppleMusic = "applemusic"
+
+// AppleMusicConfig enables the Apple Music API
+type AppleMusicConfig struct {
+	DeveloperToken string `json:"developer_token"` // MusicKit JWT; empty uses iTunes Search only
+	UserToken      string `json:"user_token"`      // Optional Music-User-Token
+	Storefront     string `json:"storefront"`      // e.g. "jp"; defaults to itunes.country, then "us"
+}
+
+// appleMusic holds the settings used by every Apple Music API request; Reload swaps them
+// under requests already running (nil until configured)
+var appleMusic atomic.Pointer[AppleMusicConfig]
+
+// appleMusicBreaker pauses Apple Music API requests during an outage or a rejected token
+var appleMusicBreaker = &circuitBreaker{name: "Apple Music API"}
+
+// ConfigureAppleMusic sets the token and storefront for subsequent requests
+func ConfigureAppleMusic(cfg AppleMusicConfig, store ITunesConfig) {
+	cfg.Storefront = strings.ToLower(cmp.Or(cfg.Storefront, store.Country, "us"))
+	appleMusic.Store(&cfg)
+}
+
+// appleMusicEnabled reports whether a developer token is configured
+func appleMusicEnabled() bool {
+	cfg := appleMusic.Load()
+	return cfg != nil && cfg.DeveloperToken != ""
+}
+
+// appleMusicArtwork is the artwork object of a catalog resource
+type appleMusicArtwork struct {
+	URL string `json:"url"` // template with {w} and {h}
+}
+
+// sized fills the artwork URL template ("" if there's no artwork)
+func (a appleMusicArtwork) sized() string {
+	if a.URL == "" {
+		return ""
+	}
+	return strings.NewReplacer("{w}", appleMusicArtworkSize, "{h}", appleMusicArtworkSize).Replace(a.URL)
+}
+
+// appleMusicSearch is the subset of the catalog search response we use
+type appleMusicSearch struct {
+	Results struct {
+		Songs struct {
+			Data []struct {
+				ID         string `json:"id"`
+				Attributes struct {
+					Name         string            `json:"name"`
+					ArtistName   string            `json:"artistName"`
+					AlbumName    string            `json:"albumName"`
+					ComposerName string            `json:"composerName"`
+					GenreNames   []string          `json:"genreNames"`
+					ReleaseDate  string            `json:"releaseDate"` // "2006-01-02", or just the year
+					URL          string            `json:"url"`
+					Artwork      appleMusicArtwork `json:"artwork"`
+					Rating       string            `json:"contentRating"` // "explicit", "clean" or absent
+				} `json:"attributes"`
+			} `json:"data"`
+		} `json:"songs"`
+		Albums struct {
+			Data []struct {
+				ID         string `json:"id"`
+				Attributes struct {
+					Name       string            `json:"name"`
+					ArtistName string            `json:"artistName"`
+					URL        string            `json:"url"`
+					Artwork    appleMusicArtwork `json:"artwork"`
+				} `json:"attributes"`
+			} `json:"data"`
+		} `json:"albums"`
+	} `json:"results"`
+}
+
+// searchAppleMusic runs a catalog search for one resource type ("songs" or "albums")
+func searchAppleMusic(ctx context.Context, term, types string) (*appleMusicSearch, error) {
+	if err := appleMusicBreaker.allow(); err != nil {
+		return nil, err
+	}
+	cfg := appleMusic.Load()
+	if cfg == nil {
+		cfg = &AppleMusicConfig{}
+	}
+
+	params := url.Values{}
+	params.Set("term", term)
+	params.Set("types", types)
+	params.Set("limit", fmt.Sprint(catalogSearchLimit))
+	requestURL := fmt.Sprintf("%s/catalog/%s/search?%s", appleMusicAPI, url.PathEscape(cfg.Storefront), params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+cfg.DeveloperToken)
+	if cfg.UserToken != "" {
+		req.Header.Set("Music-User-Token", cfg.UserToken)
+	}
+
+	resp, err := lookupClient.Do(req)
+	if err != nil {
+		appleMusicBreaker.record(0, err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+	appleMusicBreaker.record(resp.StatusCode, nil)
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return nil, errors.New("Apple Music API rejected the developer token (expired?)")
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("Apple Music API status %d", resp.StatusCode)
+	}
+
+	var result appleMusicSearch
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// resolveAppleMusicTrack finds the exact catalog song for a track
+func resolveAppleMusicTrack(ctx context.Context, track *Track) (*CatalogMatch, error) {
+	return searchByArtists(track, func(artist string) (*CatalogMatch, error) {
+		return searchAppleMusicTrack(ctx, track, artist)
+	})
+}
+
+// searchAppleMusicTrack looks for the exact catalog song for a track, searching by artist
+func searchAppleMusicTrack(ctx context.Context, track *Track, artist string) (*CatalogMatch, error) {
+	result, err := searchAppleMusic(ctx, artist+" "+track.Name, "songs")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, song := range result.Results.Songs.Data {
+		attrs := song.Attributes
+		if !strings.EqualFold(attrs.Name, track.Name) ||
+			!creditMatches(artist, attrs.ArtistName) ||
+			!strings.EqualFold(attrs.AlbumName, track.Album) {
+			continue
+		}
+
+		storeID, _ := strconv.ParseInt(song.ID, 10, 64)
+		match := &CatalogMatch{
+			StoreID:    storeID,
+			ArtworkURL: attrs.Artwork.sized(),
+			TrackURL:   attrs.URL,
+			Composer:   attrs.ComposerName,
+			Explicit:   attrs.Rating == "explicit",
+		}
+		for _, genre := range attrs.GenreNames {
+			if genre != "Music" { // every song also lists the catch-all "Music"
+				match.Genre = genre
+				break
+			}
+		}
+		if len(attrs.ReleaseDate) >= 4 {
+			match.Year, _ = strconv.Atoi(attrs.ReleaseDate[:4])
+		}
+		return match, nil
+	}
+
+	return nil, fmt.Errorf("%w for %s - %s", errNoCatalogMatch, track.Artist, track.Name)
+}
+
+// appleMusicProvider looks album covers up in the Apple Music catalog
+type appleMusicProvider struct{}
+
+// Name identifies the provider
+func (appleMusicProvider) Name() string { return "Apple Music" }
+
+// FetchArtwork accepts only an album with exactly the same name (and artist, if given)
+func (appleMusicProvider) FetchArtwork(ctx context.Context, artist, album string) (ArtworkResult, error) {
+	result, err := searchAppleMusic(ctx, strings.TrimSpace(artist+" "+album), "albums")
+	if err != nil {
+		return ArtworkResult{}, err
+	}
+
+	for _, candidate := range result.Results.Albums.Data {
+		attrs := candidate.Attributes
+		if !strings.EqualFold(attrs.Name, album) || (artist != "" && !strings.EqualFold(attrs.ArtistName, artist)) {
+			continue
+		}
+		if url := attrs.Artwork.sized(); url != "" {
+			id, _ := strconv.ParseInt(candidate.ID, 10, 64)
+			return ArtworkResult{ArtworkURL: url, AlbumURL: attrs.URL, CollectionID: id, Score: 1}, nil
+		}
+	}
+	return ArtworkResult{}, errNoArtwork
+}
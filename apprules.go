@@ -0,0 +1,90 @@
+// Per-genre and per-playlist Discord applications
+// Discord titles the activity with the application's name ("Listening to Jazz"), so
+// discord.app_rules can pick one of discord.profiles by genre or playlist. Switching
+// application means a fresh handshake, done when a track needs a different one.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+)
+
+// AppRule selects a Discord profile for matching tracks
+type AppRule struct {
+	Genres    []string `json:"genres"`    // Case-insensitive genre names
+	Playlists []string `json:"playlists"` // Case-insensitive playlist globs, e.g. "Lo-Fi*"
+	Profile   string   `json:"profile"`   // Entry of discord.profiles to use
+}
+
+// Matches reports whether the track's genre or playlist is listed
+func (r AppRule) Matches(track *Track) bool {
+	if track.Genre != "" && slices.ContainsFunc(r.Genres, func(genre string) bool {
+		return strings.EqualFold(genre, track.Genre)
+	}) {
+		return true
+	}
+	if track.Playlist == "" {
+		return false
+	}
+	return slices.ContainsFunc(r.Playlists, func(pattern string) bool {
+		re, err := compileGlob(pattern)
+		return err == nil && re.MatchString(track.Playlist)
+	})
+}
+
+// validateAppRules checks that each rule matches something and names a known profile
+func (d DiscordConfig) validateAppRules() error {
+	for i, rule := range d.AppRules {
+		if len(rule.Genres) == 0 && len(rule.Playlists) == 0 {
+			return fmt.Errorf("discord.app_rules[%d] needs genres or playlists", i)
+		}
+		if _, ok := d.Profiles[rule.Profile]; !ok {
+			return fmt.Errorf("discord.app_rules[%d]: unknown Discord profile %q", i, rule.Profile)
+		}
+		for _, pattern := range rule.Playlists {
+			if _, err := compileGlob(pattern); err != nil {
+				return fmt.Errorf("discord.app_rules[%d] playlist %q: %w", i, pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// AppIDFor returns the application to show a track under: the first matching rule's
+// profile, otherwise the active one
+func (d DiscordConfig) AppIDFor(track *Track) (appID, profile string) {
+	for _, rule := range d.AppRules {
+		if rule.Matches(track) {
+			return d.Profiles[rule.Profile], rule.Profile
+		}
+	}
+	appID, _ = d.ActiveAppID() // checked by Validate
+	return appID, d.Profile
+}
+
+// useAppFor re-handshakes as the application the track calls for. Call with b.mu held
+// while connected; on failure the bridge is left disconnected and the next poll retries.
+func (b *Bridge) useAppFor(track *Track) error {
+	appID, profile := b.config.Discord.AppIDFor(track)
+	if appID == b.client.ClientID() {
+		return nil
+	}
+
+	b.presence.Reset()
+	b.client.ClearActivity(b.ctx)
+	b.client.Logout()
+	b.client.SetClientID(appID)
+	if err := b.client.Login(); err != nil {
+		b.connected = false
+		return fmt.Errorf("failed to switch Discord application: %w", err)
+	}
+
+	if profile == "" {
+		profile = "default"
+	}
+	log.Printf("🔀 Showing %s under Discord profile %q (%s)", track.Name, profile, appID)
+	return nil
+}
@@ -0,0 +1,343 @@
+// Artwork providers
+// Album covers are looked up through an ordered chain of providers; the first hit wins.
+// Each provider keeps its own cache, including misses, so an album one service lacks
+// isn't searched there again.
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// errNoArtwork means a provider answered but has no cover for the album
+var errNoArtwork = errors.New("no artwork found")
+
+// Artwork provider names
+const (
+	ProviderITunes = "itunes"
+	ProviderDeezer = "deezer"
+	ProviderLastFM = "lastfm"
+)
+
+const (
+	deezerSearchURL = "https://api.deezer.com/search/album"
+	lastFMAPIURL    = "https://ws.audioscrobbler.com/2.0/"
+)
+
+// ArtworkConfig orders and configures the artwork providers
+type ArtworkConfig struct {
+	Providers     []string `json:"providers"`       // Tried in order; omit one to disable it
+	LastFMAPIKey  string   `json:"lastfm_api_key"`  // Required by the lastfm provider
+	CacheSize     int      `json:"cache_size"`      // Albums remembered per cache; 0 = unlimited
+	CacheTTLHours float64  `json:"cache_ttl_hours"` // How long a cover (or miss) is trusted; 0 = forever
+	Prefetch      bool     `json:"prefetch"`        // Look up the next queued track's cover ahead of time
+	// SearchCleanup rewrites album names before they are searched, after the built-in rules
+	SearchCleanup []CleanupRule `json:"search_cleanup"`
+}
+
+// NewCache creates an artwork cache with the configured limits
+func (a ArtworkConfig) NewCache() *ArtworkCache {
+	return NewArtworkCache(a.CacheSize, a.cacheTTL())
+}
+
+// NewCatalogCache creates a catalog cache with the same limits as the artwork caches
+func (a ArtworkConfig) NewCatalogCache() *CatalogCache {
+	return NewCatalogCache(a.CacheSize, a.cacheTTL())
+}
+
+// cacheTTL is how long cached lookups are trusted (0 = forever)
+func (a ArtworkConfig) cacheTTL() time.Duration {
+	return time.Duration(a.CacheTTLHours * float64(time.Hour))
+}
+
+// sameChain reports whether two configs build the same provider chain, whose caches can
+// then be kept
+func (a ArtworkConfig) sameChain(b ArtworkConfig) bool {
+	return slices.Equal(a.Providers, b.Providers) && a.LastFMAPIKey == b.LastFMAPIKey &&
+		a.CacheSize == b.CacheSize && a.CacheTTLHours == b.CacheTTLHours &&
+		sameCleanup(a.SearchCleanup, b.SearchCleanup)
+}
+
+// ArtworkResult is what a provider found for an album
+type ArtworkResult struct {
+	ArtworkURL   string    // public cover URL
+	AlbumURL     string    // the album's page on the provider's service, if it has one
+	CollectionID int64     // the provider's ID for the album; 0 when unknown
+	Score        float64   // how closely the album found matches the one asked for, 0 to 1
+	Expires      time.Time // when a temporary host deletes the cover; zero if it doesn't
+}
+
+// expired reports whether the cover's host has deleted it
+func (a ArtworkResult) expired() bool {
+	return !a.Expires.IsZero() && time.Now().After(a.Expires)
+}
+
+// ArtworkProvider finds a public cover for an album
+type ArtworkProvider interface {
+	Name() string
+	FetchArtwork(ctx context.Context, artist, album string) (ArtworkResult, error)
+}
+
+// cachedProvider remembers both hits and errNoArtwork misses of one provider
+type cachedProvider struct {
+	ArtworkProvider
+	cache *ArtworkCache
+}
+
+// ArtworkChain tries each provider in order
+type ArtworkChain struct {
+	providers []cachedProvider
+}
+
+// NewArtworkChain builds the configured providers
+func NewArtworkChain(cfg ArtworkConfig) (*ArtworkChain, error) {
+	chain := &ArtworkChain{}
+	cleanup := cfg.searchCleanup()
+	for _, name := range cfg.Providers {
+		var provider ArtworkProvider
+		switch name {
+		case ProviderITunes:
+			provider = iTunesProvider{cleanup}
+		case ProviderDeezer:
+			provider = deezerProvider{cleanup}
+		case ProviderAppleMusic:
+			provider = appleMusicProvider{} // token checked by Config.Validate
+		case ProviderLastFM:
+			if cfg.LastFMAPIKey == "" {
+				return nil, fmt.Errorf("artwork provider %q requires artwork.lastfm_api_key", name)
+			}
+			provider = lastFMProvider{apiKey: cfg.LastFMAPIKey, cleanup: cleanup}
+		default:
+			return nil, fmt.Errorf("unknown artwork provider %q", name)
+		}
+		chain.providers = append(chain.providers, cachedProvider{provider, cfg.NewCache()})
+	}
+	return chain, nil
+}
+
+// Fetch returns the first cover any provider has for the album; an empty artist
+// searches by album alone, as for compilations
+func (c *ArtworkChain) Fetch(ctx context.Context, artist, album string) (ArtworkResult, error) {
+	var lastErr error
+	for _, p := range c.providers {
+		if result, exists := p.cache.Get(artist, album); exists {
+			if result.ArtworkURL != "" {
+				return result, nil
+			}
+			continue
+		}
+
+		result, err := p.FetchArtwork(ctx, artist, album)
+		if err != nil {
+			// Network failures aren't cached so the provider is retried next time
+			if errors.Is(err, errNoArtwork) {
+				p.cache.Set(artist, album, ArtworkResult{})
+			}
+			if !errors.Is(err, errCircuitOpen) {
+				log.Printf("⚠️  %s artwork lookup failed: %v", p.Name(), err)
+			}
+			// A network failure outranks misses so callers don't treat the album as missing
+			if lastErr == nil || !errors.Is(err, errNoArtwork) {
+				lastErr = err
+			}
+			continue
+		}
+
+		p.cache.Set(artist, album, result)
+		return result, nil
+	}
+
+	if lastErr == nil {
+		lastErr = errNoArtwork
+	}
+	return ArtworkResult{}, fmt.Errorf("no artwork for %s - %s: %w", artist, album, lastErr)
+}
+
+// iTunesProvider uses the multi-strategy iTunes album search
+type iTunesProvider struct {
+	cleanup []CleanupRule
+}
+
+// Name identifies the provider
+func (iTunesProvider) Name() string { return "iTunes" }
+
+// FetchArtwork searches the iTunes catalog
+func (p iTunesProvider) FetchArtwork(ctx context.Context, artist, album string) (ArtworkResult, error) {
+	return FetchAlbumArtwork(ctx, artist, album, p.cleanup)
+}
+
+// deezerProvider uses Deezer's public album search (no key required)
+type deezerProvider struct {
+	cleanup []CleanupRule
+}
+
+// Name identifies the provider
+func (deezerProvider) Name() string { return "Deezer" }
+
+// deezerSearchResult is the subset of the Deezer album search response we use
+type deezerSearchResult struct {
+	Data []struct {
+		ID      int64  `json:"id"`
+		Title   string `json:"title"`
+		Link    string `json:"link"`
+		CoverXL string `json:"cover_xl"`
+	} `json:"data"`
+}
+
+// FetchArtwork looks the album up with Deezer's advanced search syntax
+func (p deezerProvider) FetchArtwork(ctx context.Context, artist, album string) (ArtworkResult, error) {
+	album = cleanName(p.cleanup, album)
+	query := fmt.Sprintf("album:%q", album)
+	if artist != "" {
+		query = fmt.Sprintf("artist:%q %s", artist, query)
+	}
+
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("limit", "1")
+
+	var result deezerSearchResult
+	if err := getJSON(ctx, fmt.Sprintf("%s?%s", deezerSearchURL, params.Encode()), &result); err != nil {
+		return ArtworkResult{}, err
+	}
+	if len(result.Data) == 0 || result.Data[0].CoverXL == "" {
+		return ArtworkResult{}, errNoArtwork
+	}
+	found := result.Data[0]
+	artworkURL, err := verifyArtwork(ctx, found.CoverXL)
+	if err != nil {
+		return ArtworkResult{}, err
+	}
+	return ArtworkResult{
+		ArtworkURL:   artworkURL,
+		AlbumURL:     found.Link,
+		CollectionID: found.ID,
+		Score:        nameSimilarity(album, found.Title),
+	}, nil
+}
+
+// lastFMProvider uses Last.fm album.getInfo
+type lastFMProvider struct {
+	apiKey  string
+	cleanup []CleanupRule
+}
+
+// Name identifies the provider
+func (lastFMProvider) Name() string { return "Last.fm" }
+
+// lastFMAlbumInfo is the subset of the album.getInfo response we use
+type lastFMAlbumInfo struct {
+	Album struct {
+		Name  string `json:"name"`
+		URL   string `json:"url"`
+		Image []struct {
+			URL  string `json:"#text"`
+			Size string `json:"size"`
+		} `json:"image"`
+	} `json:"album"`
+	Error   int    `json:"error"`
+	Message string `json:"message"`
+}
+
+// FetchArtwork returns the largest image Last.fm lists for the album
+func (p lastFMProvider) FetchArtwork(ctx context.Context, artist, album string) (ArtworkResult, error) {
+	params := url.Values{}
+	params.Set("method", "album.getinfo")
+	params.Set("api_key", p.apiKey)
+	params.Set("artist", cmp.Or(artist, variousArtists)) // Last.fm files compilations under it
+	params.Set("album", cleanName(p.cleanup, album))
+	params.Set("autocorrect", "1")
+	params.Set("format", "json")
+
+	var info lastFMAlbumInfo
+	if err := getJSON(ctx, fmt.Sprintf("%s?%s", lastFMAPIURL, params.Encode()), &info); err != nil {
+		return ArtworkResult{}, err
+	}
+	if info.Error == 6 { // "Album not found"
+		return ArtworkResult{}, errNoArtwork
+	}
+	if info.Error != 0 {
+		return ArtworkResult{}, fmt.Errorf("last.fm error %d: %s", info.Error, info.Message)
+	}
+
+	// Images are listed smallest first
+	for i := len(info.Album.Image) - 1; i >= 0; i-- {
+		if info.Album.Image[i].URL != "" {
+			artworkURL, err := verifyArtwork(ctx, info.Album.Image[i].URL)
+			if err != nil {
+				return ArtworkResult{}, err
+			}
+			return ArtworkResult{
+				ArtworkURL: artworkURL,
+				AlbumURL:   info.Album.URL,
+				Score:      nameSimilarity(album, info.Album.Name),
+			}, nil
+		}
+	}
+	return ArtworkResult{}, errNoArtwork
+}
+
+// highResArtwork upgrades an iTunes 100x100 artwork URL to 600x600, keeping the
+// original when the larger rendition doesn't exist
+func highResArtwork(ctx context.Context, url100 string) (string, error) {
+	return verifyArtwork(ctx, strings.Replace(url100, "100x100bb", "600x600bb", 1), url100)
+}
+
+// verifyArtwork returns the first candidate URL that actually serves an image.
+// Providers return URLs that can 404, and a cached dead URL would leave Discord
+// showing a blank image until restart. Fails with errNoArtwork if none exist.
+func verifyArtwork(ctx context.Context, candidates ...string) (string, error) {
+	for _, candidate := range slices.Compact(candidates) {
+		ctx, cancel := context.WithTimeout(ctx, APITimeout)
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, candidate, nil)
+		if err != nil {
+			cancel()
+			continue
+		}
+		resp, err := lookupClient.Do(req)
+		cancel()
+		if err != nil {
+			return "", fmt.Errorf("failed to verify artwork: %w", err)
+		}
+		resp.Body.Close()
+
+		switch {
+		case resp.StatusCode < 300, resp.StatusCode == http.StatusMethodNotAllowed:
+			// Hosts that refuse HEAD can't be checked; trust them
+			return candidate, nil
+		case resp.StatusCode >= http.StatusInternalServerError:
+			return "", fmt.Errorf("failed to verify artwork: status %d", resp.StatusCode)
+		}
+		log.Printf("🖼️  Artwork URL returned %d, skipping: %s", resp.StatusCode, candidate)
+	}
+	return "", errNoArtwork
+}
+
+// getJSON fetches a URL with the shared client and decodes the JSON body
+func getJSON(ctx context.Context, requestURL string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := lookupClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Last.fm reports errors in the JSON body with a 4xx status, so only 5xx is fatal
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
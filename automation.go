@@ -0,0 +1,111 @@
+// AppleScript failure handling
+// osascript reports why an Apple Event failed with a numeric code. Two failures won't fix
+// themselves within a poll: macOS refusing Automation access (the user has to allow it in
+// System Settings) and a player that's busy, usually behind a modal dialog. Either is
+// logged once with what to do about it, and polling backs off until scripts work again.
+
+package main
+
+import (
+	"errors"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Apple Event error codes osascript reports
+const (
+	errAEEventNotPermitted = -1743 // Automation access denied
+	errAEWouldNeedConsent  = -1744 // Automation prompt not answered yet
+	errAETimeout           = -1712 // App didn't reply in time (busy or showing a dialog)
+)
+
+const (
+	// automationSettingsURL - Opens System Settings → Privacy & Security → Automation
+	automationSettingsURL = "x-apple.systempreferences:com.apple.preference.security?Privacy_Automation"
+
+	// scriptBackoffMax - Longest wait between polls while scripts keep failing
+	scriptBackoffMax = 2 * time.Minute
+)
+
+// scriptErrorCode matches the code osascript puts at the end of its error message
+var scriptErrorCode = regexp.MustCompile(`\((-?\d+)\)$`)
+
+// ScriptError is a failed osascript run
+type ScriptError struct {
+	Code    int    // Apple Event error code, 0 if none was reported
+	Message string // osascript's error output
+}
+
+// Error returns osascript's message
+func (e *ScriptError) Error() string {
+	return e.Message
+}
+
+// parseScriptError turns osascript's stderr, e.g. "0:52: execution error: Not authorized
+// to send Apple events to Music. (-1743)", into a ScriptError
+func parseScriptError(stderr string) *ScriptError {
+	message := strings.TrimSpace(stderr)
+	e := &ScriptError{Message: message}
+	if m := scriptErrorCode.FindStringSubmatch(message); m != nil {
+		e.Code, _ = strconv.Atoi(m[1])
+	}
+	return e
+}
+
+// scriptErrorCodeOf returns the Apple Event error code of err, or 0
+func scriptErrorCodeOf(err error) int {
+	var scriptErr *ScriptError
+	if errors.As(err, &scriptErr) {
+		return scriptErr.Code
+	}
+	return 0
+}
+
+// automationDenied reports whether macOS refused to let am-bridge script an app
+func automationDenied(err error) bool {
+	code := scriptErrorCodeOf(err)
+	return code == errAEEventNotPermitted || code == errAEWouldNeedConsent
+}
+
+// appBusy reports whether an app didn't answer, e.g. because a dialog is open
+func appBusy(err error) bool {
+	return scriptErrorCodeOf(err) == errAETimeout
+}
+
+// scriptHealth tracks consecutive denied or busy polls (poll goroutine only)
+type scriptHealth struct {
+	failures int
+	warned   bool
+}
+
+// Failed returns the delay before the next poll after err: base for ordinary errors,
+// doubling up to scriptBackoffMax while access is denied or the player is busy
+func (h *scriptHealth) Failed(err error, base time.Duration) time.Duration {
+	if !automationDenied(err) && !appBusy(err) {
+		return base
+	}
+
+	if !h.warned {
+		h.warned = true
+		if automationDenied(err) {
+			log.Printf("🔒 macOS won't let am-bridge control the player: %v", err)
+			log.Printf("🔒 Allow it under System Settings → Privacy & Security → Automation (open %q)", automationSettingsURL)
+		} else {
+			log.Printf("⏳ The player isn't responding, maybe a dialog is open; backing off: %v", err)
+		}
+	}
+
+	h.failures++
+	return min(base<<min(h.failures, 8), scriptBackoffMax)
+}
+
+// Recovered resets the backoff once a poll's scripts succeed
+func (h *scriptHealth) Recovered() {
+	if h.warned {
+		log.Println("✅ Player scripting works again")
+	}
+	*h = scriptHealth{}
+}
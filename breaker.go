@@ -0,0 +1,109 @@
+// iTunes outage backoff
+// During an iTunes Search outage every new track would still make a catalog lookup and up
+// to four album searches. After breakerThreshold consecutive failures, or at once when
+// Apple throttles with 403/429, requests are refused for breakerCooldown and the outage is
+// logged once. After the cooldown a single request probes whether the API is back; the
+// others are refused until its outcome is recorded.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// breakerThreshold - Consecutive failed requests that pause iTunes lookups
+	breakerThreshold = 3
+
+	// breakerCooldown - How long lookups stay paused
+	breakerCooldown = 5 * time.Minute
+
+	// breakerProbeTimeout - How long a probe may go unrecorded before another is let through
+	breakerProbeTimeout = 2 * APITimeout
+)
+
+// errCircuitOpen means requests are paused after repeated failures
+var errCircuitOpen = errors.New("paused after failed requests")
+
+// circuitBreaker stops calling an API that keeps failing
+type circuitBreaker struct {
+	name string
+
+	mu         sync.Mutex
+	failures   int       // consecutive failures
+	openUntil  time.Time // requests refused until then
+	open       bool      // outage logged; cleared by the next success
+	probeUntil time.Time // a probe is in flight until it is recorded or this passes
+}
+
+// iTunesBreaker guards every iTunes Search request
+var iTunesBreaker = &circuitBreaker{name: "iTunes Search"}
+
+// allow returns errCircuitOpen while requests are paused, and after the cooldown while
+// the one probe request is out
+func (c *circuitBreaker) allow() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if now.Before(c.openUntil) || (c.open && now.Before(c.probeUntil)) {
+		return fmt.Errorf("%s %w", c.name, errCircuitOpen)
+	}
+	if c.open {
+		c.probeUntil = now.Add(breakerProbeTimeout)
+	}
+	return nil
+}
+
+// record counts the outcome of a request: transport errors, 5xx and throttling are failures
+func (c *circuitBreaker) record(status int, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.probeUntil = time.Time{}
+	throttled := status == http.StatusForbidden || status == http.StatusTooManyRequests
+	if err == nil && status < 500 && !throttled {
+		if c.open {
+			log.Printf("✅ %s is reachable again", c.name)
+		}
+		c.failures, c.open = 0, false
+		return
+	}
+
+	c.failures++
+	if c.failures < breakerThreshold && !throttled && !c.open {
+		return
+	}
+	c.openUntil = time.Now().Add(breakerCooldown)
+	if !c.open {
+		reason := fmt.Sprintf("status %d", status)
+		if err != nil {
+			reason = err.Error()
+		}
+		log.Printf("⛔ %s unavailable (%s), pausing lookups for %s", c.name, reason, breakerCooldown)
+		c.open = true
+	}
+}
+
+// iTunesGet fetches an iTunes Search URL through the breaker
+func iTunesGet(ctx context.Context, requestURL string) (*http.Response, error) {
+	if err := iTunesBreaker.allow(); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := lookupClient.Do(req)
+	if err != nil {
+		iTunesBreaker.record(0, err)
+		return nil, err
+	}
+	iTunesBreaker.record(resp.StatusCode, nil)
+	return resp, nil
+}
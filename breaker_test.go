@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// endCooldown moves the breaker to the end of its cooldown
+func endCooldown(c *circuitBreaker) {
+	c.mu.Lock()
+	c.openUntil = time.Now().Add(-time.Second)
+	c.mu.Unlock()
+}
+
+func TestBreakerOpensAfterThreshold(t *testing.T) {
+	c := &circuitBreaker{name: "test"}
+	for i := range breakerThreshold - 1 {
+		c.record(http.StatusBadGateway, nil)
+		if err := c.allow(); err != nil {
+			t.Fatalf("refused after %d failures: %v", i+1, err)
+		}
+	}
+	c.record(0, errors.New("connection reset"))
+	if err := c.allow(); !errors.Is(err, errCircuitOpen) {
+		t.Fatalf("allow after %d failures = %v, want errCircuitOpen", breakerThreshold, err)
+	}
+}
+
+func TestBreakerSuccessResetsCount(t *testing.T) {
+	c := &circuitBreaker{name: "test"}
+	for range breakerThreshold - 1 {
+		c.record(http.StatusInternalServerError, nil)
+	}
+	c.record(http.StatusOK, nil)
+	c.record(http.StatusNotFound, nil) // a 4xx answer means the API is up
+	c.record(http.StatusInternalServerError, nil)
+	if err := c.allow(); err != nil {
+		t.Errorf("refused after a success broke the run of failures: %v", err)
+	}
+}
+
+func TestBreakerThrottleOpensAtOnce(t *testing.T) {
+	for _, status := range []int{http.StatusForbidden, http.StatusTooManyRequests} {
+		c := &circuitBreaker{name: "test"}
+		c.record(status, nil)
+		if err := c.allow(); !errors.Is(err, errCircuitOpen) {
+			t.Errorf("allow after status %d = %v, want errCircuitOpen", status, err)
+		}
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	c := &circuitBreaker{name: "test"}
+	c.record(http.StatusTooManyRequests, nil)
+	endCooldown(c)
+
+	// Of many requests at the end of the cooldown, exactly one probes
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if c.allow() == nil {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != 1 {
+		t.Fatalf("%d requests let through after the cooldown, want 1 probe", allowed)
+	}
+
+	// A failed probe (even below the threshold) pauses again
+	c.record(http.StatusServiceUnavailable, nil)
+	if err := c.allow(); !errors.Is(err, errCircuitOpen) {
+		t.Fatalf("allow after a failed probe = %v, want errCircuitOpen", err)
+	}
+
+	// A successful probe closes the breaker for everyone
+	endCooldown(c)
+	if err := c.allow(); err != nil {
+		t.Fatalf("second probe refused: %v", err)
+	}
+	c.record(http.StatusOK, nil)
+	for range 3 {
+		if err := c.allow(); err != nil {
+			t.Fatalf("refused after a successful probe: %v", err)
+		}
+	}
+}
+
+func TestBreakerLostProbe(t *testing.T) {
+	c := &circuitBreaker{name: "test"}
+	c.record(http.StatusForbidden, nil)
+	endCooldown(c)
+	if err := c.allow(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A probe that never reports back doesn't keep the API paused forever
+	c.mu.Lock()
+	c.probeUntil = time.Now().Add(-time.Second)
+	c.mu.Unlock()
+	if err := c.allow(); err != nil {
+		t.Errorf("refused after the probe timed out: %v", err)
+	}
+}
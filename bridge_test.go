@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"am-discord-bridge/discord"
+	"am-discord-bridge/discord/discordtest"
+)
+
+// testCover - Artwork the fake tracks carry, so no lookup leaves the machine
+const testCover = "https://example.com/cover.jpg"
+
+// testScript plays a track, pauses it, resumes, skips to another and stops
+const testScript = `
+0   play   Radiohead|OK Computer|Airbag|284
+10  pause
+20  play
+30  play   Massive Attack|Mezzanine|Teardrop|330
+60  stop
+`
+
+// pipeline is a bridge wired to a fake player and a fake Discord client
+type pipeline struct {
+	t      *testing.T
+	ctx    context.Context
+	bridge *Bridge
+	fake   *fakeSource
+	server *discordtest.Server
+	clock  *testClock
+	origin time.Time // script start by clock
+	seen   int       // commands already checked
+}
+
+// testClock is a bridge clock the test moves along the script
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) set(now time.Time) {
+	c.mu.Lock()
+	c.now = now
+	c.mu.Unlock()
+}
+
+// newPipeline connects a bridge reading script to a fresh fake Discord client
+func newPipeline(t *testing.T, script string) *pipeline {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir()) // caches and state stay out of the real home
+
+	fake, err := parseFakeScript("test script", strings.NewReader(script))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range fake.steps {
+		if step.track != nil {
+			step.track.ArtworkURL = testCover
+		}
+	}
+	previous, registered := sourceFactories[fakeSourceName]
+	sourceFactories[fakeSourceName] = func(ScriptRunner) Source { return fake }
+	t.Cleanup(func() {
+		if registered {
+			sourceFactories[fakeSourceName] = previous
+		} else {
+			delete(sourceFactories, fakeSourceName)
+		}
+	})
+
+	server, err := discordtest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Close)
+
+	config := DefaultConfig()
+	config.useFakePlayer()
+	config.Artwork.Prefetch = false // it reads the script from another goroutine
+	config.Discord.Transport = discord.TransportIPC
+	config.Discord.Socket = server.Socket
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	clock := &testClock{now: time.Now()}
+	bridge, err := NewBridge(config, WithContext(ctx), WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bridge.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(bridge.Disconnect)
+	return &pipeline{t: t, ctx: ctx, bridge: bridge, fake: fake, server: server, clock: clock}
+}
+
+// pollAt polls with the fake player at the given second of its script, the bridge's clock
+// having moved along with it
+func (p *pipeline) pollAt(seconds float64) {
+	at := time.Duration(seconds * float64(time.Second))
+	p.fake.started = time.Now().Add(-at)
+	p.clock.set(p.start().Add(at))
+	pollAndUpdate(p.ctx, p.bridge)
+}
+
+// start is when, by the bridge's clock, the script began
+func (p *pipeline) start() time.Time {
+	if p.origin.IsZero() {
+		p.origin = p.clock.Now()
+	}
+	return p.origin
+}
+
+// next waits for the next SET_ACTIVITY the fake client receives
+func (p *pipeline) next() discordtest.Command {
+	p.t.Helper()
+	ctx, cancel := context.WithTimeout(p.ctx, 5*time.Second)
+	defer cancel()
+	index := 0
+	command, err := p.server.Wait(ctx, func(command discordtest.Command) bool {
+		index++
+		return index > p.seen && command.Cmd == "SET_ACTIVITY"
+	})
+	if err != nil {
+		p.t.Fatalf("no SET_ACTIVITY after the %d already seen: %v", p.seen, err)
+	}
+	p.seen = index
+	if len(command.Problems) > 0 {
+		p.t.Fatalf("Discord would reject %+v: %v", command.Activity, command.Problems)
+	}
+	return command
+}
+
+// quiet checks that nothing more was sent
+func (p *pipeline) quiet() {
+	p.t.Helper()
+	time.Sleep(100 * time.Millisecond)
+	if commands := p.server.Commands(); len(commands) > p.seen {
+		p.t.Fatalf("unexpected command %+v", commands[p.seen].Activity)
+	}
+}
+
+func TestPipelineHandshake(t *testing.T) {
+	p := newPipeline(t, testScript)
+
+	handshakes := p.server.Handshakes()
+	if len(handshakes) != 1 {
+		t.Fatalf("got %d handshakes, want 1", len(handshakes))
+	}
+	hello := handshakes[0]
+	if hello.Version != "1" || hello.ClientID != DiscordAppID || len(hello.Problems) > 0 {
+		t.Errorf("handshake = %+v, want v1 with client ID %s", hello, DiscordAppID)
+	}
+}
+
+func TestPipelinePlayback(t *testing.T) {
+	p := newPipeline(t, testScript)
+
+	// Play
+	p.pollAt(1)
+	playing := p.next().Activity
+	if playing == nil || playing.Type != discord.ActivityTypeListening {
+		t.Fatalf("playing activity = %+v", playing)
+	}
+	if playing.Details != "Airbag" || playing.State != "by Radiohead" {
+		t.Errorf("playing text = %q / %q, want Airbag / by Radiohead", playing.Details, playing.State)
+	}
+	if playing.Assets.LargeImage != testCover || playing.Assets.LargeText != "OK Computer" {
+		t.Errorf("playing assets = %+v", playing.Assets)
+	}
+	if playing.Timestamps == nil || playing.Timestamps.End == nil {
+		t.Fatalf("playing timestamps = %+v, want an end time", playing.Timestamps)
+	}
+	if ends := time.UnixMilli(*playing.Timestamps.End).Sub(p.start()); ends < 283*time.Second || ends > 285*time.Second {
+		t.Errorf("track ends %v after the start, want 284s", ends)
+	}
+
+	// The same track a poll later sends nothing
+	p.pollAt(5)
+	p.quiet()
+
+	// Pause
+	p.pollAt(11)
+	paused := p.next().Activity
+	if paused == nil || paused.Details != "Airbag" || !strings.HasPrefix(paused.State, "Paused") {
+		t.Fatalf("paused activity = %+v, want Airbag marked Paused", paused)
+	}
+	if paused.Timestamps != nil {
+		t.Errorf("paused timestamps = %+v, want none", paused.Timestamps)
+	}
+
+	// Resume
+	p.pollAt(21)
+	resumed := p.next().Activity
+	if resumed == nil || resumed.Details != "Airbag" || resumed.Timestamps == nil {
+		t.Fatalf("resumed activity = %+v", resumed)
+	}
+
+	// Track change, past the skip debounce
+	p.pollAt(30 + SkipSettle.Seconds() + 1)
+	changed := p.next().Activity
+	if changed == nil || changed.Details != "Teardrop" || changed.State != "by Massive Attack" || changed.Assets.LargeText != "Mezzanine" {
+		t.Fatalf("changed activity = %+v, want Teardrop by Massive Attack", changed)
+	}
+
+	// Stop clears the presence
+	p.pollAt(61)
+	if cleared := p.next(); cleared.Activity != nil {
+		t.Fatalf("stopped activity = %+v, want a clear", cleared.Activity)
+	}
+	if p.server.Activity() != nil {
+		t.Error("Discord still shows an activity after the stop")
+	}
+}
+
+func TestPipelineSkipDebounce(t *testing.T) {
+	p := newPipeline(t, testScript)
+
+	p.pollAt(29)
+	if first := p.next().Activity; first == nil || first.Details != "Airbag" {
+		t.Fatalf("first activity = %+v", first)
+	}
+
+	// Just skipped to: held back until it has played for SkipSettle
+	p.pollAt(31)
+	p.quiet()
+	p.pollAt(30 + SkipSettle.Seconds() + 1)
+	if changed := p.next().Activity; changed == nil || changed.Details != "Teardrop" {
+		t.Fatalf("changed activity = %+v, want Teardrop", changed)
+	}
+}
@@ -0,0 +1,105 @@
+// Presence buttons
+// Up to two buttons whose labels and URLs are templates, such as
+// {"label": "Lyrics", "url": "https://genius.com/search?q={artist} {name}"}. Values are
+// URL-escaped when substituted, except placeholders that are URLs themselves.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"am-discord-bridge/discord"
+)
+
+// maxPresenceButtons - Discord shows at most two buttons
+const maxPresenceButtons = 2
+
+// urlFields are placeholders holding complete URLs, substituted without escaping
+var urlFields = map[string]bool{
+	"apple_music_url": true,
+	"song_link":       true,
+}
+
+// ButtonConfig is one presence button
+type ButtonConfig struct {
+	Label string `json:"label"` // Template, e.g. "Lyrics for {name}"
+	URL   string `json:"url"`   // Template; must render to an http(s) URL
+}
+
+// validate checks the templates and that the URL can only render as http(s)
+func (c ButtonConfig) validate() error {
+	if c.Label == "" || c.URL == "" {
+		return fmt.Errorf("button needs both a label and a url")
+	}
+	for _, tmpl := range []string{c.Label, c.URL} {
+		if err := validateTemplate(tmpl); err != nil {
+			return err
+		}
+	}
+
+	// A template starting with a URL placeholder gets its scheme from the track
+	if match := templatePlaceholder.FindStringSubmatchIndex(c.URL); match != nil && match[0] == 0 &&
+		urlFields[c.URL[match[2]:match[3]]] {
+		return nil
+	}
+	if !isWebURL(c.URL) {
+		return fmt.Errorf("button url %q must start with https:// or http://", c.URL)
+	}
+	return nil
+}
+
+// render fills in the button for a track; ok is false when a placeholder is empty
+// or the result isn't a web URL
+func (c ButtonConfig) render(track *Track) (button *discord.Button, ok bool) {
+	empty := false
+	link := templatePlaceholder.ReplaceAllStringFunc(c.URL, func(match string) string {
+		name := match[1 : len(match)-1]
+		field, known := templateFields[name]
+		if !known {
+			return match
+		}
+		value := field(track)
+		if value == "" {
+			empty = true
+		}
+		if urlFields[name] {
+			return value
+		}
+		// QueryEscape's "+" for spaces only works in queries; %20 works everywhere
+		return strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+	})
+	if empty {
+		return nil, false
+	}
+	link = strings.ReplaceAll(link, " ", "%20") // literal spaces in the template
+	if !isWebURL(link) {
+		log.Printf("⚠️  Skipping button %q: %q is not an http(s) URL", c.Label, link)
+		return nil, false
+	}
+
+	label := RenderTemplate(c.Label, track)
+	if label == "" {
+		return nil, false
+	}
+	return &discord.Button{Label: label, Url: link}, true
+}
+
+// isWebURL reports whether s is an absolute http or https URL
+func isWebURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+}
+
+// renderButtons builds the buttons that have everything they need for the track
+func renderButtons(configs []ButtonConfig, track *Track) []*discord.Button {
+	var buttons []*discord.Button
+	for _, cfg := range configs {
+		if button, ok := cfg.render(track); ok {
+			buttons = append(buttons, button)
+		}
+	}
+	return buttons
+}
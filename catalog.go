@@ -0,0 +1,222 @@
+// Apple Music catalog resolution
+// Tracks whose store ID the player reports are fetched with iTunes Lookup, which is exact.
+// The rest (and IDs the storefront doesn't carry) are resolved with an iTunes song search,
+// or the Apple Music API when configured, and accepted only on an exact name and album
+// match by the artist searched for (alone or first in the credit). The match carries the
+// store ID, exact artwork and the track's Apple Music URL.
+// Local files skip this and use the fuzzy album search in FetchAlbumArtwork.
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// catalogSearchLimit - Candidates fetched per song search before exact matching
+const catalogSearchLimit = 25
+
+// errNoCatalogMatch means the search succeeded but no result matched exactly
+var errNoCatalogMatch = errors.New("no exact catalog match")
+
+// CatalogMatch is an exact Apple Music catalog hit for a track
+type CatalogMatch struct {
+	StoreID    int64
+	ArtworkURL string // 600x600, 100x100 if the larger size is missing, "" if neither exists
+	TrackURL   string // music.apple.com deep link
+	Genre      string // Apple Music API only, like the rest below
+	Composer   string
+	Year       int
+	Explicit   bool // from both iTunes Search and the API
+}
+
+// apply copies the match onto the track, keeping metadata the player already reported
+func (m *CatalogMatch) apply(track *Track) {
+	track.StoreID = m.StoreID
+	track.AppleMusicURL = m.TrackURL
+	track.Genre = cmp.Or(track.Genre, m.Genre)
+	track.Composer = cmp.Or(track.Composer, m.Composer)
+	track.Year = cmp.Or(track.Year, m.Year)
+	track.Explicit = track.Explicit || m.Explicit
+}
+
+// iTunesSongResult represents the song entity search and lookup response
+type iTunesSongResult struct {
+	Results []iTunesSong `json:"results"`
+}
+
+// iTunesSong is one song in an iTunes Search or Lookup response
+type iTunesSong struct {
+	TrackID        int64  `json:"trackId"`
+	TrackName      string `json:"trackName"`
+	ArtistName     string `json:"artistName"`
+	CollectionName string `json:"collectionName"`
+	ArtworkURL100  string `json:"artworkUrl100"`
+	TrackViewURL   string `json:"trackViewUrl"`
+	Explicitness   string `json:"trackExplicitness"` // "explicit", "cleaned" or "notExplicit"
+}
+
+// CatalogCache remembers resolved tracks, including misses, so each track is searched once
+// per storefront. It is bounded like the artwork caches.
+type CatalogCache struct {
+	*lruCache[*CatalogMatch] // key: "storefront|artist|album|name" -> match (nil = not in catalog)
+}
+
+// NewCatalogCache creates a cache holding at most maxEntries, each for at most ttl (0 lifts
+// either limit)
+func NewCatalogCache(maxEntries int, ttl time.Duration) *CatalogCache {
+	return &CatalogCache{newLRUCache[*CatalogMatch](maxEntries, ttl, nil)}
+}
+
+// catalogKey identifies a track in the cache; the same song has other IDs and links, or
+// none, in another storefront
+func catalogKey(track *Track) string {
+	return catalogStorefront() + "|" + track.Artist + "|" + track.Album + "|" + track.Name
+}
+
+// catalogStorefront is the storefront catalog lookups currently search
+func catalogStorefront() string {
+	if cfg := appleMusic.Load(); cfg != nil && cfg.DeveloperToken != "" {
+		return cfg.Storefront
+	}
+	country := ""
+	if store := iTunesStore.Load(); store != nil {
+		country = strings.ToLower(store.Country)
+	}
+	return cmp.Or(country, "us")
+}
+
+// Get returns a cached match (possibly nil) and whether the track was resolved before
+func (c *CatalogCache) Get(track *Track) (*CatalogMatch, bool) {
+	return c.get(catalogKey(track))
+}
+
+// Set stores a resolution result; nil records a miss
+func (c *CatalogCache) Set(track *Track, match *CatalogMatch) {
+	c.set(catalogKey(track), match)
+}
+
+// ResolveCatalogTrack finds the exact catalog entry for a streamed or purchased track
+func ResolveCatalogTrack(ctx context.Context, track *Track) (*CatalogMatch, error) {
+	if track.StoreID != 0 {
+		match, err := lookupITunesTrack(ctx, track)
+		// A miss means another storefront's ID; the searches find this one's edition
+		if !errors.Is(err, errNoCatalogMatch) {
+			return match, err
+		}
+	}
+
+	if appleMusicEnabled() {
+		match, err := resolveAppleMusicTrack(ctx, track)
+		// The API's catalog is the same one iTunes Search covers, so only failures fall back
+		if err == nil || errors.Is(err, errNoCatalogMatch) {
+			return match, err
+		}
+		if !errors.Is(err, errCircuitOpen) {
+			log.Printf("⚠️  Apple Music API lookup failed, using iTunes Search: %v", err)
+		}
+	}
+
+	return searchByArtists(track, func(artist string) (*CatalogMatch, error) {
+		return searchITunesTrack(ctx, track, artist)
+	})
+}
+
+// searchByArtists runs a song search by each of the track's search artists in turn, until
+// one finds the song or fails for another reason than a miss
+func searchByArtists(track *Track, search func(artist string) (*CatalogMatch, error)) (*CatalogMatch, error) {
+	var match *CatalogMatch
+	var err error
+	for _, artist := range searchArtists(track) {
+		if match, err = search(artist); !errors.Is(err, errNoCatalogMatch) {
+			break
+		}
+	}
+	return match, err
+}
+
+// searchITunesTrack finds the catalog song for a track with iTunes Search, searching by artist
+func searchITunesTrack(ctx context.Context, track *Track, artist string) (*CatalogMatch, error) {
+	params := url.Values{}
+	params.Set("term", fmt.Sprintf("%s %s", artist, track.Name))
+	params.Set("media", "music")
+	params.Set("entity", "song")
+	params.Set("limit", fmt.Sprint(catalogSearchLimit))
+
+	result, err := getITunesSongs(ctx, iTunesRequestURL(params))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, song := range result.Results {
+		if strings.EqualFold(song.TrackName, track.Name) &&
+			creditMatches(artist, song.ArtistName) &&
+			strings.EqualFold(song.CollectionName, track.Album) {
+			return song.match(ctx)
+		}
+	}
+
+	return nil, fmt.Errorf("%w for %s - %s", errNoCatalogMatch, track.Artist, track.Name)
+}
+
+// lookupITunesTrack fetches the catalog song with the track's store ID from iTunes Lookup
+func lookupITunesTrack(ctx context.Context, track *Track) (*CatalogMatch, error) {
+	params := url.Values{}
+	params.Set("id", strconv.FormatInt(track.StoreID, 10))
+
+	result, err := getITunesSongs(ctx, iTunesEndpointURL(iTunesLookupURL, params))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, song := range result.Results {
+		if song.TrackID == track.StoreID {
+			return song.match(ctx)
+		}
+	}
+
+	return nil, fmt.Errorf("%w for store ID %d", errNoCatalogMatch, track.StoreID)
+}
+
+// getITunesSongs runs an iTunes Search or Lookup request for songs
+func getITunesSongs(ctx context.Context, requestURL string) (*iTunesSongResult, error) {
+	resp, err := iTunesGet(ctx, requestURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	var result iTunesSongResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// match turns an iTunes song into a catalog match, checking its cover exists
+func (song iTunesSong) match(ctx context.Context) (*CatalogMatch, error) {
+	// A dead cover URL still leaves the deep link; album search supplies the artwork
+	artworkURL, err := highResArtwork(ctx, song.ArtworkURL100)
+	if err != nil && !errors.Is(err, errNoArtwork) {
+		return nil, err
+	}
+	return &CatalogMatch{
+		StoreID:    song.TrackID,
+		ArtworkURL: artworkURL,
+		TrackURL:   song.TrackViewURL,
+		Explicit:   song.Explicitness == "explicit",
+	}, nil
+}
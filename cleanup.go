@@ -0,0 +1,76 @@
+// Album name cleanup
+// Stores dress album names up with qualifiers (" - Single", " (From \"Film\")",
+// "(Deluxe Edition)", 「…」 brackets) that hurt searches and crowd the presence. Cleanup
+// rules are regexes applied in order, each replacing every match: the built-in search
+// rules and then artwork.search_cleanup before album searches, presence.album_cleanup
+// before the album is shown on Discord.
+// Display rules run before Discord's length limit is applied, so the qualifier rather
+// than the name is what goes. A rule that would leave nothing keeps the name as it was.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// CleanupRule rewrites the parts of a name matching Pattern
+type CleanupRule struct {
+	Pattern string `json:"pattern"` // Case-insensitive regex
+	Replace string `json:"replace"` // Replacement, may use $1; empty removes the match
+
+	re *regexp.Regexp
+}
+
+// builtinSearchCleanup - Qualifiers always cut from album names before they are searched,
+// ahead of the configured rules
+var builtinSearchCleanup = []CleanupRule{
+	{Pattern: ` - Single.*$`, re: regexp.MustCompile(`(?i) - Single.*$`)},
+	{Pattern: ` \(From.*$`, re: regexp.MustCompile(`(?i) \(From.*$`)},
+}
+
+// searchCleanup returns the built-in rules followed by the configured ones
+func (a ArtworkConfig) searchCleanup() []CleanupRule {
+	return append(slices.Clip(builtinSearchCleanup), a.SearchCleanup...)
+}
+
+// compileCleanup checks every rule's pattern and prepares its regex; name prefixes errors
+func compileCleanup(name string, rules []CleanupRule) error {
+	for i := range rules {
+		re, err := regexp.Compile("(?i)" + rules[i].Pattern)
+		if err != nil {
+			return fmt.Errorf("%s[%d] %q: %w", name, i, rules[i].Pattern, err)
+		}
+		rules[i].re = re
+	}
+	return nil
+}
+
+// sameCleanup reports whether two rule lists rewrite names alike
+func sameCleanup(a, b []CleanupRule) bool {
+	return slices.EqualFunc(a, b, func(x, y CleanupRule) bool {
+		return x.Pattern == y.Pattern && x.Replace == y.Replace
+	})
+}
+
+// cleanName applies the rules in order, keeping the original if nothing would be left
+func cleanName(rules []CleanupRule, name string) string {
+	cleaned := name
+	for _, rule := range rules {
+		re := rule.re
+		if re == nil {
+			// Not compiled by Validate, as for configs built in code
+			var err error
+			if re, err = regexp.Compile("(?i)" + rule.Pattern); err != nil {
+				continue
+			}
+		}
+		cleaned = strings.TrimSpace(re.ReplaceAllString(cleaned, rule.Replace))
+	}
+	if cleaned == "" {
+		return name
+	}
+	return cleaned
+}
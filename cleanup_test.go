@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCleanNameBuiltin(t *testing.T) {
+	rules := ArtworkConfig{}.searchCleanup()
+	tests := []struct {
+		album, want string
+	}{
+		{"OK Computer", "OK Computer"},
+		{"Shallow - Single", "Shallow"},
+		{"Shallow (From “A Star Is Born”) - Single", "Shallow"},
+		{"Let It Go (From \"Frozen\")", "Let It Go"},
+		{"Blinding Lights - single", "Blinding Lights"},
+		{"From Gardens Where We Feel Secure", "From Gardens Where We Feel Secure"}, // "From" not in brackets
+		{" - Single", " - Single"},                                                 // nothing would be left
+	}
+	for _, tt := range tests {
+		if got := cleanName(rules, tt.album); got != tt.want {
+			t.Errorf("cleanName(%q) = %q, want %q", tt.album, got, tt.want)
+		}
+	}
+}
+
+func TestCleanNameConfigured(t *testing.T) {
+	rules := []CleanupRule{
+		{Pattern: `\s*\((?:deluxe|remastered)[^)]*\)`},
+		{Pattern: `「(.+)」`, Replace: "$1"},
+	}
+	if err := compileCleanup("presence.album_cleanup", rules); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		album, want string
+	}{
+		{"Currents (Deluxe Edition)", "Currents"},
+		{"Abbey Road (Remastered 2019)", "Abbey Road"},
+		{"「夜に駆ける」", "夜に駆ける"},
+		{"Rumours (Live)", "Rumours (Live)"},
+		{"(Deluxe)", "(Deluxe)"},
+	}
+	for _, tt := range tests {
+		if got := cleanName(rules, tt.album); got != tt.want {
+			t.Errorf("cleanName(%q) = %q, want %q", tt.album, got, tt.want)
+		}
+	}
+
+	// Rules applied in order: the second sees the first's output
+	ordered := []CleanupRule{{Pattern: `Edition`, Replace: "Ed."}, {Pattern: `Ed\.$`, Replace: "Version"}}
+	if got := cleanName(ordered, "Deluxe Edition"); got != "Deluxe Version" {
+		t.Errorf("ordered rules = %q, want Deluxe Version", got)
+	}
+}
+
+func TestCleanNameUncompiled(t *testing.T) {
+	// Rules built in code never pass through Validate
+	rules := []CleanupRule{{Pattern: ` \[Explicit\]`}, {Pattern: `(`}}
+	if got := cleanName(rules, "Song [explicit]"); got != "Song" {
+		t.Errorf("uncompiled rules = %q, want Song", got)
+	}
+}
+
+func TestCompileCleanup(t *testing.T) {
+	err := compileCleanup("artwork.search_cleanup", []CleanupRule{{Pattern: "ok"}, {Pattern: "(unclosed"}})
+	if err == nil {
+		t.Fatal("invalid pattern accepted")
+	}
+	if want := "artwork.search_cleanup[1]"; !strings.Contains(err.Error(), want) {
+		t.Errorf("error %q doesn't name %s", err, want)
+	}
+}
+
+func TestSameCleanup(t *testing.T) {
+	a := []CleanupRule{{Pattern: "x", Replace: "y"}}
+	if !sameCleanup(a, []CleanupRule{{Pattern: "x", Replace: "y"}}) {
+		t.Error("equal rules reported different")
+	}
+	if sameCleanup(a, []CleanupRule{{Pattern: "x"}}) || sameCleanup(a, nil) {
+		t.Error("different rules reported equal")
+	}
+}
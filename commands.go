@@ -0,0 +1,377 @@
+// CLI subcommands
+// `am-bridge run` is the daemon; the rest are one-shot helpers for scripts and status bars.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"time"
+)
+
+// version is overridden at build time: go build -ldflags="-X main.version=v1.2.3"
+var version = "dev"
+
+// command is a single CLI subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+// commands lists every subcommand in help order
+var commands []command
+
+func init() {
+	commands = []command{
+		{"run", "Run the bridge daemon (default)", cmdRun},
+		{"now", "Print the current track and exit", cmdNow},
+		{"status", "Query the running daemon", cmdStatus},
+		{"preview", "Show a made-up track on Discord to try out templates", cmdPreview},
+		{"profile", "Switch the running daemon to a Discord profile", cmdProfile},
+		{"pause", "Stop publishing presence without quitting the daemon", cmdPause},
+		{"resume", "Resume publishing presence", cmdResume},
+		{"quit", "Stop the running daemon", cmdQuit},
+		{"logs", "Print or follow the daemon's log file", cmdLogs},
+		{"history", "Show recently played tracks", cmdHistory},
+		{"stats", "Show top artists and tracks", cmdStats},
+		{"report", "Summarize this day's or week's listening", cmdReport},
+		{"config", "Create the config file interactively (config init)", cmdConfig},
+		{"doctor", "Diagnose permissions, players and Discord", cmdDoctor},
+		{"selftest", "Play a scripted track list into a fake Discord to check the config", cmdSelftest},
+		{"extension", "Write the browser extension for the Apple Music web player", cmdExtension},
+		{"auth", "Store API credentials in the macOS Keychain", cmdAuth},
+		{"update", "Install the latest release from GitHub", cmdUpdate},
+		{"bugreport", "Open a GitHub issue, optionally with the last crash", cmdBugreport},
+		{"version", "Print the version", cmdVersion},
+		{"help", "Show this help", cmdHelp},
+	}
+}
+
+// dispatch runs the subcommand named by args[0], defaulting to run
+func dispatch(args []string) error {
+	if len(args) == 0 || (len(args[0]) > 0 && args[0][0] == '-') {
+		return cmdRun(args)
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:])
+		}
+	}
+
+	cmdHelp(nil)
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+// cmdHelp prints the available subcommands
+func cmdHelp(args []string) error {
+	fmt.Fprintln(os.Stderr, "Usage: am-bridge <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run 'am-bridge <command> -h' for command flags.")
+	return nil
+}
+
+// cmdVersion prints the build version
+func cmdVersion(args []string) error {
+	fmt.Println("am-bridge", version)
+	return nil
+}
+
+// cmdNow prints the current track as text or JSON
+func cmdNow(args []string) error {
+	fs := flag.NewFlagSet("now", flag.ExitOnError)
+	configPath := fs.String("config", DefaultConfigPath(), "path to the JSON config file")
+	asJSON := fs.Bool("json", false, "print JSON instead of text")
+	fs.Parse(args)
+
+	config, err := LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	sources, err := NewSourceManager(config.Sources, OSAScriptRunner{})
+	if err != nil {
+		return err
+	}
+	ConfigureITunes(config.ITunes)
+	ConfigureAppleMusic(config.AppleMusic, config.ITunes)
+	ConfigureCider(config.Cider)
+	if err := ConfigureNetwork(config.Network); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	source, state, err := sources.Select(ctx)
+	if source == nil && err != nil {
+		return fmt.Errorf("failed to query players: %w", err)
+	}
+
+	var track *Track
+	if source != nil {
+		if track, err = source.CurrentTrack(ctx); err != nil {
+			return err
+		}
+	}
+
+	if *asJSON {
+		return printJSON(struct {
+			State string `json:"state"`
+			Track *Track `json:"track,omitempty"`
+		}{state.String(), track})
+	}
+
+	if track == nil {
+		fmt.Println(state)
+		return nil
+	}
+	fmt.Printf("%s - %s (%s) [%s]\n", track.Name, track.Artist, track.Album, state)
+	return nil
+}
+
+// cmdStatus asks the running daemon for its status
+func cmdStatus(args []string) error {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print JSON instead of text")
+	fs.Parse(args)
+
+	resp, err := sendControl("status")
+	if err != nil {
+		return err
+	}
+	status := resp.Status
+
+	if *asJSON {
+		return printJSON(status)
+	}
+
+	fmt.Printf("am-bridge %s (pid %d, up %s)\n", status.Version, status.PID, status.Uptime)
+	discordStatus := "disconnected"
+	if status.DiscordConnected {
+		discordStatus = "connected"
+		if status.DiscordUser != "" {
+			discordStatus += " as " + status.DiscordUser
+		}
+	}
+	presenceStatus := "publishing"
+	if status.PresencePaused {
+		presenceStatus = "paused"
+	}
+	fmt.Printf("Discord:  %s\n", discordStatus)
+	fmt.Printf("Presence: %s\n", presenceStatus)
+	fmt.Printf("Player:   %s\n", status.State)
+	if status.Profile != "" {
+		fmt.Printf("Profile:  %s\n", status.Profile)
+	}
+	if status.Track != nil {
+		fmt.Printf("Track:    %s - %s (%s)\n", status.Track.Name, status.Track.Artist, status.Track.Album)
+	}
+	if cache := status.ArtworkCache; cache.Hits+cache.Misses > 0 {
+		fmt.Printf("Artwork:  %d cached, %d%% hits (%d of %d lookups)\n", cache.Entries,
+			100*cache.Hits/(cache.Hits+cache.Misses), cache.Hits, cache.Hits+cache.Misses)
+	}
+	return nil
+}
+
+// cmdProfile switches the daemon's Discord profile, or lists profiles without an argument
+func cmdProfile(args []string) error {
+	fs := flag.NewFlagSet("profile", flag.ExitOnError)
+	configPath := fs.String("config", DefaultConfigPath(), "path to the JSON config file")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		config, err := LoadConfig(*configPath)
+		if err != nil {
+			return err
+		}
+		if len(config.Discord.Profiles) == 0 {
+			fmt.Println("No profiles configured (add discord.profiles to the config file)")
+			return nil
+		}
+		for _, name := range slices.Sorted(maps.Keys(config.Discord.Profiles)) {
+			fmt.Printf("  %-12s %s\n", name, config.Discord.Profiles[name])
+		}
+		return nil
+	}
+
+	resp, err := sendControl("profile", fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Switched to profile %q\n", resp.Status.Profile)
+	return nil
+}
+
+// cmdPause hides the presence until resumed
+func cmdPause(args []string) error {
+	if _, err := sendControl("pause"); err != nil {
+		return err
+	}
+	fmt.Println("Presence paused")
+	return nil
+}
+
+// cmdResume republishes the current track
+func cmdResume(args []string) error {
+	if _, err := sendControl("resume"); err != nil {
+		return err
+	}
+	fmt.Println("Presence resumed")
+	return nil
+}
+
+// cmdQuit asks the daemon to clear its presence and exit
+func cmdQuit(args []string) error {
+	resp, err := sendControl("quit")
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Stopping am-bridge (pid %d)\n", resp.Status.PID)
+	return nil
+}
+
+// cmdAuth stores, removes or lists Keychain credentials
+func cmdAuth(args []string) error {
+	fs := flag.NewFlagSet("auth", flag.ExitOnError)
+	remove := fs.Bool("delete", false, "remove the stored credential instead")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		for _, slot := range secretSlots {
+			stored := "not set"
+			if _, err := keychainGet(slot.account); err == nil {
+				stored = "stored"
+			} else if !errors.Is(err, errSecretNotFound) {
+				stored = err.Error()
+			}
+			fmt.Printf("  %-14s %-26s %s\n", slot.account, slot.label, stored)
+		}
+		return nil
+	}
+
+	index := slices.IndexFunc(secretSlots, func(slot secretSlot) bool { return slot.account == fs.Arg(0) })
+	if index < 0 {
+		return fmt.Errorf("unknown service %q (run 'am-bridge auth' to list them)", fs.Arg(0))
+	}
+	slot := secretSlots[index]
+
+	if *remove {
+		if err := keychainDelete(slot.account); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", slot.label, err)
+		}
+		fmt.Printf("Removed %s from Keychain\n", slot.label)
+		return nil
+	}
+
+	fmt.Printf("Enter your %s.\n", slot.label)
+	if err := keychainPrompt(slot.account, slot.label); err != nil {
+		return err
+	}
+	fmt.Printf("Stored %s in Keychain; restart am-bridge to use it\n", slot.label)
+	return nil
+}
+
+// openHistoryForCommand loads the config and opens its history database
+func openHistoryForCommand(configPath string) (*HistoryStore, error) {
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	path := historyPath(config.History)
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("no listening history at %s", path)
+	}
+	return openHistoryStore(path)
+}
+
+// cmdHistory prints the most recent listens
+func cmdHistory(args []string) error {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	configPath := fs.String("config", DefaultConfigPath(), "path to the JSON config file")
+	limit := fs.Int("n", 20, "number of listens to show")
+	fs.Parse(args)
+
+	history, err := openHistoryForCommand(*configPath)
+	if err != nil {
+		return err
+	}
+
+	listens, err := history.Recent(*limit)
+	if err != nil {
+		return err
+	}
+
+	for _, listen := range listens {
+		fmt.Printf("%s  %s - %s (%s)\n",
+			listen.StartedAt.Format("2006-01-02 15:04"), listen.Track, listen.Artist, listen.Album)
+	}
+	return nil
+}
+
+// statsPeriods maps -period values to their lookback window (0 = all time)
+var statsPeriods = map[string]time.Duration{
+	"day":   24 * time.Hour,
+	"week":  7 * 24 * time.Hour,
+	"month": 30 * 24 * time.Hour,
+	"all":   0,
+}
+
+// cmdStats prints top artists and tracks for a period
+func cmdStats(args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	configPath := fs.String("config", DefaultConfigPath(), "path to the JSON config file")
+	period := fs.String("period", "week", "day, week, month or all")
+	limit := fs.Int("n", 10, "entries per ranking")
+	fs.Parse(args)
+
+	window, ok := statsPeriods[*period]
+	if !ok {
+		return fmt.Errorf("unknown period %q (want day, week, month or all)", *period)
+	}
+	since := time.Unix(0, 0)
+	if window > 0 {
+		since = time.Now().Add(-window)
+	}
+
+	history, err := openHistoryForCommand(*configPath)
+	if err != nil {
+		return err
+	}
+
+	for _, ranking := range []struct{ kind, title string }{
+		{"artist", "Top artists"},
+		{"track", "Top tracks"},
+	} {
+		entries, err := history.Top(ranking.kind, since, time.Now(), *limit)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s (%s)\n", ranking.title, *period)
+		if len(entries) == 0 {
+			fmt.Println("  (no listens)")
+		}
+		for i, entry := range entries {
+			fmt.Printf("  %2d. %s — %d plays, %s\n", i+1, entry.Name, entry.Plays, entry.Listened.Round(time.Minute))
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// printJSON writes v as indented JSON to stdout
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
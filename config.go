@@ -0,0 +1,618 @@
+// User configuration
+// Loaded from ~/.config/am-bridge/config.json (override with --config or AM_CONFIG).
+// A missing file is not an error; every field has a working default.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"am-discord-bridge/discord"
+)
+
+// Timestamp modes for the Discord progress display
+const (
+	TimestampsEnd   = "end"   // Countdown to the end of the track
+	TimestampsStart = "start" // Elapsed time counting up
+	TimestampsBoth  = "both"  // Progress bar with total duration
+	TimestampsText  = "text"  // No timestamps; a gauge in State, see gauge.go
+)
+
+// Config holds all user-tunable settings
+type Config struct {
+	Sources      []SourceEntry      `json:"sources"`      // Player priority order, e.g. ["music", "spotify"]
+	PollSeconds  float64            `json:"poll_seconds"` // How often players are checked
+	Discord      DiscordConfig      `json:"discord"`
+	Presence     PresenceConfig     `json:"presence"`
+	Privacy      PrivacyConfig      `json:"privacy"`
+	Artwork      ArtworkConfig      `json:"artwork"`
+	ITunes       ITunesConfig       `json:"itunes"`
+	LocalArtwork LocalArtworkConfig `json:"local_artwork"`
+	Hooks        HookConfig         `json:"hooks"`
+	ListenBrainz ListenBrainzConfig `json:"listenbrainz"`
+	History      HistoryConfig      `json:"history"`
+	Overlay      OverlayConfig      `json:"overlay"`
+	Browser      BrowserConfig      `json:"browser"`
+	Cider        CiderConfig        `json:"cider"`
+	Power        PowerConfig        `json:"power"`
+	Report       ReportConfig       `json:"report"`
+	Network      NetworkConfig      `json:"network"`
+	SongLink     SongLinkConfig     `json:"songlink"`
+	Slack        SlackConfig        `json:"slack"`
+	MQTT         MQTTConfig         `json:"mqtt"`
+	AppleMusic   AppleMusicConfig   `json:"apple_music"`
+	Notify       NotifyConfig       `json:"notify"`
+}
+
+// DiscordConfig controls how the bridge reaches the Discord client
+type DiscordConfig struct {
+	Transport string            `json:"transport"` // "auto", "ipc" or "websocket" (arRPC)
+	AppID     string            `json:"app_id"`    // Overrides the built-in DiscordAppID
+	Profile   string            `json:"profile"`   // Active entry of Profiles; wins over AppID
+	Profiles  map[string]string `json:"profiles"`  // name -> Discord application ID
+	Socket    string            `json:"socket"`    // IPC socket path; empty searches the usual places
+	AppRules  []AppRule         `json:"app_rules"` // Genre/playlist -> profile, first match wins
+	Assets    AssetConfig       `json:"assets"`    // Upload covers as application assets
+	Optional  bool              `json:"optional"`  // Keep publishing to the other sinks while Discord is missing
+}
+
+// ActiveAppID resolves the application ID from the active profile, the explicit
+// app_id, or the built-in default, in that order
+func (d DiscordConfig) ActiveAppID() (string, error) {
+	if d.Profile != "" {
+		appID, ok := d.Profiles[d.Profile]
+		if !ok {
+			return "", fmt.Errorf("unknown Discord profile %q", d.Profile)
+		}
+		return appID, nil
+	}
+	if d.AppID != "" {
+		return d.AppID, nil
+	}
+	return DiscordAppID, nil
+}
+
+// ApplyOverrides layers environment variables and then command-line flags over the
+// file settings. An explicit app ID replaces any configured profile.
+func (d *DiscordConfig) ApplyOverrides(flagAppID, flagProfile string) {
+	for _, override := range []struct{ appID, profile string }{
+		{os.Getenv("AM_DISCORD_APP_ID"), os.Getenv("AM_PROFILE")},
+		{flagAppID, flagProfile},
+	} {
+		if override.appID != "" {
+			d.AppID = override.appID
+			d.Profile = ""
+		}
+		if override.profile != "" {
+			d.Profile = override.profile
+		}
+	}
+}
+
+// ITunesConfig picks the iTunes Search storefront for artwork and catalog lookups
+type ITunesConfig struct {
+	Country string `json:"country"` // ISO 3166 code such as "jp"; the API defaults to "us"
+	Lang    string `json:"lang"`    // e.g. "ja_jp" for Japanese titles
+}
+
+// Small image modes
+const (
+	SmallImageNone  = "none"  // No badge over the album art
+	SmallImageLogo  = "logo"  // Player logo badge
+	SmallImageState = "state" // Play/pause glyph
+)
+
+// Loved-track indicator modes
+const (
+	LovedHidden     = "none"        // No indicator
+	LovedInDetails  = "details"     // Append " ♥" to the Details line
+	LovedSmallImage = "small_image" // Heart badge, replacing the small image for loved tracks
+)
+
+// Stopped player modes
+const (
+	StoppedClear    = "clear"    // Clear the presence, as when the player quits
+	StoppedBrowsing = "browsing" // Show "Browsing library" under the player logo
+)
+
+// lovedMark - Heart appended to Details and rendered by {loved}
+const lovedMark = "♥"
+
+// Play mode marks rendered by {shuffle} and {repeat}
+const (
+	shuffleMark   = "🔀"
+	repeatMark    = "🔁"
+	repeatOneMark = "🔂"
+)
+
+// Play mode display modes
+const (
+	PlayModesHidden      = "none"       // Don't show shuffle and repeat
+	PlayModesInState     = "state"      // Append the marks to the State line
+	PlayModesInSmallText = "small_text" // Show the marks when hovering the small image
+)
+
+// Playlist display modes
+const (
+	PlaylistHidden      = "none"       // Don't show the playlist
+	PlaylistInState     = "state"      // Append "via {playlist}" to the State line
+	PlaylistInSmallText = "small_text" // Show "via {playlist}" when hovering the small image
+)
+
+// Up-next display modes
+const (
+	UpNextHidden      = "none"       // Don't show the next track
+	UpNextInSmallText = "small_text" // Show "Up next: …" when hovering the small image
+	UpNextInLargeText = "large_text" // Append "Up next: …" to the album art tooltip
+)
+
+// Output device display modes
+const (
+	OutputHidden      = "none"       // Don't show AirPlay devices
+	OutputInState     = "state"      // Append "on {output}" to the State line
+	OutputInSmallText = "small_text" // Show "on {output}" when hovering the small image
+)
+
+// PresenceConfig controls how the Discord activity is rendered
+type PresenceConfig struct {
+	Details    string           `json:"details"`    // Template for the first line, e.g. "{name}"
+	State      string           `json:"state"`      // Template for the second line, e.g. "by {artist}"
+	LargeText  string           `json:"large_text"` // Template for the album art tooltip
+	Timestamps string           `json:"timestamps"` // "end", "start", "both" or "text"
+	Gauge      string           `json:"gauge"`      // "time" or "bar", for timestamps "text"
+	SmallImage SmallImageConfig `json:"small_image"`
+	Classical  ClassicalConfig  `json:"classical"`
+	Featured   string           `json:"featured"` // "keep", "move" (into the artist line) or "strip"
+	// ShowPlaylist credits the playing playlist or source: "none", "state" or "small_text"
+	ShowPlaylist string `json:"show_playlist"`
+	// UpNext names the track queued after this one: "none", "small_text" or "large_text"
+	UpNext string `json:"up_next"`
+	// PlayModes marks shuffle and repeat when they are on: "none", "state" or "small_text"
+	PlayModes string `json:"play_modes"`
+	// Output names the AirPlay devices playing, "on HomePod": "none", "state" or "small_text"
+	Output string `json:"output"`
+	// AlbumCleanup rewrites album names before they are shown, in order
+	AlbumCleanup []CleanupRule `json:"album_cleanup"`
+	// Loved marks favorited tracks: "none", "details" or "small_image"
+	Loved string `json:"loved"`
+	// Buttons are shown under the presence, at most two; one missing a value is skipped
+	Buttons []ButtonConfig `json:"buttons"`
+	// CompilationLargeText replaces LargeText for compilation albums; empty uses LargeText
+	CompilationLargeText string `json:"compilation_large_text"`
+	// PauseGraceMinutes keeps a paused track visible this long before clearing; 0 clears at once
+	PauseGraceMinutes float64 `json:"pause_grace_minutes"`
+	// Locale picks the language of built-in text, e.g. "de"; empty follows the system
+	Locale string `json:"locale"`
+	// Stopped is shown while the player runs with nothing loaded: "clear" or "browsing"
+	Stopped string `json:"stopped"`
+	// ClearOnLock hides the presence while the screen is locked
+	ClearOnLock bool `json:"clear_on_lock"`
+	// PartySize shows the track's place on its disc, "(3 of 12)", as Discord's party size
+	PartySize bool `json:"party_size"`
+	// ClearOnFocus hides the presence while a macOS Focus is on
+	ClearOnFocus bool `json:"clear_on_focus"`
+	// Schedule hides the presence at set times of the week
+	Schedule ScheduleConfig `json:"schedule"`
+	// Watching renders shows and movies from the TV app
+	Watching WatchingConfig `json:"watching"`
+	// Audiobook renders audiobooks from Books
+	Audiobook AudiobookConfig `json:"audiobook"`
+}
+
+// ClassicalConfig renders composer-led presence for classical genres
+type ClassicalConfig struct {
+	Genres  []string `json:"genres"`  // Genres using classical templates; empty disables the mode
+	Details string   `json:"details"` // e.g. "{composer}: {work}"
+	State   string   `json:"state"`   // e.g. "{movement} · {artist}"
+}
+
+// Applies reports whether a track is rendered in classical mode: its genre is listed
+// and it has a composer to lead with
+func (c ClassicalConfig) Applies(track *Track) bool {
+	if track.Composer == "" {
+		return false
+	}
+	return slices.ContainsFunc(c.Genres, func(genre string) bool {
+		return strings.EqualFold(genre, track.Genre)
+	})
+}
+
+// showsOutputs reports whether the AirPlay devices are shown, by presence.output or an
+// {output} placeholder
+func (p PresenceConfig) showsOutputs() bool {
+	if p.Output != OutputHidden {
+		return true
+	}
+	return slices.ContainsFunc([]string{p.Details, p.State, p.LargeText, p.CompilationLargeText,
+		p.Classical.Details, p.Classical.State}, func(tmpl string) bool {
+		return strings.Contains(tmpl, "{output}")
+	})
+}
+
+// checkSmallText rejects text meant for the badge's hover when there is no badge: Discord
+// only shows SmallText over a small image
+func (p PresenceConfig) checkSmallText() error {
+	if p.SmallImage.Mode != SmallImageNone {
+		return nil
+	}
+	settings := []struct {
+		name      string
+		smallText bool
+	}{
+		{"presence.show_playlist", p.ShowPlaylist == PlaylistInSmallText},
+		{"presence.up_next", p.UpNext == UpNextInSmallText},
+		{"presence.play_modes", p.PlayModes == PlayModesInSmallText},
+		{"presence.output", p.Output == OutputInSmallText},
+	}
+	for _, setting := range settings {
+		if setting.smallText {
+			return fmt.Errorf("%s %q needs presence.small_image.mode %q or %q",
+				setting.name, "small_text", SmallImageLogo, SmallImageState)
+		}
+	}
+	return nil
+}
+
+// PauseGrace returns the paused-presence grace period
+func (p PresenceConfig) PauseGrace() time.Duration {
+	return time.Duration(p.PauseGraceMinutes * float64(time.Minute))
+}
+
+// SmallImageConfig selects the badge drawn over the album art.
+// Images are Discord application asset keys or https URLs.
+type SmallImageConfig struct {
+	Mode    string            `json:"mode"` // "none", "logo" or "state"
+	Logo    string            `json:"logo"`
+	Logos   map[string]string `json:"logos"` // Logo per player by source name ("Spotify"); others use Logo
+	Playing string            `json:"playing"`
+	Paused  string            `json:"paused"`
+	Loved   string            `json:"loved"` // Heart badge for presence.loved "small_image"
+}
+
+// LogoFor returns the logo of the named player
+func (s SmallImageConfig) LogoFor(source string) string {
+	if logo := s.Logos[source]; logo != "" {
+		return logo
+	}
+	return s.Logo
+}
+
+// PollEvery returns the interval between player polls
+func (c *Config) PollEvery() time.Duration {
+	return time.Duration(c.PollSeconds * float64(time.Second))
+}
+
+// IdlePollEvery returns the relaxed poll interval used once the player has been idle
+func (c *Config) IdlePollEvery() time.Duration {
+	return max(IdlePollInterval, c.PollEvery())
+}
+
+// DefaultConfig returns the settings used when no config file exists
+func DefaultConfig() *Config {
+	return &Config{
+		PollSeconds: PollInterval.Seconds(),
+		Discord: DiscordConfig{
+			Transport: discord.TransportAuto,
+			Assets:    AssetConfig{Keep: 200},
+		},
+		Presence: PresenceConfig{
+			Details:           "{name}",
+			State:             "by {artist}",
+			LargeText:         "{album}",
+			Timestamps:        TimestampsEnd,
+			Gauge:             GaugeTime,
+			PauseGraceMinutes: 5,
+			Featured:          FeaturedKeep,
+			ShowPlaylist:      PlaylistHidden,
+			UpNext:            UpNextHidden,
+			PlayModes:         PlayModesHidden,
+			Output:            OutputHidden,
+			Loved:             LovedHidden,
+			Stopped:           StoppedClear,
+			Buttons: []ButtonConfig{
+				{Label: "Listen on Apple Music", URL: "{apple_music_url}"},
+			},
+			CompilationLargeText: "{album} · {album_artist}",
+			Classical: ClassicalConfig{
+				Genres:  []string{"Classical"},
+				Details: "{composer}: {work}",
+				State:   "{movement} · {artist}",
+			},
+			ClearOnLock: true,
+			Watching: WatchingConfig{
+				Details:   "{name}",
+				State:     "{show} · {episode_code}",
+				LargeText: "{show}",
+			},
+			Audiobook: AudiobookConfig{
+				Details:   "{name}",
+				State:     "by {artist} · {chapter}",
+				LargeText: "{name}",
+			},
+			SmallImage: SmallImageConfig{
+				Mode: SmallImageNone,
+				Logo: "apple_music",
+				Logos: map[string]string{
+					"Spotify":  "spotify",
+					"Cider":    "cider",
+					"Podcasts": "podcasts",
+					"TV":       "tv",
+					"Books":    "books",
+				},
+				Playing: "playing",
+				Paused:  "paused",
+				Loved:   "loved",
+			},
+		},
+		Artwork: ArtworkConfig{
+			Providers:     []string{ProviderITunes, ProviderDeezer},
+			CacheSize:     2000,
+			CacheTTLHours: 7 * 24,
+			Prefetch:      true,
+		},
+		History: HistoryConfig{
+			Enabled: true,
+		},
+		Network: NetworkConfig{
+			MaxQPS: 5,
+		},
+		Cider: CiderConfig{
+			Addr: "localhost:10767",
+		},
+		Power: PowerConfig{
+			Enabled:        true,
+			PollMultiplier: 3,
+		},
+		Notify: NotifyConfig{
+			AfterMinutes: 10,
+		},
+		Report: ReportConfig{
+			Period: ReportWeekly,
+		},
+		SongLink: SongLinkConfig{
+			Label: "Listen on your platform",
+		},
+		Slack: SlackConfig{
+			Emoji: ":musical_note:",
+			Text:  "{name} — {artist}",
+		},
+		MQTT: MQTTConfig{
+			Topic:    "am-bridge/now_playing",
+			ClientID: "am-bridge",
+		},
+	}
+}
+
+// Validate rejects settings the bridge can't act on
+func (c *Config) Validate() error {
+	if err := validateSources(c.Sources); err != nil {
+		return err
+	}
+	if err := c.Browser.validate(); err != nil {
+		return err
+	}
+	if c.Browser.Addr == "" && slices.ContainsFunc(c.Sources, func(e SourceEntry) bool { return e.Name == "web" && e.Enabled }) {
+		return errors.New(`the "web" source requires browser.addr`)
+	}
+	if c.Cider.Addr == "" && slices.ContainsFunc(c.Sources, func(e SourceEntry) bool { return e.Name == "cider" && e.Enabled }) {
+		return errors.New(`the "cider" source requires cider.addr`)
+	}
+	if c.Artwork.CacheSize < 0 || c.Artwork.CacheTTLHours < 0 {
+		return errors.New("artwork.cache_size and artwork.cache_ttl_hours must not be negative")
+	}
+	if c.PollSeconds < 1 {
+		return fmt.Errorf("poll_seconds must be at least 1, got %v", c.PollSeconds)
+	}
+	switch c.Discord.Transport {
+	case discord.TransportAuto, discord.TransportIPC, discord.TransportWebSocket, discord.TransportDryRun:
+	default:
+		return fmt.Errorf("discord.transport must be %q, %q, %q or %q, got %q",
+			discord.TransportAuto, discord.TransportIPC, discord.TransportWebSocket, discord.TransportDryRun, c.Discord.Transport)
+	}
+	if err := c.Discord.validateAppRules(); err != nil {
+		return err
+	}
+	if _, err := c.Discord.ActiveAppID(); err != nil {
+		return err
+	}
+	if err := c.Discord.Assets.validate(); err != nil {
+		return err
+	}
+	switch c.Presence.Timestamps {
+	case TimestampsEnd, TimestampsStart, TimestampsBoth, TimestampsText:
+	default:
+		return fmt.Errorf("presence.timestamps must be %q, %q, %q or %q, got %q",
+			TimestampsEnd, TimestampsStart, TimestampsBoth, TimestampsText, c.Presence.Timestamps)
+	}
+	switch c.Presence.Gauge {
+	case GaugeTime, GaugeBar:
+	default:
+		return fmt.Errorf("presence.gauge must be %q or %q, got %q", GaugeTime, GaugeBar, c.Presence.Gauge)
+	}
+	if err := c.Privacy.compile(); err != nil {
+		return err
+	}
+	if err := compileCleanup("artwork.search_cleanup", c.Artwork.SearchCleanup); err != nil {
+		return err
+	}
+	if err := compileCleanup("presence.album_cleanup", c.Presence.AlbumCleanup); err != nil {
+		return err
+	}
+	for _, tmpl := range []string{c.Presence.Details, c.Presence.State, c.Presence.LargeText,
+		c.Presence.Classical.Details, c.Presence.Classical.State, c.Presence.CompilationLargeText,
+		c.Presence.Watching.Details, c.Presence.Watching.State, c.Presence.Watching.LargeText,
+		c.Presence.Audiobook.Details, c.Presence.Audiobook.State, c.Presence.Audiobook.LargeText} {
+		if err := validateTemplate(tmpl); err != nil {
+			return fmt.Errorf("presence: %w", err)
+		}
+	}
+	switch c.Presence.Featured {
+	case FeaturedKeep, FeaturedMove, FeaturedStrip:
+	default:
+		return fmt.Errorf("presence.featured must be %q, %q or %q, got %q",
+			FeaturedKeep, FeaturedMove, FeaturedStrip, c.Presence.Featured)
+	}
+	if err := c.Presence.Schedule.validate(); err != nil {
+		return err
+	}
+	if c.Presence.PauseGraceMinutes < 0 {
+		return fmt.Errorf("presence.pause_grace_minutes must not be negative")
+	}
+	switch c.Presence.SmallImage.Mode {
+	case SmallImageNone, SmallImageLogo, SmallImageState:
+	default:
+		return fmt.Errorf("presence.small_image.mode must be %q, %q or %q, got %q",
+			SmallImageNone, SmallImageLogo, SmallImageState, c.Presence.SmallImage.Mode)
+	}
+	switch c.Presence.ShowPlaylist {
+	case PlaylistHidden, PlaylistInState, PlaylistInSmallText:
+	default:
+		return fmt.Errorf("presence.show_playlist must be %q, %q or %q, got %q",
+			PlaylistHidden, PlaylistInState, PlaylistInSmallText, c.Presence.ShowPlaylist)
+	}
+	switch c.Presence.UpNext {
+	case UpNextHidden, UpNextInSmallText, UpNextInLargeText:
+	default:
+		return fmt.Errorf("presence.up_next must be %q, %q or %q, got %q",
+			UpNextHidden, UpNextInSmallText, UpNextInLargeText, c.Presence.UpNext)
+	}
+	switch c.Presence.PlayModes {
+	case PlayModesHidden, PlayModesInState, PlayModesInSmallText:
+	default:
+		return fmt.Errorf("presence.play_modes must be %q, %q or %q, got %q",
+			PlayModesHidden, PlayModesInState, PlayModesInSmallText, c.Presence.PlayModes)
+	}
+	switch c.Presence.Output {
+	case OutputHidden, OutputInState, OutputInSmallText:
+	default:
+		return fmt.Errorf("presence.output must be %q, %q or %q, got %q",
+			OutputHidden, OutputInState, OutputInSmallText, c.Presence.Output)
+	}
+	if err := c.Presence.checkSmallText(); err != nil {
+		return err
+	}
+	if len(c.Presence.Buttons) > maxPresenceButtons {
+		return fmt.Errorf("presence.buttons allows at most %d buttons, got %d", maxPresenceButtons, len(c.Presence.Buttons))
+	}
+	for i, button := range c.Presence.Buttons {
+		if err := button.validate(); err != nil {
+			return fmt.Errorf("presence.buttons[%d]: %w", i, err)
+		}
+	}
+	if c.SongLink.Enabled {
+		if err := validateTemplate(c.SongLink.Label); err != nil {
+			return fmt.Errorf("songlink.label: %w", err)
+		}
+	}
+	if err := validateTemplate(c.Slack.Text); err != nil {
+		return fmt.Errorf("slack.text: %w", err)
+	}
+	if _, err := parseClockRange(c.Slack.WorkingHours); err != nil {
+		return fmt.Errorf("slack.working_hours: %w", err)
+	}
+	if err := c.MQTT.validate(); err != nil {
+		return err
+	}
+	if c.AppleMusic.Storefront != "" && !isCountryCode(c.AppleMusic.Storefront) {
+		return fmt.Errorf("apple_music.storefront must be a two-letter country code, got %q", c.AppleMusic.Storefront)
+	}
+	if slices.Contains(c.Artwork.Providers, ProviderAppleMusic) && c.AppleMusic.DeveloperToken == "" {
+		return fmt.Errorf("artwork provider %q requires apple_music.developer_token", ProviderAppleMusic)
+	}
+	switch c.Presence.Loved {
+	case LovedHidden, LovedInDetails, LovedSmallImage:
+	default:
+		return fmt.Errorf("presence.loved must be %q, %q or %q, got %q",
+			LovedHidden, LovedInDetails, LovedSmallImage, c.Presence.Loved)
+	}
+	if err := validateLocale(c.Presence.Locale); err != nil {
+		return err
+	}
+	switch c.Presence.Stopped {
+	case StoppedClear, StoppedBrowsing:
+	default:
+		return fmt.Errorf("presence.stopped must be %q or %q, got %q", StoppedClear, StoppedBrowsing, c.Presence.Stopped)
+	}
+	if c.ITunes.Country != "" && !isCountryCode(c.ITunes.Country) {
+		return fmt.Errorf("itunes.country must be a two-letter country code, got %q", c.ITunes.Country)
+	}
+	if c.Notify.Enabled && c.Notify.AfterMinutes <= 0 {
+		return fmt.Errorf("notify.after_minutes must be positive, got %v", c.Notify.AfterMinutes)
+	}
+	if c.Power.Enabled && c.Power.PollMultiplier < 1 {
+		return fmt.Errorf("power.poll_multiplier must be at least 1, got %v", c.Power.PollMultiplier)
+	}
+	if c.Report.Period != ReportDaily && c.Report.Period != ReportWeekly {
+		return fmt.Errorf("report.period must be %q or %q, got %q", ReportDaily, ReportWeekly, c.Report.Period)
+	}
+	if _, err := newTransport(c.Network); err != nil {
+		return err
+	}
+	if _, err := NewArtworkChain(c.Artwork); err != nil {
+		return err
+	}
+	switch c.LocalArtwork.Host {
+	case "", HostCatbox, HostLitterbox, HostImgur:
+	default:
+		return fmt.Errorf("local_artwork.host must be %q, %q or %q, got %q",
+			HostCatbox, HostLitterbox, HostImgur, c.LocalArtwork.Host)
+	}
+	return nil
+}
+
+// isCountryCode reports whether s looks like an ISO 3166-1 alpha-2 code
+func isCountryCode(s string) bool {
+	if len(s) != 2 {
+		return false
+	}
+	for _, r := range strings.ToLower(s) {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// DefaultConfigPath resolves the config file location
+func DefaultConfigPath() string {
+	if path := os.Getenv("AM_CONFIG"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "config.json"
+	}
+	return filepath.Join(home, ".config", "am-bridge", "config.json")
+}
+
+// LoadConfig reads the config file at path, falling back to defaults if it doesn't exist
+func LoadConfig(path string) (*Config, error) {
+	cfg := DefaultConfig()
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		cfg.localizeDefaults()
+		cfg.loadSecrets()
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	cfg.localizeDefaults()
+	cfg.loadSecrets()
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return cfg, nil
+}
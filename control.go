@@ -0,0 +1,197 @@
+// Control socket
+// The daemon listens on ~/.cache/am-bridge/am-bridge.sock so other invocations of the
+// binary (am-bridge status, ...) can query it. One JSON request and one JSON response per connection.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// controlTimeout - Deadline for a single control request/response exchange
+const controlTimeout = 3 * time.Second
+
+// controlRequest is sent by a client command
+type controlRequest struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
+}
+
+// controlResponse is returned by the daemon
+type controlResponse struct {
+	OK     bool          `json:"ok"`
+	Error  string        `json:"error,omitempty"`
+	Status *DaemonStatus `json:"status,omitempty"`
+}
+
+// DaemonStatus describes the running daemon for `am-bridge status`
+type DaemonStatus struct {
+	PID              int        `json:"pid"`
+	Version          string     `json:"version"`
+	Uptime           string     `json:"uptime"`
+	DiscordConnected bool       `json:"discord_connected"`
+	DiscordUser      string     `json:"discord_user,omitempty"`
+	PresencePaused   bool       `json:"presence_paused"`
+	Profile          string     `json:"profile,omitempty"`
+	State            string     `json:"state"`
+	Track            *Track     `json:"track,omitempty"`
+	ArtworkCache     CacheStats `json:"artwork_cache"`
+	LogFile          string     `json:"log_file,omitempty"` // empty when logging to stderr
+}
+
+// ControlServer answers control requests for a bridge
+type ControlServer struct {
+	bridge   *Bridge
+	quit     func() // stops the daemon as if it received SIGTERM
+	logFile  string // absolute path of the log file, for `am-bridge logs`
+	listener net.Listener
+	started  time.Time
+}
+
+// controlSocketPath returns the Unix socket location
+func controlSocketPath() (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "am-bridge.sock"), nil
+}
+
+// StartControlServer listens on the control socket. Must be called while holding the instance lock,
+// which makes removing a stale socket file safe.
+func StartControlServer(bridge *Bridge, quit func(), logFile string) (*ControlServer, error) {
+	path, err := controlSocketPath()
+	if err != nil {
+		return nil, err
+	}
+	os.Remove(path)
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open control socket: %w", err)
+	}
+
+	if logFile != "" {
+		if abs, err := filepath.Abs(logFile); err == nil {
+			logFile = abs
+		}
+	}
+	s := &ControlServer{bridge: bridge, quit: quit, logFile: logFile, listener: listener, started: time.Now()}
+	go s.serve()
+	return s, nil
+}
+
+// Close stops accepting requests and removes the socket file
+func (s *ControlServer) Close() {
+	if s == nil {
+		return
+	}
+	s.listener.Close()
+}
+
+// serve accepts connections until the listener is closed
+func (s *ControlServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("⚠️  Control socket error: %v", err)
+			}
+			return
+		}
+		goSafe("control request", func() { s.handle(conn) })
+	}
+}
+
+// handle processes a single request
+func (s *ControlServer) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(controlTimeout))
+
+	var req controlRequest
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		json.NewEncoder(conn).Encode(controlResponse{Error: "malformed request"})
+		return
+	}
+
+	json.NewEncoder(conn).Encode(s.dispatch(req))
+}
+
+// dispatch maps a command to its response
+func (s *ControlServer) dispatch(req controlRequest) controlResponse {
+	switch req.Command {
+	case "status":
+		return controlResponse{OK: true, Status: s.status()}
+	case "profile":
+		if len(req.Args) != 1 {
+			return controlResponse{Error: "profile requires a name"}
+		}
+		if err := s.bridge.SwitchProfile(req.Args[0]); err != nil {
+			return controlResponse{Error: err.Error()}
+		}
+		return controlResponse{OK: true, Status: s.status()}
+	case "pause", "resume":
+		s.bridge.SetPresencePaused(req.Command == "pause")
+		return controlResponse{OK: true, Status: s.status()}
+	case "quit":
+		// Answer first; shutdown clears the presence and closes this socket
+		log.Println("🛑 Quit requested over control socket")
+		time.AfterFunc(100*time.Millisecond, s.quit)
+		return controlResponse{OK: true, Status: s.status()}
+	default:
+		return controlResponse{Error: fmt.Sprintf("unknown command %q", req.Command)}
+	}
+}
+
+// status snapshots the bridge for reporting
+func (s *ControlServer) status() *DaemonStatus {
+	track, state := s.bridge.Snapshot()
+	return &DaemonStatus{
+		PID:              os.Getpid(),
+		Version:          version,
+		Uptime:           time.Since(s.started).Round(time.Second).String(),
+		DiscordConnected: s.bridge.Connected(),
+		DiscordUser:      s.bridge.DiscordUser(),
+		PresencePaused:   s.bridge.PresencePaused(),
+		Profile:          s.bridge.Profile(),
+		State:            state.String(),
+		Track:            track,
+		ArtworkCache:     s.bridge.CacheStats(),
+		LogFile:          s.logFile,
+	}
+}
+
+// sendControl issues a command to the running daemon
+func sendControl(command string, args ...string) (*controlResponse, error) {
+	path, err := controlSocketPath()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialTimeout("unix", path, controlTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("am-bridge daemon is not running")
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(controlTimeout))
+
+	if err := json.NewEncoder(conn).Encode(controlRequest{Command: command, Args: args}); err != nil {
+		return nil, err
+	}
+
+	var resp controlResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("invalid response from daemon: %w", err)
+	}
+	if !resp.OK {
+		return nil, errors.New(resp.Error)
+	}
+	return &resp, nil
+}
@@ -0,0 +1,149 @@
+// Panic recovery
+// A bug in one poll or background task shouldn't take the whole daemon down. Panics are
+// recovered, logged and written with their stack trace to ~/.cache/am-bridge/crash.txt,
+// and the daemon carries on; a poll that keeps panicking restarts it instead.
+// `am-bridge bugreport` opens a prefilled GitHub issue; --attach-crash adds crash.txt.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+const (
+	// issuesURL - Where bug reports are filed
+	issuesURL = "https://github.com/ahammednibras8/applemusicdiscord/issues/new"
+
+	// pollPanicLimit - Consecutive panicking polls before the daemon restarts itself
+	pollPanicLimit = 3
+
+	// serviceRestartDelay - Pause before a panicked background loop starts again
+	serviceRestartDelay = time.Second
+
+	// maxAttachedCrash - Bytes of crash.txt put in the issue; longer URLs are rejected
+	maxAttachedCrash = 5000
+)
+
+// guard runs fn, turning a panic into a crash report. It reports whether fn panicked.
+func guard(where string, fn func()) (panicked bool) {
+	defer func() {
+		if value := recover(); value != nil {
+			reportPanic(where, value, debug.Stack())
+			panicked = true
+		}
+	}()
+	fn()
+	return false
+}
+
+// goSafe runs a one-off task in a goroutine that can't crash the daemon
+func goSafe(where string, fn func()) {
+	go guard(where, fn)
+}
+
+// goService runs a long-lived loop in a goroutine, starting it again after a panic
+func goService(where string, fn func()) {
+	go func() {
+		for guard(where, fn) {
+			time.Sleep(serviceRestartDelay)
+		}
+	}()
+}
+
+// reportPanic logs a recovered panic and saves its stack trace
+func reportPanic(where string, value any, stack []byte) {
+	log.Printf("💥 Panic in %s: %v", where, value)
+	path, err := writeCrashFile(where, value, stack)
+	if err != nil {
+		log.Printf("⚠️  Failed to write crash file: %v\n%s", err, stack)
+		return
+	}
+	log.Printf("💥 Stack trace saved to %s ('am-bridge bugreport --attach-crash' files it)", path)
+}
+
+// crashPath returns where the latest crash report lives
+func crashPath() (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "crash.txt"), nil
+}
+
+// writeCrashFile replaces crash.txt with the latest panic
+func writeCrashFile(where string, value any, stack []byte) (string, error) {
+	path, err := crashPath()
+	if err != nil {
+		return "", err
+	}
+	report := fmt.Sprintf("am-bridge %s (%s/%s) panicked in %s at %s\n\npanic: %v\n\n%s",
+		version, runtime.GOOS, runtime.GOARCH, where, time.Now().Format(time.RFC3339), value, stack)
+	if err := os.WriteFile(path, []byte(report), 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// cmdBugreport opens a new GitHub issue with the environment filled in
+func cmdBugreport(args []string) error {
+	fs := flag.NewFlagSet("bugreport", flag.ExitOnError)
+	attach := fs.Bool("attach-crash", false, "include the last crash report (review it before submitting)")
+	printOnly := fs.Bool("print", false, "print the issue URL instead of opening it")
+	fs.Parse(args)
+
+	var body strings.Builder
+	body.WriteString("**What happened?**\n\n\n**What did you expect?**\n\n\n")
+	fmt.Fprintf(&body, "**Environment**\n- am-bridge %s\n- %s\n", version, osVersion())
+
+	path, err := crashPath()
+	if err != nil {
+		return err
+	}
+	crash, err := os.ReadFile(path)
+	switch {
+	case err != nil:
+		if *attach {
+			return fmt.Errorf("no crash report at %s", path)
+		}
+	case *attach:
+		if len(crash) > maxAttachedCrash {
+			crash = append(crash[:maxAttachedCrash], "\n… (truncated; the full file is "+path+")"...)
+		}
+		fmt.Fprintf(&body, "\n<details><summary>crash.txt</summary>\n\n```\n%s\n```\n</details>\n", crash)
+	default:
+		if info, err := os.Stat(path); err == nil {
+			fmt.Fprintf(os.Stderr, "A crash report from %s is at %s; add --attach-crash to include it\n",
+				info.ModTime().Format("2006-01-02 15:04"), path)
+		}
+	}
+
+	params := url.Values{}
+	params.Set("body", body.String())
+	issue := issuesURL + "?" + params.Encode()
+	if *printOnly {
+		fmt.Println(issue)
+		return nil
+	}
+	if err := exec.Command("open", issue).Run(); err != nil {
+		fmt.Println(issue) // no browser to open; copy it by hand
+	}
+	return nil
+}
+
+// osVersion describes the OS for bug reports
+func osVersion() string {
+	if out, err := exec.Command("sw_vers", "-productVersion").Output(); err == nil {
+		return fmt.Sprintf("macOS %s (%s)", strings.TrimSpace(string(out)), runtime.GOARCH)
+	}
+	return runtime.GOOS + "/" + runtime.GOARCH
+}
@@ -4,15 +4,46 @@
 package discord
 
 import (
+	"cmp"
+	"context"
 	"crypto/rand"
-	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"net"
 	"os"
+	"sync"
 	"time"
 )
 
+// IPC opcodes
+const (
+	opHandshake = 0
+	opFrame     = 1
+	opClose     = 2
+	opPing      = 3
+	opPong      = 4
+)
+
+// replyTimeout - How long a command waits for Discord to answer its nonce
+const replyTimeout = 5 * time.Second
+
+// ErrConnectionLost is reported when Discord closes the socket or it stops reading
+var ErrConnectionLost = errors.New("discord connection lost")
+
+// errNotLoggedIn is returned by commands sent before Login
+var errNotLoggedIn = errors.New("not logged in")
+
+// RPCError is an ERROR payload returned by Discord for a command
+type RPCError struct {
+	Cmd     string
+	Code    int
+	Message string
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("%s rejected (code %d): %s", e.Cmd, e.Code, e.Message)
+}
+
 // Activity Types
 const (
 	ActivityTypePlaying   = 0 // "Playing {name}"
@@ -24,17 +55,25 @@ const (
 
 // Activity holds the data for discord rich presence
 type Activity struct {
-	Type       int        // Activity type (0=Playing, 2=Listening, etc.)
-	Details    string     // What the player is currently doing
-	State      string     // The user's current party status
-	LargeImage string     // Large image URL or asset key
-	LargeText  string     // Text displayed when hovering over large image
-	SmallImage string     // Small image URL or asset key
-	SmallText  string     // Text displayed when hovering over small image
+	Type       int    // Activity type (0=Playing, 2=Listening, etc.)
+	Details    string // What the player is currently doing
+	State      string // The user's current party status
+	LargeImage string // Large image URL or asset key
+	LargeText  string // Text displayed when hovering over large image
+	SmallImage string // Small image URL or asset key
+	SmallText  string // Text displayed when hovering over small image
 	Timestamps *Timestamps
+	Party      *Party // Shown as "(Size of Max)" after State
 	Buttons    []*Button
 }
 
+// Party holds the party size shown next to State
+type Party struct {
+	ID   string
+	Size int
+	Max  int
+}
+
 // Timestamps holds unix timestamps for start and/or end
 type Timestamps struct {
 	Start *time.Time
@@ -65,12 +104,18 @@ type args struct {
 }
 
 type payloadActivity struct {
-	Type       int               `json:"type"` // This is the key addition!
-	Details    string            `json:"details,omitempty"`
-	State      string            `json:"state,omitempty"`
-	Assets     payloadAssets     `json:"assets,omitempty"`
+	Type       int                `json:"type"` // This is the key addition!
+	Details    string             `json:"details,omitempty"`
+	State      string             `json:"state,omitempty"`
+	Assets     payloadAssets      `json:"assets,omitempty"`
 	Timestamps *payloadTimestamps `json:"timestamps,omitempty"`
-	Buttons    []*payloadButton  `json:"buttons,omitempty"`
+	Party      *payloadParty      `json:"party,omitempty"`
+	Buttons    []*payloadButton   `json:"buttons,omitempty"`
+}
+
+type payloadParty struct {
+	ID   string `json:"id,omitempty"`
+	Size [2]int `json:"size"` // current, max
 }
 
 type payloadAssets struct {
@@ -90,66 +135,319 @@ type payloadButton struct {
 	Url   string `json:"url,omitempty"`
 }
 
+// response is a frame sent back by Discord
+type response struct {
+	Cmd   string          `json:"cmd"`
+	Evt   string          `json:"evt"`
+	Nonce string          `json:"nonce"`
+	Data  json.RawMessage `json:"data"`
+}
+
+type errorData struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type readyData struct {
+	V      int `json:"v"`
+	Config struct {
+		CDNHost     string `json:"cdn_host"`
+		APIEndpoint string `json:"api_endpoint"`
+	} `json:"config"`
+	User struct {
+		ID            string `json:"id"`
+		Username      string `json:"username"`
+		Discriminator string `json:"discriminator"`
+		GlobalName    string `json:"global_name"`
+	} `json:"user"`
+}
+
+// Ready describes the session Discord confirmed in its READY dispatch
+type Ready struct {
+	Version     int    // RPC version
+	CDNHost     string // e.g. "cdn.discordapp.com"
+	APIEndpoint string
+	User        User
+}
+
+// User is the Discord account the client is signed in to
+type User struct {
+	ID            string
+	Username      string
+	Discriminator string // "0" for accounts on the new unique usernames
+	GlobalName    string // Display name, may be empty
+}
+
+// Handle formats the user as @username, or name#1234 for legacy accounts
+func (u User) Handle() string {
+	if u.Discriminator != "" && u.Discriminator != "0" {
+		return fmt.Sprintf("%s#%s", u.Username, u.Discriminator)
+	}
+	return "@" + u.Username
+}
+
+// parseReady interprets the reply to the handshake; anything but READY is a failure
+func parseReady(opcode uint32, data []byte) (*Ready, error) {
+	if opcode == opClose {
+		var e errorData
+		json.Unmarshal(data, &e)
+		return nil, fmt.Errorf("discord closed the connection (code %d): %s", e.Code, e.Message)
+	}
+
+	var resp response
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("malformed handshake reply: %w", err)
+	}
+	switch resp.Evt {
+	case "READY":
+	case "ERROR":
+		var e errorData
+		json.Unmarshal(resp.Data, &e)
+		return nil, &RPCError{Cmd: "HANDSHAKE", Code: e.Code, Message: e.Message}
+	default:
+		return nil, fmt.Errorf("unexpected handshake reply %s/%s", resp.Cmd, resp.Evt)
+	}
+
+	var d readyData
+	if err := json.Unmarshal(resp.Data, &d); err != nil {
+		return nil, fmt.Errorf("malformed READY payload: %w", err)
+	}
+	return &Ready{
+		Version:     d.V,
+		CDNHost:     d.Config.CDNHost,
+		APIEndpoint: d.Config.APIEndpoint,
+		User: User{
+			ID:            d.User.ID,
+			Username:      d.User.Username,
+			Discriminator: d.User.Discriminator,
+			GlobalName:    d.User.GlobalName,
+		},
+	}, nil
+}
+
 // Client manages the Discord RPC connection
 type Client struct {
 	clientID string
-	conn     net.Conn
+	mode     string
+	socket   string     // IPC socket path; empty searches the usual locations
+	trace    Tracer     // receives every frame when set
+	mu       sync.Mutex // guards conn, logged, ready and replies
+	conn     transport
 	logged   bool
+	ready    *Ready
+	replies  map[string]chan error // commands awaiting Discord's answer, by nonce
+	errs     chan error
 }
 
-// NewClient creates a new Discord RPC client
+// NewClient creates a new Discord RPC client using automatic transport selection
 func NewClient(clientID string) *Client {
 	return &Client{
 		clientID: clientID,
+		mode:     TransportAuto,
+		replies:  make(map[string]chan error),
+		errs:     make(chan error, 16),
 	}
 }
 
+// SetClientID changes the application ID used by the next Login
+func (c *Client) SetClientID(clientID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clientID = clientID
+}
+
+// ClientID returns the application ID used by the next Login
+func (c *Client) ClientID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.clientID
+}
+
+// SetTransport selects how the client reaches Discord (TransportAuto, TransportIPC
+// or TransportWebSocket). Takes effect on the next Login.
+func (c *Client) SetTransport(mode string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.mode = mode
+}
+
+// SetSocket pins the IPC socket path instead of searching for it ("" restores the
+// search). Takes effect on the next Login.
+func (c *Client) SetSocket(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.socket = path
+}
+
+// Errors delivers asynchronous failures: *RPCError for rejected commands no caller was
+// waiting on and ErrConnectionLost when the socket dies. The client is logged out before ErrConnectionLost is sent.
+func (c *Client) Errors() <-chan error {
+	return c.errs
+}
+
 // Login connects to Discord RPC
 func (c *Client) Login() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.logged {
 		return nil
 	}
 
 	// Find Discord socket
-	conn, err := openSocket()
+	conn, err := openTransport(c.mode, c.clientID, c.socket)
 	if err != nil {
 		return fmt.Errorf("failed to connect to Discord: %w", err)
 	}
-	c.conn = conn
+	if c.trace != nil {
+		conn = &tracedTransport{conn, c.trace}
+	}
 
 	// Send handshake
 	payload, err := json.Marshal(handshake{"1", c.clientID})
 	if err != nil {
+		conn.Close()
 		return err
 	}
 
-	if err := c.send(0, payload); err != nil {
+	if err := conn.Send(opHandshake, payload); err != nil {
+		conn.Close()
 		return err
 	}
 
-	// Read response (we don't parse it, just confirm connection)
-	if _, err := c.receive(); err != nil {
+	// Discord answers with READY, or ERROR/CLOSE if it rejected the client ID
+	opcode, reply, err := conn.Receive()
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("handshake failed: %w", err)
+	}
+	ready, err := parseReady(opcode, reply)
+	if err != nil {
+		conn.Close()
 		return fmt.Errorf("handshake failed: %w", err)
 	}
 
+	c.conn = conn
 	c.logged = true
+	c.ready = ready
+	go c.readLoop(conn)
 	return nil
 }
 
+// Ready returns the session details from the last successful Login (nil when logged out)
+func (c *Client) Ready() *Ready {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ready
+}
+
 // Logout disconnects from Discord RPC
 func (c *Client) Logout() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.conn != nil {
 		c.conn.Close()
 		c.conn = nil
 	}
 	c.logged = false
+	c.ready = nil
+	c.failRepliesLocked(errNotLoggedIn)
 }
 
-// SetActivity updates the Discord Rich Presence
-func (c *Client) SetActivity(activity Activity) error {
-	if !c.logged {
-		return fmt.Errorf("not logged in")
+// readLoop consumes every frame Discord sends so the socket buffer never fills,
+// reporting ERROR responses and connection loss on the Errors channel
+func (c *Client) readLoop(conn transport) {
+	for {
+		opcode, data, err := conn.Receive()
+		if err != nil {
+			c.connectionLost(conn)
+			return
+		}
+
+		switch opcode {
+		case opFrame:
+			var resp response
+			if err := json.Unmarshal(data, &resp); err != nil {
+				continue
+			}
+			var rpcErr error
+			if resp.Evt == "ERROR" {
+				var e errorData
+				json.Unmarshal(resp.Data, &e)
+				rpcErr = &RPCError{Cmd: resp.Cmd, Code: e.Code, Message: e.Message}
+			}
+			if !c.answer(resp.Nonce, rpcErr) && rpcErr != nil {
+				c.report(rpcErr)
+			}
+
+		case opPing:
+			c.mu.Lock()
+			if c.conn == conn {
+				conn.Send(opPong, data)
+			}
+			c.mu.Unlock()
+
+		case opClose:
+			c.connectionLost(conn)
+			return
+		}
+	}
+}
+
+// connectionLost logs out if conn is still the active connection and reports it
+func (c *Client) connectionLost(conn transport) {
+	c.mu.Lock()
+	active := c.conn == conn
+	if active {
+		c.conn.Close()
+		c.conn = nil
+		c.logged = false
+		c.ready = nil
+		c.failRepliesLocked(ErrConnectionLost)
+	}
+	c.mu.Unlock()
+
+	// A deliberate Logout closes the socket too; only report unexpected loss
+	if active {
+		c.report(ErrConnectionLost)
+	}
+}
+
+// answer hands the outcome of a command to the caller waiting on its nonce, reporting
+// whether there was one
+func (c *Client) answer(nonce string, err error) bool {
+	c.mu.Lock()
+	reply, ok := c.replies[nonce]
+	delete(c.replies, nonce)
+	c.mu.Unlock()
+	if ok {
+		reply <- err
+	}
+	return ok
+}
+
+// failRepliesLocked fails every waiting command; call with c.mu held
+func (c *Client) failRepliesLocked(err error) {
+	for nonce, reply := range c.replies {
+		reply <- err
+		delete(c.replies, nonce)
 	}
+}
+
+// report queues an async error, dropping it if nobody is listening
+func (c *Client) report(err error) {
+	select {
+	case c.errs <- err:
+	default:
+	}
+}
+
+// SetActivity updates the Discord Rich Presence, returning once Discord has accepted it
+// or with the *RPCError it rejected it with. A write still blocked when ctx ends is
+// abandoned by closing the connection.
+func (c *Client) SetActivity(ctx context.Context, activity Activity) error {
+	activity = activity.sanitize()
 
 	// Map activity to payload
 	pa := &payloadActivity{
@@ -176,6 +474,13 @@ func (c *Client) SetActivity(activity Activity) error {
 		}
 	}
 
+	if activity.Party != nil {
+		pa.Party = &payloadParty{
+			ID:   activity.Party.ID,
+			Size: [2]int{activity.Party.Size, activity.Party.Max},
+		}
+	}
+
 	for _, btn := range activity.Buttons {
 		pa.Buttons = append(pa.Buttons, &payloadButton{
 			Label: btn.Label,
@@ -183,65 +488,73 @@ func (c *Client) SetActivity(activity Activity) error {
 		})
 	}
 
-	payload, err := json.Marshal(frame{
-		Cmd:   "SET_ACTIVITY",
-		Args:  args{os.Getpid(), pa},
-		Nonce: nonce(),
-	})
-	if err != nil {
-		return err
-	}
-
-	return c.send(1, payload)
+	return c.command(ctx, "SET_ACTIVITY", args{os.Getpid(), pa})
 }
 
-// ClearActivity clears the current presence
-func (c *Client) ClearActivity() error {
-	if !c.logged {
+// ClearActivity clears the current presence, waiting and abandoning the write like
+// SetActivity. Clearing while logged out does nothing.
+func (c *Client) ClearActivity(ctx context.Context) error {
+	err := c.command(ctx, "SET_ACTIVITY", args{Pid: os.Getpid(), Activity: nil})
+	if errors.Is(err, errNotLoggedIn) {
 		return nil
 	}
+	return err
+}
 
-	payload, err := json.Marshal(frame{
-		Cmd:   "SET_ACTIVITY",
-		Args:  args{Pid: os.Getpid(), Activity: nil},
-		Nonce: nonce(),
-	})
+// command sends cmd and waits for the reply carrying its nonce. Discord answers every
+// command, so no reply within replyTimeout means it is ignoring us.
+func (c *Client) command(ctx context.Context, cmd string, a args) error {
+	id := nonce()
+	payload, err := json.Marshal(frame{Cmd: cmd, Args: a, Nonce: id})
 	if err != nil {
 		return err
 	}
 
-	return c.send(1, payload)
-}
-
-// send writes a message to the Discord socket
-func (c *Client) send(opcode uint32, payload []byte) error {
-	header := make([]byte, 8)
-	binary.LittleEndian.PutUint32(header[0:4], opcode)
-	binary.LittleEndian.PutUint32(header[4:8], uint32(len(payload)))
-
-	if _, err := c.conn.Write(header); err != nil {
+	reply := make(chan error, 1)
+	c.mu.Lock()
+	if !c.logged {
+		c.mu.Unlock()
+		return errNotLoggedIn
+	}
+	c.replies[id] = reply
+	if err := c.send(ctx, payload); err != nil {
+		delete(c.replies, id)
+		c.mu.Unlock()
 		return err
 	}
-	if _, err := c.conn.Write(payload); err != nil {
+	c.mu.Unlock()
+
+	timer := time.NewTimer(replyTimeout)
+	defer timer.Stop()
+	select {
+	case err := <-reply:
 		return err
+	case <-timer.C:
+		err = fmt.Errorf("%s: no reply from Discord within %v", cmd, replyTimeout)
+	case <-ctx.Done():
+		err = ctx.Err()
 	}
-	return nil
+	c.mu.Lock()
+	delete(c.replies, id)
+	c.mu.Unlock()
+	return err
 }
 
-// receive reads a message from the Discord socket
-func (c *Client) receive() ([]byte, error) {
-	header := make([]byte, 8)
-	if _, err := c.conn.Read(header); err != nil {
-		return nil, err
+// send writes a command frame; call with c.mu held. The transport gives up on a write
+// after writeTimeout; closing the connection interrupts it sooner when ctx ends, and
+// the read loop then reports it as lost.
+func (c *Client) send(ctx context.Context, payload []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
+	conn := c.conn
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
 
-	length := binary.LittleEndian.Uint32(header[4:8])
-	data := make([]byte, length)
-	if _, err := c.conn.Read(data); err != nil {
-		return nil, err
+	if err := conn.Send(opFrame, payload); err != nil {
+		return cmp.Or(ctx.Err(), err)
 	}
-
-	return data, nil
+	return nil
 }
 
 // nonce generates a random nonce for RPC requests
@@ -251,30 +564,3 @@ func nonce() string {
 	buf[6] = (buf[6] & 0x0f) | 0x40
 	return fmt.Sprintf("%x-%x-%x-%x-%x", buf[0:4], buf[4:6], buf[6:8], buf[8:10], buf[10:])
 }
-
-// openSocket connects to the Discord IPC socket (macOS/Linux)
-func openSocket() (net.Conn, error) {
-	// Try different socket paths
-	tmpDirs := []string{
-		os.Getenv("XDG_RUNTIME_DIR"),
-		os.Getenv("TMPDIR"),
-		os.Getenv("TMP"),
-		os.Getenv("TEMP"),
-		"/tmp",
-	}
-
-	for _, tmpDir := range tmpDirs {
-		if tmpDir == "" {
-			continue
-		}
-		for i := 0; i < 10; i++ {
-			path := fmt.Sprintf("%s/discord-ipc-%d", tmpDir, i)
-			conn, err := net.Dial("unix", path)
-			if err == nil {
-				return conn, nil
-			}
-		}
-	}
-
-	return nil, fmt.Errorf("Discord IPC socket not found")
-}
@@ -0,0 +1,390 @@
+// Fake Discord client
+// Package discordtest stands in for the Discord desktop app the way net/http/httptest
+// stands in for a web server: Server listens on a discord-ipc-0 Unix socket, answers the
+// handshake with READY and every command with a reply carrying its nonce, and records
+// what it was sent. Frames the real client would refuse (an invalid client ID, a field
+// over Discord's limits) are answered with the same ERROR or CLOSE Discord sends, and
+// their problems are kept with the recorded frame.
+
+package discordtest
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// IPC opcodes
+const (
+	opHandshake = 0
+	opFrame     = 1
+	opClose     = 2
+	opPing      = 3
+	opPong      = 4
+)
+
+// Discord's limits on SET_ACTIVITY fields, checked independently of the client's own clamping
+const (
+	maxTextLength  = 128
+	minTextLength  = 2
+	maxLabelLength = 32
+	maxButtons     = 2
+	maxURLLength   = 512
+	maxFrame       = 1 << 20
+)
+
+// errInvalidPayload - RPC error code Discord uses for a malformed command
+const errInvalidPayload = 4000
+
+// snowflake matches a Discord application ID
+var snowflake = regexp.MustCompile(`^[0-9]{17,20}$`)
+
+// Handshake is the opening frame of a connection
+type Handshake struct {
+	Version  string   `json:"v"`
+	ClientID string   `json:"client_id"`
+	Problems []string `json:"-"` // why Discord would close the connection; empty when accepted
+}
+
+// Command is a frame sent after the handshake
+type Command struct {
+	Cmd      string
+	Nonce    string
+	Pid      int
+	Activity *Activity // nil clears the presence
+	Received time.Time
+	Problems []string // why Discord would reject it; empty when accepted
+}
+
+// Activity is a SET_ACTIVITY payload as it appears on the wire
+type Activity struct {
+	Type       int         `json:"type"`
+	Details    string      `json:"details,omitempty"`
+	State      string      `json:"state,omitempty"`
+	Assets     Assets      `json:"assets"`
+	Timestamps *Timestamps `json:"timestamps,omitempty"`
+	Party      *Party      `json:"party,omitempty"`
+	Buttons    []Button    `json:"buttons,omitempty"`
+}
+
+// Assets holds the images and their hover texts
+type Assets struct {
+	LargeImage string `json:"large_image,omitempty"`
+	LargeText  string `json:"large_text,omitempty"`
+	SmallImage string `json:"small_image,omitempty"`
+	SmallText  string `json:"small_text,omitempty"`
+}
+
+// Timestamps are unix milliseconds
+type Timestamps struct {
+	Start *int64 `json:"start,omitempty"`
+	End   *int64 `json:"end,omitempty"`
+}
+
+// Party holds the party size as [current, max]
+type Party struct {
+	ID   string `json:"id,omitempty"`
+	Size []int  `json:"size"`
+}
+
+// Button is a link under the activity
+type Button struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
+}
+
+// Server is a fake Discord desktop client
+type Server struct {
+	Socket string // path of the socket; pass it to Client.SetSocket
+
+	dir      string
+	listener net.Listener
+
+	mu         sync.Mutex
+	handshakes []Handshake
+	commands   []Command
+	conns      map[net.Conn]struct{}
+	changed    chan struct{} // closed and replaced whenever a frame is recorded
+}
+
+// NewServer starts a fake client on a fresh socket in a temporary directory
+func NewServer() (*Server, error) {
+	// Unix socket paths are short (104 bytes on macOS), so stay out of deep temp dirs
+	dir, err := os.MkdirTemp("", "discordtest")
+	if err != nil {
+		return nil, err
+	}
+	socket := filepath.Join(dir, "discord-ipc-0")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to listen on %s: %w", socket, err)
+	}
+
+	s := &Server{
+		Socket:   socket,
+		dir:      dir,
+		listener: listener,
+		conns:    make(map[net.Conn]struct{}),
+		changed:  make(chan struct{}),
+	}
+	go s.accept()
+	return s, nil
+}
+
+// Close stops listening, drops every connection and removes the socket
+func (s *Server) Close() {
+	s.listener.Close()
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	os.RemoveAll(s.dir)
+}
+
+// Handshakes returns every handshake received so far
+func (s *Server) Handshakes() []Handshake {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Handshake(nil), s.handshakes...)
+}
+
+// Commands returns every command received so far, oldest first
+func (s *Server) Commands() []Command {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Command(nil), s.commands...)
+}
+
+// Activity returns the presence Discord would be showing: the last accepted
+// SET_ACTIVITY, or nil when it was a clear or none has arrived
+func (s *Server) Activity() *Activity {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.commands) - 1; i >= 0; i-- {
+		if command := s.commands[i]; command.Cmd == "SET_ACTIVITY" && len(command.Problems) == 0 {
+			return command.Activity
+		}
+	}
+	return nil
+}
+
+// Wait blocks until a received command satisfies match, earlier ones included, or ctx ends
+func (s *Server) Wait(ctx context.Context, match func(Command) bool) (Command, error) {
+	seen := 0
+	for {
+		s.mu.Lock()
+		commands, changed := s.commands[seen:], s.changed
+		seen = len(s.commands)
+		s.mu.Unlock()
+
+		for _, command := range commands {
+			if match(command) {
+				return command, nil
+			}
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return Command{}, ctx.Err()
+		}
+	}
+}
+
+// notifyLocked wakes every Wait to look at the new frame; call with s.mu held
+func (s *Server) notifyLocked() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// accept serves connections until Close
+func (s *Server) accept() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+		go s.serve(conn)
+	}
+}
+
+// serve runs one connection: the handshake, then commands until either side closes
+func (s *Server) serve(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+	}()
+
+	opcode, payload, err := receive(conn)
+	if err != nil {
+		return
+	}
+	var hello Handshake
+	if opcode != opHandshake || json.Unmarshal(payload, &hello) != nil {
+		hello.Problems = append(hello.Problems, "first frame is not a handshake")
+	}
+	hello.Problems = append(hello.Problems, checkHandshake(hello)...)
+	s.mu.Lock()
+	s.handshakes = append(s.handshakes, hello)
+	s.notifyLocked()
+	s.mu.Unlock()
+	if len(hello.Problems) > 0 {
+		reply, _ := json.Marshal(map[string]any{"code": errInvalidPayload, "message": hello.Problems[0]})
+		send(conn, opClose, reply)
+		return
+	}
+	send(conn, opFrame, []byte(`{"cmd":"DISPATCH","evt":"READY","nonce":null,"data":{"v":1,`+
+		`"config":{"cdn_host":"cdn.discordapp.com","api_endpoint":"//discord.com/api"},`+
+		`"user":{"id":"1","username":"discordtest","discriminator":"0"}}}`))
+
+	for {
+		opcode, payload, err := receive(conn)
+		if err != nil {
+			return
+		}
+		switch opcode {
+		case opFrame:
+			s.command(conn, payload)
+		case opPing:
+			send(conn, opPong, payload)
+		case opClose:
+			return
+		}
+	}
+}
+
+// command records a frame and replies to it the way Discord would
+func (s *Server) command(conn net.Conn, payload []byte) {
+	var frame struct {
+		Cmd   string `json:"cmd"`
+		Nonce string `json:"nonce"`
+		Args  struct {
+			Pid      int       `json:"pid"`
+			Activity *Activity `json:"activity"`
+		} `json:"args"`
+	}
+	command := Command{Received: time.Now()}
+	if err := json.Unmarshal(payload, &frame); err != nil {
+		command.Problems = []string{fmt.Sprintf("malformed frame: %v", err)}
+	} else {
+		command.Cmd, command.Nonce = frame.Cmd, frame.Nonce
+		command.Pid, command.Activity = frame.Args.Pid, frame.Args.Activity
+		command.Problems = checkCommand(command)
+	}
+	s.mu.Lock()
+	s.commands = append(s.commands, command)
+	s.notifyLocked()
+	s.mu.Unlock()
+
+	reply := map[string]any{"cmd": command.Cmd, "nonce": command.Nonce, "evt": nil, "data": command.Activity}
+	if len(command.Problems) > 0 {
+		reply["evt"] = "ERROR"
+		reply["data"] = map[string]any{"code": errInvalidPayload, "message": command.Problems[0]}
+	}
+	data, _ := json.Marshal(reply)
+	send(conn, opFrame, data)
+}
+
+// checkHandshake lists what Discord would object to in a handshake
+func checkHandshake(hello Handshake) []string {
+	var problems []string
+	if hello.Version != "1" {
+		problems = append(problems, fmt.Sprintf("unsupported RPC version %q", hello.Version))
+	}
+	if !snowflake.MatchString(hello.ClientID) {
+		problems = append(problems, fmt.Sprintf("invalid client ID %q", hello.ClientID))
+	}
+	return problems
+}
+
+// checkCommand lists what Discord would object to in a command
+func checkCommand(command Command) []string {
+	if command.Cmd != "SET_ACTIVITY" {
+		return []string{fmt.Sprintf("unknown command %q", command.Cmd)}
+	}
+	var problems []string
+	if command.Nonce == "" {
+		problems = append(problems, "missing nonce")
+	}
+	if command.Pid <= 0 {
+		problems = append(problems, "args.pid must be the sender's process ID")
+	}
+	if a := command.Activity; a != nil {
+		switch a.Type {
+		case 0, 2, 3, 5:
+		default:
+			problems = append(problems, fmt.Sprintf("activity.type %d is not allowed", a.Type))
+		}
+		problems = append(problems, checkText("activity.details", a.Details)...)
+		problems = append(problems, checkText("activity.state", a.State)...)
+		problems = append(problems, checkText("activity.assets.large_text", a.Assets.LargeText)...)
+		problems = append(problems, checkText("activity.assets.small_text", a.Assets.SmallText)...)
+		if len(a.Buttons) > maxButtons {
+			problems = append(problems, fmt.Sprintf("activity.buttons has %d buttons, at most %d are allowed", len(a.Buttons), maxButtons))
+		}
+		for i, button := range a.Buttons {
+			if n := len([]rune(button.Label)); n < 1 || n > maxLabelLength {
+				problems = append(problems, fmt.Sprintf("activity.buttons[%d].label must be 1 to %d characters", i, maxLabelLength))
+			}
+			if n := len(button.URL); n < 1 || n > maxURLLength {
+				problems = append(problems, fmt.Sprintf("activity.buttons[%d].url must be 1 to %d characters", i, maxURLLength))
+			}
+		}
+		if p := a.Party; p != nil && (len(p.Size) != 2 || p.Size[0] < 1 || p.Size[1] < p.Size[0]) {
+			problems = append(problems, fmt.Sprintf("activity.party.size %v must be [current, max] with 1 <= current <= max", p.Size))
+		}
+		if t := a.Timestamps; t != nil && t.Start != nil && t.End != nil && *t.End < *t.Start {
+			problems = append(problems, "activity.timestamps.end is before start")
+		}
+	}
+	return problems
+}
+
+// checkText reports a set text field outside Discord's length limits
+func checkText(field, text string) []string {
+	if n := len([]rune(text)); text != "" && (n < minTextLength || n > maxTextLength) {
+		return []string{fmt.Sprintf("%s must be %d to %d characters, got %d", field, minTextLength, maxTextLength, n)}
+	}
+	return nil
+}
+
+// receive reads one framed message
+func receive(conn net.Conn) (uint32, []byte, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return 0, nil, err
+	}
+	length := binary.LittleEndian.Uint32(header[4:8])
+	if length > maxFrame {
+		return 0, nil, fmt.Errorf("frame too large (%d bytes)", length)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		return 0, nil, err
+	}
+	return binary.LittleEndian.Uint32(header[0:4]), payload, nil
+}
+
+// send writes one framed message
+func send(conn net.Conn, opcode uint32, payload []byte) error {
+	frame := make([]byte, 8+len(payload))
+	binary.LittleEndian.PutUint32(frame[0:4], opcode)
+	binary.LittleEndian.PutUint32(frame[4:8], uint32(len(payload)))
+	copy(frame[8:], payload)
+	_, err := conn.Write(frame)
+	return err
+}
@@ -0,0 +1,73 @@
+// Dry-run transport
+// Stands in for Discord during development: the handshake always succeeds and every
+// command is logged instead of sent, so the full presence pipeline runs without a client.
+
+package discord
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"sync"
+)
+
+// dryRunTransport logs outgoing frames and answers the handshake with a fake READY
+type dryRunTransport struct {
+	replies   chan []byte
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// openDryRun returns a transport that never touches the network
+func openDryRun() (transport, error) {
+	return &dryRunTransport{
+		replies: make(chan []byte, 16),
+		closed:  make(chan struct{}),
+	}, nil
+}
+
+// Send logs commands and accepts each one; a handshake queues the READY reply Login waits for
+func (t *dryRunTransport) Send(opcode uint32, payload []byte) error {
+	switch opcode {
+	case opHandshake:
+		t.reply([]byte(`{"cmd":"DISPATCH","evt":"READY","data":{"v":1,"user":{"username":"dry-run","discriminator":"0"}}}`))
+	case opFrame:
+		var pretty bytes.Buffer
+		if json.Indent(&pretty, payload, "", "  ") != nil {
+			pretty.Write(payload)
+		}
+		log.Printf("🧪 [dry-run] %s", pretty.String())
+
+		var command frame
+		json.Unmarshal(payload, &command)
+		accepted, _ := json.Marshal(response{Cmd: command.Cmd, Nonce: command.Nonce, Data: json.RawMessage("null")})
+		t.reply(accepted)
+	}
+	return nil
+}
+
+// reply queues a frame for Receive; the read loop drains them, so a full queue only
+// drops replies when nothing is listening
+func (t *dryRunTransport) reply(data []byte) {
+	select {
+	case t.replies <- data:
+	default:
+	}
+}
+
+// Receive returns queued replies and blocks until Close otherwise
+func (t *dryRunTransport) Receive() (uint32, []byte, error) {
+	select {
+	case reply := <-t.replies:
+		return opFrame, reply, nil
+	case <-t.closed:
+		return 0, nil, io.EOF
+	}
+}
+
+// Close unblocks Receive
+func (t *dryRunTransport) Close() error {
+	t.closeOnce.Do(func() { close(t.closed) })
+	return nil
+}
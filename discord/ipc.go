@@ -0,0 +1,124 @@
+// Native IPC transport
+// Discord desktop listens on a Unix socket; every message is framed by an 8-byte
+// little-endian header (opcode, payload length).
+
+package discord
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// ipcMaxFrame - Largest payload accepted; anything bigger means the stream is out of sync
+const ipcMaxFrame = 1 << 20
+
+// ipcTransport speaks the framed protocol over the discord-ipc-N Unix socket
+type ipcTransport struct {
+	conn   net.Conn
+	reader *bufio.Reader // a header and its payload usually arrive in one socket read
+}
+
+// newIPCTransport wraps a connected socket
+func newIPCTransport(conn net.Conn) *ipcTransport {
+	return &ipcTransport{conn: conn, reader: bufio.NewReader(conn)}
+}
+
+// Send writes a message to the Discord socket as a single write so frames never interleave
+// and header and payload can't be split by a failure between them
+func (t *ipcTransport) Send(opcode uint32, payload []byte) error {
+	frame := make([]byte, 8+len(payload))
+	binary.LittleEndian.PutUint32(frame[0:4], opcode)
+	binary.LittleEndian.PutUint32(frame[4:8], uint32(len(payload)))
+	copy(frame[8:], payload)
+
+	return writeFull(t.conn, frame)
+}
+
+// Receive reads one complete message, looping over short reads; a frame may span
+// several socket reads and a lone Read can return half a header
+func (t *ipcTransport) Receive() (uint32, []byte, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(t.reader, header); err != nil {
+		return 0, nil, err
+	}
+
+	opcode := binary.LittleEndian.Uint32(header[0:4])
+	length := binary.LittleEndian.Uint32(header[4:8])
+	if length > ipcMaxFrame {
+		return 0, nil, fmt.Errorf("discord frame too large (%d bytes)", length)
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(t.reader, data); err != nil {
+		return 0, nil, err
+	}
+
+	return opcode, data, nil
+}
+
+// Close closes the socket
+func (t *ipcTransport) Close() error {
+	return t.conn.Close()
+}
+
+// sandboxDirs - Where Flatpak and Snap builds of Discord put the socket, relative to a temp dir
+var sandboxDirs = []string{
+	"app/com.discordapp.Discord",
+	"app/com.discordapp.DiscordCanary",
+	"snap.discord",
+	"snap.discord-canary",
+}
+
+// socketDirs lists the directories that may hold discord-ipc-N, most likely first
+func socketDirs() []string {
+	tmpDirs := []string{
+		os.Getenv("XDG_RUNTIME_DIR"),
+		os.Getenv("TMPDIR"),
+		os.Getenv("TMP"),
+		os.Getenv("TEMP"),
+		"/tmp",
+	}
+	if os.Getenv("XDG_RUNTIME_DIR") == "" {
+		tmpDirs = append(tmpDirs, fmt.Sprintf("/run/user/%d", os.Getuid()))
+	}
+
+	var dirs []string
+	for _, tmpDir := range tmpDirs {
+		if tmpDir == "" {
+			continue
+		}
+		dirs = append(dirs, tmpDir)
+		for _, sandbox := range sandboxDirs {
+			dirs = append(dirs, filepath.Join(tmpDir, sandbox))
+		}
+	}
+	return dirs
+}
+
+// openSocket connects to the Discord IPC socket (macOS/Linux); a non-empty path is
+// the only socket tried
+func openSocket(path string) (transport, error) {
+	if path != "" {
+		conn, err := net.Dial("unix", path)
+		if err != nil {
+			return nil, fmt.Errorf("Discord IPC socket not reachable: %w", err)
+		}
+		return newIPCTransport(conn), nil
+	}
+
+	for _, dir := range socketDirs() {
+		for i := 0; i < 10; i++ {
+			conn, err := net.Dial("unix", filepath.Join(dir, fmt.Sprintf("discord-ipc-%d", i)))
+			if err == nil {
+				return newIPCTransport(conn), nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("Discord IPC socket not found")
+}
@@ -0,0 +1,66 @@
+// Field sanitization
+// Discord rejects SET_ACTIVITY outright when a single field is out of bounds, so every
+// activity is clamped to the documented limits before it is sent.
+
+package discord
+
+import "strings"
+
+// Discord activity limits
+const (
+	maxTextLength  = 128 // details, state and asset hover text
+	minTextLength  = 2
+	maxLabelLength = 32
+	maxButtons     = 2
+	maxURLLength   = 512
+	ellipsis       = "…"
+	zeroWidthSpace = "\u200b"
+)
+
+// sanitize returns a copy of the activity that fits Discord's limits
+func (a Activity) sanitize() Activity {
+	a.Details = clampText(a.Details, maxTextLength)
+	a.State = clampText(a.State, maxTextLength)
+	a.LargeText = clampText(a.LargeText, maxTextLength)
+	a.SmallText = clampText(a.SmallText, maxTextLength)
+
+	buttons := make([]*Button, 0, min(len(a.Buttons), maxButtons))
+	for _, btn := range a.Buttons {
+		if len(buttons) == maxButtons {
+			break
+		}
+		label := truncate(strings.TrimSpace(btn.Label), maxLabelLength)
+		if label == "" || btn.Url == "" || len(btn.Url) > maxURLLength {
+			continue
+		}
+		buttons = append(buttons, &Button{Label: label, Url: btn.Url})
+	}
+	a.Buttons = buttons
+
+	// Discord rejects a party whose size is zero or above its maximum
+	if a.Party != nil && (a.Party.Size < 1 || a.Party.Max < a.Party.Size) {
+		a.Party = nil
+	}
+	return a
+}
+
+// clampText trims, truncates and pads a field; empty fields stay empty so they are omitted
+func clampText(s string, max int) string {
+	s = truncate(strings.TrimSpace(s), max)
+	if s == "" {
+		return ""
+	}
+	for len([]rune(s)) < minTextLength {
+		s += zeroWidthSpace
+	}
+	return s
+}
+
+// truncate shortens s to max runes, ending with an ellipsis when cut
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return strings.TrimSpace(string(runes[:max-1])) + ellipsis
+}
@@ -0,0 +1,60 @@
+// Frame tracing
+// A client with a tracer passes every frame it exchanges with Discord to it, opcode and
+// raw JSON, so a bug report can show exactly what was sent and what came back.
+
+package discord
+
+import "fmt"
+
+// Tracer receives one formatted line per frame
+type Tracer func(format string, args ...any)
+
+// opcodeNames labels frames in traces
+var opcodeNames = map[uint32]string{
+	opHandshake: "HANDSHAKE",
+	opFrame:     "FRAME",
+	opClose:     "CLOSE",
+	opPing:      "PING",
+	opPong:      "PONG",
+}
+
+// opcodeName returns the name of an opcode, or its number when unknown
+func opcodeName(opcode uint32) string {
+	if name, ok := opcodeNames[opcode]; ok {
+		return name
+	}
+	return fmt.Sprintf("opcode %d", opcode)
+}
+
+// tracedTransport reports every frame passing through the transport it wraps
+type tracedTransport struct {
+	transport
+	trace Tracer
+}
+
+// Send traces and sends a frame
+func (t *tracedTransport) Send(opcode uint32, payload []byte) error {
+	err := t.transport.Send(opcode, payload)
+	if err != nil {
+		t.trace("Discord → %s %s (failed: %v)", opcodeName(opcode), payload, err)
+	} else {
+		t.trace("Discord → %s %s", opcodeName(opcode), payload)
+	}
+	return err
+}
+
+// Receive reads and traces a frame
+func (t *tracedTransport) Receive() (uint32, []byte, error) {
+	opcode, payload, err := t.transport.Receive()
+	if err == nil {
+		t.trace("Discord ← %s %s", opcodeName(opcode), payload)
+	}
+	return opcode, payload, err
+}
+
+// SetTracer passes every frame of the next Login's connection to trace; nil stops tracing
+func (c *Client) SetTracer(trace Tracer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.trace = trace
+}
@@ -0,0 +1,83 @@
+// Transport selection
+// The native Unix socket is used by the official client; arRPC-compatible bridges
+// (Vesktop, WebCord) expose the same RPC protocol over a local WebSocket instead.
+
+package discord
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+// writeTimeout - A frame Discord hasn't accepted by then means the client is wedged
+const writeTimeout = 5 * time.Second
+
+// Transport modes for Client.SetTransport
+const (
+	TransportAuto      = "auto"      // Unix socket first, then WebSocket
+	TransportIPC       = "ipc"       // Native discord-ipc-N Unix socket only
+	TransportWebSocket = "websocket" // arRPC ws://127.0.0.1:6463-6472 only
+	TransportDryRun    = "dry-run"   // Log payloads instead of sending them
+)
+
+// transport carries RPC messages to and from Discord
+type transport interface {
+	Send(opcode uint32, payload []byte) error
+	Receive() (opcode uint32, payload []byte, err error)
+	Close() error
+}
+
+// writeFull writes one complete frame under writeTimeout. A failed write may have left
+// part of the frame on the wire, so the connection is closed rather than reused; the
+// read loop then reports it as lost.
+func writeFull(conn net.Conn, frame []byte) error {
+	conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := conn.Write(frame); err != nil {
+		conn.Close()
+		return err
+	}
+	return nil
+}
+
+// openTransport connects using the requested mode; socket overrides the IPC socket search
+func openTransport(mode, clientID, socket string) (transport, error) {
+	switch mode {
+	case TransportIPC:
+		return openSocket(socket)
+	case TransportWebSocket:
+		return openWebSocket(clientID)
+	case TransportDryRun:
+		return openDryRun()
+	case TransportAuto, "":
+		if conn, err := openSocket(socket); err == nil {
+			return conn, nil
+		}
+		if conn, err := openWebSocket(clientID); err == nil {
+			return conn, nil
+		}
+		return nil, fmt.Errorf("no Discord IPC socket or arRPC WebSocket found")
+	default:
+		return nil, fmt.Errorf("unknown transport %q", mode)
+	}
+}
+
+// Probe opens and immediately closes a transport, describing what it found
+func Probe(mode, clientID, socket string) (string, error) {
+	conn, err := openTransport(mode, clientID, socket)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	switch t := conn.(type) {
+	case *ipcTransport:
+		return fmt.Sprintf("IPC socket %s", t.conn.RemoteAddr()), nil
+	case *wsTransport:
+		return fmt.Sprintf("arRPC WebSocket %s", t.conn.RemoteAddr()), nil
+	case *dryRunTransport:
+		return "dry run (nothing is sent)", nil
+	default:
+		return "connected", nil
+	}
+}
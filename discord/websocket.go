@@ -0,0 +1,232 @@
+// arRPC WebSocket transport
+// Minimal RFC 6455 client for arRPC-compatible servers on ws://127.0.0.1:6463-6472.
+// The handshake is carried in the URL query, so the IPC handshake frame is dropped and
+// every text message is surfaced as an opFrame. No Origin header is sent because arRPC
+// only accepts discord.com origins or none at all.
+
+package discord
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	wsPortFirst   = 6463
+	wsPortLast    = 6472
+	wsDialTimeout = 500 * time.Millisecond
+	wsGUID        = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	wsMaxMessage  = 1 << 20
+)
+
+// WebSocket frame opcodes
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xA
+)
+
+// wsTransport is a client-side WebSocket connection
+type wsTransport struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	writeMu sync.Mutex
+}
+
+// openWebSocket scans the arRPC port range for a server accepting our client ID
+func openWebSocket(clientID string) (transport, error) {
+	for port := wsPortFirst; port <= wsPortLast; port++ {
+		if t, err := dialWebSocket(port, clientID); err == nil {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("arRPC WebSocket not found on ports %d-%d", wsPortFirst, wsPortLast)
+}
+
+// dialWebSocket performs the HTTP upgrade on one port
+func dialWebSocket(port int, clientID string) (*wsTransport, error) {
+	host := fmt.Sprintf("127.0.0.1:%d", port)
+	conn, err := net.DialTimeout("tcp", host, wsDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	keyBytes := make([]byte, 16)
+	rand.Read(keyBytes)
+	key := base64.StdEncoding.EncodeToString(keyBytes)
+
+	request := fmt.Sprintf("GET /?v=1&client_id=%s&encoding=json HTTP/1.1\r\n"+
+		"Host: %s\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: %s\r\n"+
+		"Sec-WebSocket-Version: 13\r\n\r\n", clientID, host, key)
+
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+	defer conn.SetDeadline(time.Time{})
+
+	if _, err := io.WriteString(conn, request); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, fmt.Errorf("websocket upgrade refused: %s", resp.Status)
+	}
+
+	sum := sha1.Sum([]byte(key + wsGUID))
+	if resp.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(sum[:]) {
+		conn.Close()
+		return nil, fmt.Errorf("websocket upgrade: bad accept key")
+	}
+
+	return &wsTransport{conn: conn, reader: reader}, nil
+}
+
+// Send writes payload as a masked text frame. The IPC handshake is implicit in the URL.
+func (t *wsTransport) Send(opcode uint32, payload []byte) error {
+	switch opcode {
+	case opHandshake:
+		return nil
+	case opPong:
+		return t.writeFrame(wsPong, payload)
+	default:
+		return t.writeFrame(wsText, payload)
+	}
+}
+
+// Receive returns the next complete message, answering pings along the way
+func (t *wsTransport) Receive() (uint32, []byte, error) {
+	var message []byte
+	for {
+		fin, op, payload, err := t.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch op {
+		case wsPing:
+			if err := t.writeFrame(wsPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case wsPong:
+			continue
+		case wsClose:
+			return opClose, payload, nil
+		case wsText, wsBinary, wsContinuation:
+			message = append(message, payload...)
+			if len(message) > wsMaxMessage {
+				return 0, nil, fmt.Errorf("websocket message exceeds %d bytes", wsMaxMessage)
+			}
+			if fin {
+				return opFrame, message, nil
+			}
+		default:
+			return 0, nil, fmt.Errorf("unexpected websocket opcode %#x", op)
+		}
+	}
+}
+
+// Close sends a close frame and closes the connection
+func (t *wsTransport) Close() error {
+	t.writeFrame(wsClose, nil)
+	return t.conn.Close()
+}
+
+// writeFrame writes a single masked frame in one Write call
+func (t *wsTransport) writeFrame(op byte, payload []byte) error {
+	frame := []byte{0x80 | op}
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, 0x80|byte(n))
+	case n <= 0xFFFF:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+
+	mask := make([]byte, 4)
+	rand.Read(mask)
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	return writeFull(t.conn, frame)
+}
+
+// readFrame reads one (unmasked, server-to-client) frame
+func (t *wsTransport) readFrame() (fin bool, op byte, payload []byte, err error) {
+	header := make([]byte, 2)
+	if _, err = io.ReadFull(t.reader, header); err != nil {
+		return
+	}
+	fin = header[0]&0x80 != 0
+	op = header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err = io.ReadFull(t.reader, ext); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err = io.ReadFull(t.reader, ext); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(ext)
+	}
+	if length > wsMaxMessage {
+		err = fmt.Errorf("websocket frame exceeds %d bytes", wsMaxMessage)
+		return
+	}
+
+	var mask []byte
+	if masked {
+		mask = make([]byte, 4)
+		if _, err = io.ReadFull(t.reader, mask); err != nil {
+			return
+		}
+	}
+
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(t.reader, payload); err != nil {
+		return
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return
+}
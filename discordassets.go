@@ -0,0 +1,297 @@
+// Discord asset upload
+// Some Discord clients are set not to load images from external URLs, which leaves the
+// large image blank. With discord.assets.enabled, each cover is uploaded once as an asset
+// of the Discord application and published by asset name instead of URL. The URL is kept
+// until the upload has finished, and whenever it fails.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	// discordAPI - Base URL of Discord's HTTP API
+	discordAPI = "https://discord.com/api/v10"
+
+	// discordAssetLimit - Assets Discord allows per application
+	discordAssetLimit = 300
+
+	// discordAssetPrefix - Marks assets uploaded by the bridge; only these are ever deleted
+	discordAssetPrefix = "am_"
+
+	// discordAssetMaxBytes - Largest cover downloaded for upload
+	discordAssetMaxBytes = 8 << 20
+)
+
+// AssetConfig uploads covers to the Discord application's assets
+type AssetConfig struct {
+	Enabled bool   `json:"enabled"`
+	Token   string `json:"token"` // Bot token of the application, or "Bearer <oauth token>"
+	Keep    int    `json:"keep"`  // Uploaded covers kept before the oldest are deleted
+}
+
+// validate checks the token and the number of covers to keep
+func (c AssetConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Token == "" {
+		return fmt.Errorf("discord.assets.enabled requires discord.assets.token")
+	}
+	if c.Keep < 1 || c.Keep > discordAssetLimit {
+		return fmt.Errorf("discord.assets.keep must be between 1 and %d, got %d", discordAssetLimit, c.Keep)
+	}
+	return nil
+}
+
+// authorization returns the Authorization header value, treating a bare token as a bot token
+func (c AssetConfig) authorization() string {
+	if strings.HasPrefix(c.Token, "Bot ") || strings.HasPrefix(c.Token, "Bearer ") {
+		return c.Token
+	}
+	return "Bot " + c.Token
+}
+
+// discordAsset is one entry of an application's asset list
+type discordAsset struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// appAssets is what AssetUploader knows about one application's assets
+type appAssets struct {
+	names map[string]bool // every asset name
+	ours  []discordAsset  // assets uploaded by the bridge, oldest first
+}
+
+// AssetUploader uploads covers as Discord application assets, remembering what each
+// application already has
+type AssetUploader struct {
+	config  AssetConfig
+	mu      sync.Mutex
+	apps    map[string]*appAssets // application ID -> its assets
+	pending map[string]bool       // uploads in flight or failed, by app ID and asset name
+}
+
+// NewAssetUploader returns nil when asset upload is disabled
+func NewAssetUploader(cfg AssetConfig) *AssetUploader {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &AssetUploader{config: cfg, apps: make(map[string]*appAssets), pending: make(map[string]bool)}
+}
+
+// assetName derives a stable asset name from the cover URL
+func assetName(imageURL string) string {
+	sum := sha256.Sum256([]byte(imageURL))
+	return discordAssetPrefix + hex.EncodeToString(sum[:12])
+}
+
+// Key returns the asset name for a cover already uploaded to the application
+func (u *AssetUploader) Key(appID, imageURL string) (string, bool) {
+	name := assetName(imageURL)
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	app := u.apps[appID]
+	if app == nil || !app.names[name] {
+		return "", false
+	}
+	return name, true
+}
+
+// UploadAsync uploads a cover in the background and calls done once it is available.
+// A failed upload isn't retried until the uploader is rebuilt by a reload.
+func (u *AssetUploader) UploadAsync(ctx context.Context, appID, imageURL string, done func()) {
+	pendingKey := appID + "/" + assetName(imageURL)
+	u.mu.Lock()
+	if u.pending[pendingKey] {
+		u.mu.Unlock()
+		return
+	}
+	u.pending[pendingKey] = true
+	u.mu.Unlock()
+
+	goSafe("asset upload", func() {
+		if err := u.upload(ctx, appID, imageURL); err != nil {
+			log.Printf("⚠️  Discord asset upload failed, using the artwork URL: %v", err)
+			return
+		}
+		u.mu.Lock()
+		delete(u.pending, pendingKey)
+		u.mu.Unlock()
+		done()
+	})
+}
+
+// upload makes sure the application has the cover, deleting the oldest covers the
+// bridge uploaded when discord.assets.keep is reached
+func (u *AssetUploader) upload(ctx context.Context, appID, imageURL string) error {
+	app, err := u.assets(ctx, appID)
+	if err != nil {
+		return err
+	}
+	name := assetName(imageURL)
+	u.mu.Lock()
+	exists := app.names[name]
+	u.mu.Unlock()
+	if exists {
+		return nil
+	}
+
+	for {
+		u.mu.Lock()
+		if len(app.ours) < u.config.Keep {
+			u.mu.Unlock()
+			break
+		}
+		oldest := app.ours[0]
+		app.ours = app.ours[1:]
+		delete(app.names, oldest.Name)
+		u.mu.Unlock()
+
+		if err := u.call(ctx, http.MethodDelete, fmt.Sprintf("/oauth2/applications/%s/assets/%s", appID, oldest.ID), nil, nil); err != nil {
+			return fmt.Errorf("failed to delete old asset: %w", err)
+		}
+	}
+
+	image, err := downloadImage(ctx, imageURL)
+	if err != nil {
+		return err
+	}
+	var created discordAsset
+	body := map[string]any{"name": name, "type": 1, "image": image}
+	if err := u.call(ctx, http.MethodPost, fmt.Sprintf("/oauth2/applications/%s/assets", appID), body, &created); err != nil {
+		return err
+	}
+
+	u.mu.Lock()
+	app.names[name] = true
+	app.ours = append(app.ours, created)
+	u.mu.Unlock()
+	log.Printf("📤 Uploaded cover as Discord asset %s", name)
+	return nil
+}
+
+// assets returns the application's assets, listing them on first use
+func (u *AssetUploader) assets(ctx context.Context, appID string) (*appAssets, error) {
+	u.mu.Lock()
+	app := u.apps[appID]
+	u.mu.Unlock()
+	if app != nil {
+		return app, nil
+	}
+
+	var list []discordAsset
+	if err := u.call(ctx, http.MethodGet, fmt.Sprintf("/oauth2/applications/%s/assets", appID), nil, &list); err != nil {
+		return nil, fmt.Errorf("failed to list assets: %w", err)
+	}
+	app = &appAssets{names: make(map[string]bool)}
+	for _, asset := range list { // listed oldest first
+		app.names[asset.Name] = true
+		if strings.HasPrefix(asset.Name, discordAssetPrefix) {
+			app.ours = append(app.ours, asset)
+		}
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if existing := u.apps[appID]; existing != nil {
+		return existing, nil // listed concurrently by another upload
+	}
+	u.apps[appID] = app
+	return app, nil
+}
+
+// call sends an authorized request to the Discord API, decoding a JSON reply into v
+func (u *AssetUploader) call(ctx context.Context, method, path string, body, v any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, discordAPI+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", u.config.authorization())
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("Discord rejected discord.assets.token (status %d)", resp.StatusCode)
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("Discord API status %d", resp.StatusCode)
+	case v == nil:
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// downloadImage fetches a cover and encodes it as the data URI the assets API expects
+func downloadImage(ctx context.Context, imageURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to download cover: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download cover: status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, discordAssetMaxBytes+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > discordAssetMaxBytes {
+		return "", fmt.Errorf("cover is larger than %d MB", discordAssetMaxBytes>>20)
+	}
+	mime := http.DetectContentType(data)
+	switch mime {
+	case "image/png", "image/jpeg", "image/gif":
+	default:
+		return "", fmt.Errorf("cover is %s, not an image Discord accepts", mime)
+	}
+	return fmt.Sprintf("data:%s;base64,%s", mime, base64.StdEncoding.EncodeToString(data)), nil
+}
+
+// largeImage swaps a cover URL for its Discord asset, starting the upload when the
+// application doesn't have it yet; call with b.mu held
+func (b *Bridge) largeImage(artworkURL string) string {
+	if b.assets == nil || !isWebURL(artworkURL) {
+		return artworkURL
+	}
+	appID := b.client.ClientID()
+	if key, ok := b.assets.Key(appID, artworkURL); ok {
+		return key
+	}
+	b.assets.UploadAsync(b.ctx, appID, artworkURL, b.Refresh)
+	return artworkURL
+}
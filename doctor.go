@@ -0,0 +1,155 @@
+// Diagnostics
+// `am-bridge doctor` walks through everything the daemon depends on and prints a fix
+// for each failure, since a missing Automation grant otherwise just looks like silence.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+
+	"am-discord-bridge/discord"
+)
+
+// doctorCheck is the outcome of a single diagnostic
+type doctorCheck struct {
+	name   string
+	detail string
+	fix    string // empty when the check passed
+}
+
+// cmdDoctor runs every diagnostic and fails if any check did
+func cmdDoctor(args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	configPath := fs.String("config", DefaultConfigPath(), "path to the JSON config file")
+	fs.Parse(args)
+
+	var checks []doctorCheck
+	config, err := LoadConfig(*configPath)
+	if err != nil {
+		checks = append(checks, doctorCheck{"Config", err.Error(),
+			fmt.Sprintf("Fix or remove %s; defaults are used when it is missing", *configPath)})
+		config = DefaultConfig()
+	} else {
+		checks = append(checks, doctorCheck{"Config", *configPath, ""})
+	}
+	ConfigureITunes(config.ITunes)
+	ConfigureAppleMusic(config.AppleMusic, config.ITunes)
+	ConfigureCider(config.Cider)
+	if err := ConfigureNetwork(config.Network); err != nil {
+		checks = append(checks, doctorCheck{"Network", err.Error(), "Fix network.proxy or network.ca_bundle"})
+	}
+
+	// Player checks only mean something once osascript itself works
+	scripting := checkOSAScript()
+	checks = append(checks, scripting...)
+	if scripting[len(scripting)-1].fix == "" {
+		checks = append(checks, checkSources(config)...)
+	}
+	checks = append(checks, checkDiscord(config), checkITunes())
+
+	if failed := printChecks(checks); failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+	fmt.Println("All checks passed")
+	return nil
+}
+
+// printChecks prints each check with its fix and returns how many failed
+func printChecks(checks []doctorCheck) (failed int) {
+	for _, check := range checks {
+		if check.fix == "" {
+			fmt.Printf("✅ %s: %s\n", check.name, check.detail)
+			continue
+		}
+		failed++
+		fmt.Printf("❌ %s: %s\n", check.name, check.detail)
+		fmt.Printf("   → %s\n", check.fix)
+	}
+	return failed
+}
+
+// checkOSAScript verifies osascript exists and may drive System Events
+func checkOSAScript() []doctorCheck {
+	path, err := exec.LookPath("osascript")
+	if err != nil {
+		return []doctorCheck{{"osascript", "not found in PATH", "am-bridge only runs on macOS"}}
+	}
+	checks := []doctorCheck{{"osascript", path, ""}}
+
+	cmd := exec.Command(path, "-e", `tell application "System Events" to count processes`)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		message := strings.TrimSpace(string(output))
+		fix := "Run osascript manually from the same terminal to see the error"
+		if automationDenied(parseScriptError(message)) {
+			fix = "Allow your terminal (or am-bridge) under System Settings → Privacy & Security → Automation, then rerun"
+		}
+		return append(checks, doctorCheck{"Automation permission", message, fix})
+	}
+	return append(checks, doctorCheck{"Automation permission", "System Events responds", ""})
+}
+
+// checkSources asks each configured player for its state
+func checkSources(config *Config) []doctorCheck {
+	sources, err := NewSourceManager(config.Sources, OSAScriptRunner{})
+	if err != nil {
+		return []doctorCheck{{"Sources", err.Error(), "Set \"sources\" to known players such as \"music\" or \"spotify\""}}
+	}
+
+	var checks []doctorCheck
+	for _, source := range sources.Sources() {
+		state, err := source.State(context.Background())
+		if err != nil {
+			checks = append(checks, doctorCheck{source.Name(), err.Error(),
+				fmt.Sprintf("Allow control of %s under System Settings → Privacy & Security → Automation", source.Name())})
+			continue
+		}
+		checks = append(checks, doctorCheck{source.Name(), state.String(), ""})
+	}
+	return checks
+}
+
+// checkDiscord looks for a Discord socket or arRPC server
+func checkDiscord(config *Config) doctorCheck {
+	appID, err := config.Discord.ActiveAppID()
+	if err != nil {
+		return doctorCheck{"Discord", err.Error(), "Check discord.profile and discord.profiles in the config"}
+	}
+
+	found, err := discord.Probe(config.Discord.Transport, appID, config.Discord.Socket)
+	if err != nil {
+		return doctorCheck{"Discord", err.Error(),
+			"Start the Discord desktop app, or enable arRPC in Vesktop/WebCord and set discord.transport to \"websocket\""}
+	}
+	return doctorCheck{"Discord", found, ""}
+}
+
+// checkITunes performs a tiny search to confirm the artwork API is reachable
+func checkITunes() doctorCheck {
+	params := url.Values{}
+	params.Set("term", "apple")
+	params.Set("limit", "1")
+
+	resp, err := httpClient.Get(iTunesRequestURL(params))
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			err = fmt.Errorf("HTTP %d", resp.StatusCode)
+		}
+	}
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return doctorCheck{"iTunes API", err.Error(), "Check your network connection; presence is sent without album art until it is reachable"}
+	}
+	return doctorCheck{"iTunes API", "reachable", ""}
+}
@@ -0,0 +1,124 @@
+// Browser extension
+// `am-bridge extension [dir]` writes the companion extension for the Apple Music web
+// player, pointed at browser.addr, ready to load unpacked in Chrome, Edge or Firefox.
+// The content script reads the page's Media Session metadata and <audio> element; the
+// background script does the posting, since pages may not talk to localhost themselves.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cmdExtension writes the browser extension to a directory
+func cmdExtension(args []string) error {
+	fs := flag.NewFlagSet("extension", flag.ExitOnError)
+	configPath := fs.String("config", DefaultConfigPath(), "path to the JSON config file")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: am-bridge extension [-config path] [dir]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	config, err := LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if config.Browser.Addr == "" {
+		return fmt.Errorf(`browser.addr is not set; add "browser": {"addr": "127.0.0.1:7880"} to the config first`)
+	}
+	host, port, err := net.SplitHostPort(config.Browser.Addr)
+	if err != nil {
+		return fmt.Errorf("invalid browser.addr: %w", err)
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+
+	dir := "am-bridge-extension"
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	// Match patterns can't hold a port, so the permission covers the whole host
+	hostPattern := host
+	if strings.Contains(host, ":") {
+		hostPattern = "[" + host + "]"
+	}
+	files := map[string]string{
+		"manifest.json": strings.ReplaceAll(extensionManifest, "{{HOST}}", hostPattern),
+		"background.js": strings.ReplaceAll(extensionBackground, "{{ENDPOINT}}", "http://"+net.JoinHostPort(host, port)+"/now-playing"),
+		"content.js":    extensionContent,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("✅ Extension written to %s\n", dir)
+	fmt.Println("   Chrome/Edge: chrome://extensions → Developer mode → Load unpacked")
+	fmt.Println("   Firefox: about:debugging → This Firefox → Load Temporary Add-on → manifest.json")
+	fmt.Println(`   Then add "web" to sources and restart the bridge.`)
+	return nil
+}
+
+// extensionManifest declares the content script and the localhost permission
+const extensionManifest = `{
+  "manifest_version": 3,
+  "name": "am-bridge",
+  "version": "1.0",
+  "description": "Shares what the Apple Music web player is playing with am-bridge",
+  "host_permissions": ["http://{{HOST}}/*"],
+  "background": { "service_worker": "background.js", "scripts": ["background.js"] },
+  "content_scripts": [{ "matches": ["https://music.apple.com/*"], "js": ["content.js"] }]
+}
+`
+
+// extensionBackground forwards reports from the content script to the bridge
+const extensionBackground = `const endpoint = "{{ENDPOINT}}";
+
+chrome.runtime.onMessage.addListener((message) => {
+  const request = message.gone
+    ? { method: "DELETE" }
+    : { method: "POST", headers: { "Content-Type": "application/json" }, body: JSON.stringify(message.report) };
+  fetch(endpoint, request).catch(() => {}); // the bridge isn't running
+});
+`
+
+// extensionContent reports the web player's state on every change and every 5 seconds
+const extensionContent = `function snapshot() {
+  const metadata = navigator.mediaSession && navigator.mediaSession.metadata;
+  const player = document.querySelector("audio");
+  if (!metadata || !player) return { title: "" };
+  const artwork = (metadata.artwork || []).map((image) => image.src).pop() || "";
+  return {
+    title: metadata.title || "",
+    artist: metadata.artist || "",
+    album: metadata.album || "",
+    artwork: artwork.replace(/\/\d+x\d+[a-z]*\.(jpg|png|webp)$/, "/600x600bb.jpg"),
+    duration: Number.isFinite(player.duration) ? player.duration : 0,
+    position: player.currentTime || 0,
+    playing: !player.paused,
+  };
+}
+
+function report() {
+  chrome.runtime.sendMessage({ report: snapshot() }).catch(() => {});
+}
+
+// Media events don't bubble, so listen while they are captured
+for (const event of ["play", "pause", "seeked", "loadedmetadata", "ended"]) {
+  document.addEventListener(event, report, true);
+}
+setInterval(report, 5000);
+window.addEventListener("pagehide", () => chrome.runtime.sendMessage({ gone: true }).catch(() => {}));
+`
@@ -0,0 +1,141 @@
+// Featured artists
+// Titles like "Song (feat. A & B)" and artists like "Main ft. A" are split so the feature
+// list can be moved to the State line or dropped. Joint credits like "A, B & C" or "A; B"
+// are split too, so templates can name the primary artist and searches can fall back to it.
+
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Featured-artist handling modes
+const (
+	FeaturedKeep  = "keep"  // Show names exactly as the player reports them
+	FeaturedMove  = "move"  // Strip features from the title and append them to the artist
+	FeaturedStrip = "strip" // Drop features entirely
+)
+
+var (
+	// featuredBracketed matches " (feat. X)", " [ft. X]" and " (with X)" anywhere in a name
+	featuredBracketed = regexp.MustCompile(`(?i)\s*[(\[]\s*(?:feat\.?|ft\.?|featuring|with)\s+([^)\]]+)[)\]]`)
+	// featuredTrailing matches an unbracketed " feat. X" to the end; "with" is too common
+	// in real titles to split on without brackets
+	featuredTrailing = regexp.MustCompile(`(?i)\s+(?:feat\.?|ft\.?|featuring)\s+(.+)$`)
+	// artistSeparator splits joint credits on commas, semicolons and a spaced "&" or "/"
+	artistSeparator = regexp.MustCompile(`\s*[,;]\s*|\s+[&/]\s+`)
+)
+
+// splitFeatured separates a name from the artists it features
+func splitFeatured(s string) (main string, featured []string) {
+	main = s
+	for _, re := range []*regexp.Regexp{featuredBracketed, featuredTrailing} {
+		for _, match := range re.FindAllStringSubmatch(main, -1) {
+			featured = append(featured, strings.TrimSpace(match[1]))
+		}
+		main = re.ReplaceAllString(main, "")
+	}
+	return strings.TrimSpace(main), featured
+}
+
+// featuredArtists lists every featured artist of a track, title first, without duplicates
+func featuredArtists(track *Track) []string {
+	_, fromName := splitFeatured(track.Name)
+	_, fromArtist := splitFeatured(track.Artist)
+
+	var all []string
+	seen := map[string]bool{}
+	for _, name := range append(fromName, fromArtist...) {
+		if key := strings.ToLower(name); !seen[key] {
+			seen[key] = true
+			all = append(all, name)
+		}
+	}
+	return all
+}
+
+// mainArtist returns the artist without any featured artists
+func mainArtist(artist string) string {
+	main, _ := splitFeatured(artist)
+	return main
+}
+
+// artistList splits a track's artist credit into its artists, features excluded. Band
+// names contain the same separators ("Earth, Wind & Fire"), so the credit is only split
+// when the album is filed under another artist; a credit the album shares, or one on an
+// album without an album artist, is one artist.
+func artistList(track *Track) []string {
+	main := mainArtist(track.Artist)
+	if main == "" || track.AlbumArtist == "" || strings.EqualFold(mainArtist(track.AlbumArtist), main) {
+		return []string{main}
+	}
+	return splitArtists(main)
+}
+
+// splitArtists splits a credit on the joint-credit separators
+func splitArtists(credit string) []string {
+	var artists []string
+	for _, name := range artistSeparator.Split(credit, -1) {
+		if name = strings.TrimSpace(name); name != "" {
+			artists = append(artists, name)
+		}
+	}
+	if len(artists) == 0 {
+		return []string{credit}
+	}
+	return artists
+}
+
+// primaryArtist returns the first artist of the credit, for templates and search fallbacks
+func primaryArtist(track *Track) string {
+	return artistList(track)[0]
+}
+
+// searchArtists lists the artists to search a track by: the primary artist, then the full
+// credit when it names several, for catalogs that list the whole credit as one artist
+func searchArtists(track *Track) []string {
+	if primary := primaryArtist(track); !strings.EqualFold(primary, track.Artist) {
+		return []string{primary, track.Artist}
+	}
+	return []string{track.Artist}
+}
+
+// creditMatches reports whether a search result's artist credit is the artist searched
+// for, on its own or first among several
+func creditMatches(artist, credit string) bool {
+	return namesMatch(artist, credit) || namesMatch(artist, splitArtists(mainArtist(credit))[0])
+}
+
+// applyFeatured returns a copy of the track with features handled per mode
+func applyFeatured(track *Track, mode string) *Track {
+	if mode != FeaturedMove && mode != FeaturedStrip {
+		return track
+	}
+
+	featured := featuredArtists(track)
+	if len(featured) == 0 {
+		return track
+	}
+
+	clean := *track
+	clean.Name, _ = splitFeatured(track.Name)
+	clean.Artist = mainArtist(track.Artist)
+	if mode == FeaturedStrip {
+		// Still available to templates as {featured}
+		clean.Featured = strings.Join(featured, ", ")
+	}
+	if mode == FeaturedMove {
+		// "A & B" with "(with B)" in the title already credits B
+		var extra []string
+		for _, name := range featured {
+			if !strings.Contains(strings.ToLower(clean.Artist), strings.ToLower(name)) {
+				extra = append(extra, name)
+			}
+		}
+		if len(extra) > 0 {
+			clean.Artist += " feat. " + strings.Join(extra, ", ")
+		}
+	}
+	return &clean
+}
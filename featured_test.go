@@ -0,0 +1,143 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitFeatured(t *testing.T) {
+	tests := []struct {
+		in       string
+		main     string
+		featured []string
+	}{
+		{"Old Town Road", "Old Town Road", nil},
+		{"Old Town Road (feat. Billy Ray Cyrus)", "Old Town Road", []string{"Billy Ray Cyrus"}},
+		{"Song [ft. A & B]", "Song", []string{"A & B"}},
+		{"Song (Featuring A)", "Song", []string{"A"}},
+		{"Song (with A) [Remix]", "Song [Remix]", []string{"A"}},
+		{"Main ft. A", "Main", []string{"A"}},
+		{"Main feat A, B", "Main", []string{"A, B"}},
+		{"Song (feat. A) feat. B", "Song", []string{"A", "B"}},
+		{"Stay with Me", "Stay with Me", nil}, // unbracketed "with" is part of the title
+		{"Left (Live)", "Left (Live)", nil},
+		{"Aftermath", "Aftermath", nil}, // "ft" inside a word
+	}
+	for _, tt := range tests {
+		main, featured := splitFeatured(tt.in)
+		if main != tt.main || !slices.Equal(featured, tt.featured) {
+			t.Errorf("splitFeatured(%q) = %q, %q; want %q, %q", tt.in, main, featured, tt.main, tt.featured)
+		}
+	}
+}
+
+func TestFeaturedArtists(t *testing.T) {
+	track := &Track{Name: "Song (feat. B)", Artist: "A feat. b, C"}
+	if got := featuredArtists(track); !slices.Equal(got, []string{"B", "b, C"}) {
+		t.Errorf("featuredArtists = %q", got)
+	}
+	dupe := &Track{Name: "Song (feat. B)", Artist: "A ft. b"}
+	if got := featuredArtists(dupe); !slices.Equal(got, []string{"B"}) {
+		t.Errorf("featuredArtists with a repeated feature = %q, want [B]", got)
+	}
+}
+
+func TestApplyFeatured(t *testing.T) {
+	track := &Track{Name: "Song (feat. B)", Artist: "A ft. C"}
+	tests := []struct {
+		mode, name, artist, featured string
+	}{
+		{FeaturedKeep, "Song (feat. B)", "A ft. C", ""},
+		{FeaturedMove, "Song", "A feat. B, C", ""},
+		{FeaturedStrip, "Song", "A", "B, C"},
+	}
+	for _, tt := range tests {
+		got := applyFeatured(track, tt.mode)
+		if got.Name != tt.name || got.Artist != tt.artist || got.Featured != tt.featured {
+			t.Errorf("%s: got %q / %q / %q, want %q / %q / %q", tt.mode, got.Name, got.Artist, got.Featured, tt.name, tt.artist, tt.featured)
+		}
+	}
+	if track.Name != "Song (feat. B)" || track.Artist != "A ft. C" {
+		t.Errorf("applyFeatured changed the original to %q / %q", track.Name, track.Artist)
+	}
+
+	// An artist the credit already names isn't appended again
+	joint := &Track{Name: "Song (with B)", Artist: "A & B"}
+	if got := applyFeatured(joint, FeaturedMove); got.Artist != "A & B" {
+		t.Errorf("move onto a joint credit = %q, want A & B", got.Artist)
+	}
+}
+
+func TestSplitArtists(t *testing.T) {
+	tests := []struct {
+		credit string
+		want   []string
+	}{
+		{"Radiohead", []string{"Radiohead"}},
+		{"A, B & C", []string{"A", "B", "C"}},
+		{"A; B", []string{"A", "B"}},
+		{"A / B", []string{"A", "B"}},
+		{"AC/DC", []string{"AC/DC"}}, // unspaced slash is part of the name
+		{"Simon&Garfunkel", []string{"Simon&Garfunkel"}},
+		{" A ,  B ", []string{"A", "B"}},
+		{", ", []string{", "}},
+	}
+	for _, tt := range tests {
+		if got := splitArtists(tt.credit); !slices.Equal(got, tt.want) {
+			t.Errorf("splitArtists(%q) = %q, want %q", tt.credit, got, tt.want)
+		}
+	}
+}
+
+func TestArtistList(t *testing.T) {
+	tests := []struct {
+		name  string
+		track *Track
+		want  []string
+	}{
+		{"split under another album artist", &Track{Artist: "A, B & C", AlbumArtist: "Various Artists"}, []string{"A", "B", "C"}},
+		{"band named like a credit", &Track{Artist: "Earth, Wind & Fire", AlbumArtist: "Earth, Wind & Fire"}, []string{"Earth, Wind & Fire"}},
+		{"no album artist", &Track{Artist: "Earth, Wind & Fire"}, []string{"Earth, Wind & Fire"}},
+		{"features excluded", &Track{Artist: "A & B feat. C", AlbumArtist: "A"}, []string{"A", "B"}},
+		{"album artist with features", &Track{Artist: "A ft. B", AlbumArtist: "A feat. C"}, []string{"A"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := artistList(tt.track); !slices.Equal(got, tt.want) {
+				t.Errorf("artistList = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchArtists(t *testing.T) {
+	joint := &Track{Artist: "A, B & C", AlbumArtist: "A"}
+	if got := searchArtists(joint); !slices.Equal(got, []string{"A", "A, B & C"}) {
+		t.Errorf("searchArtists(joint credit) = %q, want the primary artist first", got)
+	}
+	solo := &Track{Artist: "Radiohead", AlbumArtist: "Radiohead"}
+	if got := searchArtists(solo); !slices.Equal(got, []string{"Radiohead"}) {
+		t.Errorf("searchArtists(single artist) = %q", got)
+	}
+}
+
+func TestCreditMatches(t *testing.T) {
+	tests := []struct {
+		artist, credit string
+		want           bool
+	}{
+		{"A", "A", true},
+		{"A", "A & B", true},
+		{"A", "A, B & C", true},
+		{"A", "A feat. B", true},
+		{"Radiohead", "radiohead", true},
+		{"A, B & C", "A, B & C", true},
+		{"B", "A & B", false}, // only the first artist of a credit counts
+		{"Radiohead", "Muse", false},
+	}
+	for _, tt := range tests {
+		if got := creditMatches(tt.artist, tt.credit); got != tt.want {
+			t.Errorf("creditMatches(%q, %q) = %v, want %v", tt.artist, tt.credit, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Focus awareness
+// With presence.clear_on_focus, the presence is cleared while a macOS Focus (Do Not
+// Disturb, Work, …) is switched on and comes back when it ends. Focus state is read from
+// ~/Library/DoNotDisturb/DB/Assertions.json, which takes Full Disk Access on recent macOS.
+// Focus modes started by their own schedule aren't listed there; presence.schedule covers those.
+
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// focusAssertions is the subset of Assertions.json we use
+type focusAssertions struct {
+	Data []struct {
+		StoreAssertionRecords []struct {
+			AssertionDetails struct {
+				ModeIdentifier string `json:"assertionDetailsModeIdentifier"`
+			} `json:"assertionDetails"`
+		} `json:"storeAssertionRecords"`
+	} `json:"data"`
+}
+
+// focusActive reports whether a Focus is switched on, and its mode identifier
+func focusActive() (mode string, active bool, err error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", false, err
+	}
+	data, err := os.ReadFile(filepath.Join(home, "Library", "DoNotDisturb", "DB", "Assertions.json"))
+	if os.IsNotExist(err) {
+		return "", false, nil // no Focus has been used yet
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	var assertions focusAssertions
+	if err := json.Unmarshal(data, &assertions); err != nil {
+		return "", false, err
+	}
+	for _, store := range assertions.Data {
+		if records := store.StoreAssertionRecords; len(records) > 0 {
+			return records[0].AssertionDetails.ModeIdentifier, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// pollFocus clears the presence while a Focus is on. It returns true when the poll
+// should skip publishing.
+func (b *Bridge) pollFocus() bool {
+	if !b.config.Presence.ClearOnFocus {
+		return false
+	}
+
+	mode, active, err := focusActive()
+	if err != nil {
+		if !b.focusDenied {
+			b.focusDenied = true
+			log.Printf("⚠️  Can't read Focus state (give am-bridge Full Disk Access): %v", err)
+		}
+		return b.focused // keep the last known state
+	}
+	b.focusDenied = false
+
+	switch {
+	case active && !b.focused:
+		log.Printf("🌙 Focus on (%s), clearing presence", mode)
+		b.ClearPresence()
+	case !active && b.focused:
+		log.Println("☀️  Focus off")
+		b.Refresh()
+	}
+	b.focused = active
+	return active
+}
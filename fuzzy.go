@@ -0,0 +1,86 @@
+// Fuzzy name matching
+// Search APIs answer with their best guess even when they have nothing close, so a result
+// is only used when its names resemble the ones asked for. Names are compared after
+// normalizing case, accents, punctuation and edition suffixes ("(Deluxe Edition)",
+// " - Single"), by edit distance relative to the longer name.
+
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// minMatchScore - Similarity from 0 to 1 a result's names need to be accepted
+const minMatchScore = 0.8
+
+var (
+	// editionSuffix matches the release qualifiers stores append to album names
+	editionSuffix = regexp.MustCompile(`(?i)\s*(?:[(\[][^)\]]*(?:edition|version|remaster(?:ed)?|deluxe|expanded|anniversary|bonus)[^)\]]*[)\]]|\s-\s(?:single|ep))\s*$`)
+	// nameNoise matches everything but letters, digits and spaces
+	nameNoise = regexp.MustCompile(`[^\pL\pN ]+`)
+)
+
+// normalizeName reduces a name to lower-case, unaccented words without punctuation
+func normalizeName(name string) string {
+	for {
+		stripped := editionSuffix.ReplaceAllString(name, "")
+		if stripped == name {
+			break
+		}
+		name = stripped
+	}
+	name = strings.ReplaceAll(name, "&", " and ")
+
+	var b strings.Builder
+	for _, r := range norm.NFD.String(strings.ToLower(name)) {
+		if !unicode.Is(unicode.Mn, r) {
+			b.WriteRune(r)
+		}
+	}
+	return strings.Join(strings.Fields(nameNoise.ReplaceAllString(b.String(), " ")), " ")
+}
+
+// nameSimilarity scores how alike two names are, from 0 (nothing shared) to 1 (equal
+// once normalized)
+func nameSimilarity(a, b string) float64 {
+	a, b = normalizeName(a), normalizeName(b)
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(editDistance(ra, rb))/float64(longest)
+}
+
+// namesMatch reports whether a result's name is close enough to the one asked for
+func namesMatch(want, got string) bool {
+	return nameSimilarity(want, got) >= minMatchScore
+}
+
+// editDistance is the Levenshtein distance between two rune slices
+func editDistance(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
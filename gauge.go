@@ -0,0 +1,65 @@
+// Text progress gauge
+// Some Discord clients (older desktop builds, mobile) don't draw the Listening progress
+// bar. presence.timestamps "text" sends no timestamps and writes the position into the
+// State line instead, "2:31 / 4:05" or a unicode bar, re-sent on every poll.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Gauge styles for presence.timestamps "text"
+const (
+	GaugeTime = "time" // "2:31 / 4:05"
+	GaugeBar  = "bar"  // "2:31 ▰▰▰▰▰▰▱▱▱▱ 4:05"
+)
+
+// gaugeCells - Width of the bar gauge in characters
+const gaugeCells = 10
+
+// renderGauge draws the position within the track; an unknown duration shows elapsed time only
+func renderGauge(style string, position, duration float64) string {
+	if duration <= 0 {
+		return formatClock(position)
+	}
+	position = min(max(position, 0), duration)
+	elapsed := formatClock(position)
+	if style != GaugeBar {
+		return elapsed + " / " + formatClock(duration)
+	}
+
+	filled := int(position / duration * gaugeCells)
+	bar := strings.Repeat("▰", filled) + strings.Repeat("▱", gaugeCells-filled)
+	return elapsed + " " + bar + " " + formatClock(duration)
+}
+
+// formatClock formats seconds as m:ss, or h:mm:ss from an hour up
+func formatClock(seconds float64) string {
+	d := time.Duration(max(seconds, 0)) * time.Second
+	h, m, s := int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
+
+// refreshGauge re-sends the playing track with its current position. Discord animates
+// timestamps by itself, so only the text mode needs this.
+func (b *Bridge) refreshGauge(track *Track) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.config.Presence.Timestamps != TimestampsText || !b.connected || b.paused ||
+		b.lastTrack == nil || track.IsStream() || b.config.Privacy.Blocked(b.lastTrack) {
+		return
+	}
+
+	// lastTrack carries the resolved cover and catalog fields; only the position moved
+	shown := *b.lastTrack
+	shown.PlayerPosition = track.PlayerPosition
+	activity := b.buildActivity(&shown, StatePlaying, shown.ArtworkURL)
+	b.presence.Submit(&activity)
+}
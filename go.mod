@@ -1,3 +1,13 @@
 module am-discord-bridge
 
 go 1.25.4
+
+require (
+	fyne.io/systray v1.12.2
+	golang.org/x/text v0.36.0
+)
+
+require (
+	github.com/godbus/dbus/v5 v5.1.0 // indirect
+	golang.org/x/sys v0.15.0 // indirect
+)
@@ -0,0 +1,252 @@
+// Listening history
+// Every qualifying play session is stored in a local SQLite database. The system sqlite3
+// CLI (shipped with macOS) is used instead of a driver to keep the binary CGO-free.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// sqliteTimeout - Deadline for a single sqlite3 invocation
+	sqliteTimeout = 5 * time.Second
+
+	// Row/column separators for sqlite3 output (ASCII record/unit separators never appear in tags)
+	sqliteRowSep = "\x1e"
+	sqliteColSep = "\x1f"
+)
+
+const historySchema = `
+CREATE TABLE IF NOT EXISTS listens (
+	id         INTEGER PRIMARY KEY,
+	started_at INTEGER NOT NULL,
+	track      TEXT NOT NULL,
+	artist     TEXT NOT NULL,
+	album      TEXT NOT NULL,
+	duration   REAL NOT NULL,
+	listened   REAL NOT NULL
+);
+CREATE INDEX IF NOT EXISTS listens_started_at ON listens (started_at);
+`
+
+// HistoryConfig controls the listening history database
+type HistoryConfig struct {
+	Enabled bool   `json:"enabled"`
+	Path    string `json:"path"` // default ~/.local/share/am-bridge/history.db
+}
+
+// HistoryStore appends listens to the SQLite database
+type HistoryStore struct {
+	path string
+}
+
+// Listen is one stored history row
+type Listen struct {
+	StartedAt time.Time
+	Track     string
+	Artist    string
+	Album     string
+	Listened  time.Duration
+}
+
+// defaultHistoryPath returns ~/.local/share/am-bridge/history.db
+func defaultHistoryPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "history.db"
+	}
+	return filepath.Join(home, ".local", "share", "am-bridge", "history.db")
+}
+
+// historyPath resolves the configured database path
+func historyPath(cfg HistoryConfig) string {
+	if cfg.Path != "" {
+		return cfg.Path
+	}
+	return defaultHistoryPath()
+}
+
+// OpenHistory prepares the database, returning nil if history is disabled or unavailable
+func OpenHistory(cfg HistoryConfig) *HistoryStore {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	store, err := openHistoryStore(historyPath(cfg))
+	if err != nil {
+		log.Printf("⚠️  Listening history disabled: %v", err)
+		return nil
+	}
+	return store
+}
+
+// openHistoryStore creates the directory and schema at path
+func openHistoryStore(path string) (*HistoryStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	store := &HistoryStore{path: path}
+	if _, err := store.exec(historySchema); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Record stores a finished play session in the background
+func (h *HistoryStore) Record(session *PlaySession) {
+	if h == nil {
+		return
+	}
+	track := session.Track
+
+	stmt := fmt.Sprintf(
+		"INSERT INTO listens (started_at, track, artist, album, duration, listened) VALUES (%d, %s, %s, %s, %f, %f);",
+		session.StartedAt.Unix(), sqlQuote(track.Name), sqlQuote(track.Artist), sqlQuote(track.Album),
+		track.Duration, session.Listened.Seconds())
+
+	goSafe("history", func() {
+		if _, err := h.exec(stmt); err != nil {
+			log.Printf("⚠️  Failed to record listen: %v", err)
+		}
+	})
+}
+
+// Recent returns the latest n listens, newest first
+func (h *HistoryStore) Recent(n int) ([]Listen, error) {
+	rows, err := h.query(fmt.Sprintf(
+		"SELECT started_at, track, artist, album, listened FROM listens ORDER BY started_at DESC LIMIT %d;", n))
+	if err != nil {
+		return nil, err
+	}
+
+	listens := make([]Listen, 0, len(rows))
+	for _, row := range rows {
+		if len(row) != 5 {
+			continue
+		}
+		started, _ := strconv.ParseInt(row[0], 10, 64)
+		listened, _ := strconv.ParseFloat(row[4], 64)
+		listens = append(listens, Listen{
+			StartedAt: time.Unix(started, 0),
+			Track:     row[1],
+			Artist:    row[2],
+			Album:     row[3],
+			Listened:  time.Duration(listened * float64(time.Second)),
+		})
+	}
+	return listens, nil
+}
+
+// RankedEntry is one line of a top-N ranking
+type RankedEntry struct {
+	Name     string
+	Plays    int
+	Listened time.Duration
+}
+
+// Top ranks artists ("artist") or tracks ("track") played in [from, to)
+func (h *HistoryStore) Top(kind string, from, to time.Time, n int) ([]RankedEntry, error) {
+	var label, group string
+	switch kind {
+	case "artist":
+		label, group = "artist", "artist"
+	case "track":
+		label, group = "track || ' - ' || artist", "track, artist"
+	default:
+		return nil, fmt.Errorf("unknown ranking %q", kind)
+	}
+
+	rows, err := h.query(fmt.Sprintf(
+		"SELECT %s, COUNT(*), SUM(listened) FROM listens WHERE started_at >= %d AND started_at < %d GROUP BY %s ORDER BY 2 DESC, 3 DESC LIMIT %d;",
+		label, from.Unix(), to.Unix(), group, n))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]RankedEntry, 0, len(rows))
+	for _, row := range rows {
+		if len(row) != 3 {
+			continue
+		}
+		plays, _ := strconv.Atoi(row[1])
+		listened, _ := strconv.ParseFloat(row[2], 64)
+		entries = append(entries, RankedEntry{
+			Name:     row[0],
+			Plays:    plays,
+			Listened: time.Duration(listened * float64(time.Second)),
+		})
+	}
+	return entries, nil
+}
+
+// Totals counts the listens, listening time and distinct artists in [from, to)
+func (h *HistoryStore) Totals(from, to time.Time) (plays int, listened time.Duration, artists int, err error) {
+	rows, err := h.query(fmt.Sprintf(
+		"SELECT COUNT(*), COALESCE(SUM(listened), 0), COUNT(DISTINCT artist) FROM listens WHERE started_at >= %d AND started_at < %d;",
+		from.Unix(), to.Unix()))
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if len(rows) != 1 || len(rows[0]) != 3 {
+		return 0, 0, 0, fmt.Errorf("unexpected totals output")
+	}
+
+	plays, _ = strconv.Atoi(rows[0][0])
+	seconds, _ := strconv.ParseFloat(rows[0][1], 64)
+	artists, _ = strconv.Atoi(rows[0][2])
+	return plays, time.Duration(seconds * float64(time.Second)), artists, nil
+}
+
+// query runs a SELECT and splits the output into rows of columns
+func (h *HistoryStore) query(stmt string) ([][]string, error) {
+	output, err := h.exec(stmt)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, line := range strings.Split(output, sqliteRowSep) {
+		if line = strings.TrimPrefix(line, "\n"); line == "" {
+			continue
+		}
+		rows = append(rows, strings.Split(line, sqliteColSep))
+	}
+	return rows, nil
+}
+
+// exec feeds SQL to the sqlite3 CLI on stdin and returns its raw output
+func (h *HistoryStore) exec(sql string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sqliteTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sqlite3", "-batch", "-noheader",
+		"-separator", sqliteColSep, "-newline", sqliteRowSep, h.path)
+	cmd.Stdin = strings.NewReader(".timeout 2000\n" + sql)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if errors.Is(err, exec.ErrNotFound) {
+		return "", fmt.Errorf("sqlite3 not found in PATH")
+	}
+	if err != nil {
+		return "", fmt.Errorf("sqlite3: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return string(output), nil
+}
+
+// sqlQuote renders s as a SQL string literal
+func sqlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
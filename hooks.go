@@ -0,0 +1,126 @@
+// Track-change hooks
+// Notifies external systems (home automation, overlays, loggers) whenever the track changes,
+// via an HTTP POST with a JSON payload and/or a user script with AM_* environment variables.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// hookTimeout - Upper bound for a single webhook request or script run
+const hookTimeout = 10 * time.Second
+
+// HookConfig configures the actions fired on every track change
+type HookConfig struct {
+	WebhookURL string `json:"webhook_url"` // POST target for the JSON payload
+	Command    string `json:"command"`     // Shell command run with AM_* environment variables
+}
+
+// Enabled reports whether any hook is configured
+func (h HookConfig) Enabled() bool {
+	return h.WebhookURL != "" || h.Command != ""
+}
+
+// hookPayload is the JSON body sent to the webhook
+type hookPayload struct {
+	Event     string `json:"event"`
+	State     string `json:"state"`
+	Track     *Track `json:"track"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// FireHooks runs all configured hooks for a track change in the background
+func FireHooks(cfg HookConfig, track *Track, state PlayerState) {
+	if !cfg.Enabled() {
+		return
+	}
+
+	goSafe("hooks", func() {
+		if cfg.WebhookURL != "" {
+			if err := postWebhook(cfg.WebhookURL, track, state); err != nil {
+				log.Printf("⚠️  Webhook failed: %v", err)
+			}
+		}
+		if cfg.Command != "" {
+			if err := runHookCommand(cfg.Command, track, state); err != nil {
+				log.Printf("⚠️  Hook command failed: %v", err)
+			}
+		}
+	})
+}
+
+// postWebhook sends the track as JSON to the configured URL
+func postWebhook(webhookURL string, track *Track, state PlayerState) error {
+	return postJSON(webhookURL, hookPayload{
+		Event:     "track_change",
+		State:     state.String(),
+		Track:     track,
+		Timestamp: time.Now().Unix(),
+	})
+}
+
+// postJSON POSTs v as a JSON body, treating any non-2xx status as failure
+func postJSON(webhookURL string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// runHookCommand executes the user's command through sh with track details in the environment
+func runHookCommand(command string, track *Track, state PlayerState) error {
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	cmd.Env = append(os.Environ(),
+		"AM_EVENT=track_change",
+		"AM_STATE="+state.String(),
+		"AM_TRACK_NAME="+track.Name,
+		"AM_TRACK_ARTIST="+track.Artist,
+		"AM_TRACK_ALBUM="+track.Album,
+		"AM_TRACK_ALBUM_ARTIST="+track.AlbumArtist,
+		"AM_TRACK_NUMBER="+formatCount(track.TrackNumber),
+		"AM_TRACK_COUNT="+formatCount(track.TrackCount),
+		"AM_DISC_NUMBER="+formatCount(track.DiscNumber),
+		"AM_DISC_COUNT="+formatCount(track.DiscCount),
+		"AM_TRACK_ORIGIN="+track.Origin(),
+		"AM_TRACK_DURATION="+strconv.FormatFloat(track.Duration, 'f', 0, 64),
+		"AM_TRACK_POSITION="+strconv.FormatFloat(track.PlayerPosition, 'f', 0, 64),
+	)
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, bytes.TrimSpace(output))
+	}
+	return nil
+}
@@ -0,0 +1,330 @@
+// Caching HTTP layer for lookups
+// Artwork and metadata providers share lookupClient. GET and HEAD replies are kept as long
+// as their Cache-Control or Expires allow and revalidated with ETag/Last-Modified after
+// that; identical requests in flight at the same time share one round trip; and every
+// request that does reach the network waits its turn under network.max_qps.
+
+package main
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// lookupCacheSize - Replies remembered across all providers
+	lookupCacheSize = 1000
+
+	// lookupMaxBody - Larger replies are passed through without caching or sharing
+	lookupMaxBody = 512 << 10
+)
+
+// lookupClient is the shared client for artwork and metadata lookups
+var lookupClient = &http.Client{
+	Timeout:   APITimeout,
+	Transport: lookupTransport,
+}
+
+// lookupTransport caches, deduplicates and paces lookups over sharedTransport
+var lookupTransport = newCachingTransport(sharedTransport, lookupCacheSize)
+
+// cachedReply is a complete reply that can be handed to several callers
+type cachedReply struct {
+	key          string
+	status       int
+	header       http.Header
+	body         []byte
+	expires      time.Time // fresh until then; revalidated afterwards
+	etag         string
+	lastModified string
+}
+
+// response builds a fresh *http.Response for one caller
+func (r *cachedReply) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        strconv.Itoa(r.status) + " " + http.StatusText(r.status),
+		StatusCode:    r.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(r.body)),
+		ContentLength: int64(len(r.body)),
+		Request:       req,
+	}
+}
+
+// inflight is a round trip other callers of the same request wait for
+type inflight struct {
+	done  chan struct{}
+	reply *cachedReply
+	err   error
+}
+
+// cachingTransport is an http.RoundTripper adding caching, sharing and a QPS cap
+type cachingTransport struct {
+	next    http.RoundTripper
+	gate    rateGate
+	mu      sync.Mutex
+	size    int
+	order   *list.List               // most recently used first
+	entries map[string]*list.Element // key -> element holding *cachedReply
+	flights map[string]*inflight
+}
+
+// newCachingTransport wraps next with a cache of size replies
+func newCachingTransport(next http.RoundTripper, size int) *cachingTransport {
+	return &cachingTransport{
+		next:    next,
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+		flights: make(map[string]*inflight),
+	}
+}
+
+// SetQPS caps the requests per second that reach the network; 0 removes the cap
+func (t *cachingTransport) SetQPS(qps float64) {
+	t.gate.SetRate(qps)
+}
+
+// lookupKey identifies a request; credentials are part of it so tokens never share replies
+func lookupKey(req *http.Request) string {
+	return req.Method + " " + req.URL.String() + " " + req.Header.Get("Authorization") + " " + req.Header.Get("Music-User-Token")
+}
+
+// RoundTrip answers from the cache when it can, else joins or starts a round trip
+func (t *cachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		if err := t.gate.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+		return t.next.RoundTrip(req)
+	}
+
+	key := lookupKey(req)
+	t.mu.Lock()
+	cached := t.getLocked(key)
+	if cached != nil && time.Now().Before(cached.expires) {
+		t.mu.Unlock()
+		return cached.response(req), nil
+	}
+	if flight, ok := t.flights[key]; ok {
+		t.mu.Unlock()
+		select {
+		case <-flight.done:
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		switch {
+		case flight.reply != nil:
+			return flight.reply.response(req), nil
+		case flight.err != nil && !errors.Is(flight.err, context.Canceled) && !errors.Is(flight.err, context.DeadlineExceeded):
+			return nil, flight.err
+		}
+		// The reply was too large to share, or the first caller gave up; go alone
+		if err := t.gate.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+		return t.next.RoundTrip(req)
+	}
+	flight := &inflight{done: make(chan struct{})}
+	t.flights[key] = flight
+	t.mu.Unlock()
+
+	reply, resp, err := t.fetch(req, key, cached)
+
+	t.mu.Lock()
+	delete(t.flights, key)
+	t.mu.Unlock()
+	flight.reply, flight.err = reply, err
+	close(flight.done)
+
+	if resp != nil {
+		return resp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return reply.response(req), nil
+}
+
+// fetch makes the round trip, revalidating a stale cached reply when it has validators.
+// It returns either a buffered reply or, for bodies over lookupMaxBody, the live response.
+func (t *cachingTransport) fetch(req *http.Request, key string, stale *cachedReply) (*cachedReply, *http.Response, error) {
+	if err := t.gate.Wait(req.Context()); err != nil {
+		return nil, nil, err
+	}
+
+	out := req
+	if stale != nil && (stale.etag != "" || stale.lastModified != "") {
+		out = req.Clone(req.Context())
+		if stale.etag != "" {
+			out.Header.Set("If-None-Match", stale.etag)
+		}
+		if stale.lastModified != "" {
+			out.Header.Set("If-Modified-Since", stale.lastModified)
+		}
+	}
+
+	resp, err := t.next.RoundTrip(out)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && stale != nil {
+		resp.Body.Close()
+		refreshed := *stale
+		refreshed.expires = freshUntil(resp.Header, time.Now())
+		t.store(&refreshed)
+		return &refreshed, nil, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, lookupMaxBody+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, nil, err
+	}
+	if len(body) > lookupMaxBody {
+		// Hand the caller the rest of the stream untouched
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return nil, resp, nil
+	}
+	resp.Body.Close()
+
+	reply := &cachedReply{
+		key:          key,
+		status:       resp.StatusCode,
+		header:       resp.Header,
+		body:         body,
+		expires:      freshUntil(resp.Header, time.Now()),
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+	}
+	if resp.StatusCode == http.StatusOK && !noStore(resp.Header) {
+		t.store(reply)
+	}
+	return reply, nil, nil
+}
+
+// getLocked returns the cached reply for key, marking it recently used; call with t.mu held
+func (t *cachingTransport) getLocked(key string) *cachedReply {
+	element, ok := t.entries[key]
+	if !ok {
+		return nil
+	}
+	t.order.MoveToFront(element)
+	return element.Value.(*cachedReply)
+}
+
+// store remembers a reply, evicting the least recently used beyond the size limit
+func (t *cachingTransport) store(reply *cachedReply) {
+	// A reply that is never fresh and can't be revalidated is of no use later
+	if !time.Now().Before(reply.expires) && reply.etag == "" && reply.lastModified == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if element, ok := t.entries[reply.key]; ok {
+		element.Value = reply
+		t.order.MoveToFront(element)
+		return
+	}
+	t.entries[reply.key] = t.order.PushFront(reply)
+	for t.order.Len() > t.size {
+		oldest := t.order.Back()
+		t.order.Remove(oldest)
+		delete(t.entries, oldest.Value.(*cachedReply).key)
+	}
+}
+
+// cacheDirectives parses Cache-Control into lower-case directives and their values
+func cacheDirectives(header http.Header) map[string]string {
+	directives := make(map[string]string)
+	for _, part := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if name != "" {
+			directives[strings.ToLower(name)] = strings.Trim(value, `"`)
+		}
+	}
+	return directives
+}
+
+// noStore reports whether the reply must not be cached at all
+func noStore(header http.Header) bool {
+	_, ok := cacheDirectives(header)["no-store"]
+	return ok
+}
+
+// freshUntil works out how long a reply may be used without revalidation
+func freshUntil(header http.Header, now time.Time) time.Time {
+	directives := cacheDirectives(header)
+	if _, ok := directives["no-cache"]; ok {
+		return now
+	}
+	if maxAge, ok := directives["max-age"]; ok {
+		if seconds, err := strconv.Atoi(maxAge); err == nil {
+			return now.Add(time.Duration(seconds) * time.Second)
+		}
+	}
+	if expires, err := http.ParseTime(header.Get("Expires")); err == nil {
+		return expires
+	}
+	return now
+}
+
+// rateGate spaces requests evenly to stay under a rate
+type rateGate struct {
+	mu       sync.Mutex
+	interval time.Duration // 0 lets everything through
+	next     time.Time     // earliest time the next request may go
+}
+
+// SetRate sets the requests per second; 0 or less removes the cap
+func (g *rateGate) SetRate(qps float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.interval = 0
+	if qps > 0 {
+		g.interval = time.Duration(float64(time.Second) / qps)
+	}
+}
+
+// Wait blocks until the request may go, or ctx ends
+func (g *rateGate) Wait(ctx context.Context) error {
+	g.mu.Lock()
+	if g.interval == 0 {
+		g.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	slot := g.next
+	if slot.Before(now) {
+		slot = now
+	}
+	g.next = slot.Add(g.interval)
+	g.mu.Unlock()
+
+	if wait := slot.Sub(now); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
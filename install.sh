@@ -35,4 +35,4 @@ launchctl unload "$LAUNCH_AGENTS_DIR/$PLIST_NAME" 2>/dev/null
 launchctl load "$LAUNCH_AGENTS_DIR/$PLIST_NAME"
 
 echo "✅ Installation complete!"
-echo "logs: tail -f /tmp/am-bridge.log"
+echo "logs: am-bridge logs -f (crashes: /tmp/am-bridge.log)"
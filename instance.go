@@ -0,0 +1,113 @@
+// Single-instance guard
+// A pidfile held under an exclusive flock ensures only one daemon talks to Discord.
+// The lock is released automatically by the kernel if the process dies.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// takeoverTimeout - How long --replace waits for the old instance to exit
+const takeoverTimeout = 5 * time.Second
+
+// InstanceLock holds the pidfile lock for the lifetime of the daemon
+type InstanceLock struct {
+	file *os.File
+}
+
+// StateDir returns ~/.cache/am-bridge, creating it if necessary
+func StateDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(home, ".cache", "am-bridge")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// AcquireInstanceLock takes the pidfile lock. If another instance holds it, either
+// returns an error naming its PID or, with replace set, terminates it and takes over.
+func AcquireInstanceLock(replace bool) (*InstanceLock, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+	path := filepath.Join(dir, "am-bridge.pid")
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pidfile: %w", err)
+	}
+
+	err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		pid := readPID(file)
+		if !replace {
+			file.Close()
+			return nil, fmt.Errorf("am-bridge is already running (pid %d); use --replace to take over", pid)
+		}
+		err = takeOver(file, pid)
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to lock pidfile: %w", err)
+	}
+
+	// Record our PID for the next instance's error message
+	file.Truncate(0)
+	file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+
+	return &InstanceLock{file: file}, nil
+}
+
+// Release drops the lock. The pidfile itself is left in place so a waiting
+// --replace instance keeps locking the same inode.
+func (l *InstanceLock) Release() {
+	if l == nil {
+		return
+	}
+	syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	l.file.Close()
+}
+
+// takeOver asks the running instance to shut down and waits for its lock
+func takeOver(file *os.File, pid int) error {
+	if pid <= 0 {
+		return fmt.Errorf("existing instance holds the lock but its pid is unknown")
+	}
+	if err := syscall.Kill(pid, syscall.SIGTERM); err != nil {
+		return fmt.Errorf("failed to signal pid %d: %w", pid, err)
+	}
+
+	deadline := time.Now().Add(takeoverTimeout)
+	for time.Now().Before(deadline) {
+		err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			return err
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return fmt.Errorf("pid %d did not exit within %v", pid, takeoverTimeout)
+}
+
+// readPID parses the PID written by the lock holder
+func readPID(file *os.File) int {
+	buf := make([]byte, 32)
+	n, _ := file.ReadAt(buf, 0)
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(buf[:n])))
+	return pid
+}
@@ -0,0 +1,94 @@
+// Keychain secrets
+// API credentials can live in the login Keychain instead of the config file. They are
+// stored as generic passwords under the "am-bridge" service with one account per
+// integration, and fill in any credential left empty in the config.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// keychainService - Keychain item service name shared by every secret
+const keychainService = "am-bridge"
+
+// secretSlot describes one credential that may come from the Keychain
+type secretSlot struct {
+	account string
+	label   string
+	field   func(c *Config) *string
+}
+
+// secretSlots lists every credential `am-bridge auth` can manage
+var secretSlots = []secretSlot{
+	{"listenbrainz", "ListenBrainz user token", func(c *Config) *string { return &c.ListenBrainz.Token }},
+	{"lastfm", "Last.fm API key", func(c *Config) *string { return &c.Artwork.LastFMAPIKey }},
+	{"imgur", "Imgur client ID", func(c *Config) *string { return &c.LocalArtwork.ImgurClientID }},
+	{"discord-assets", "Discord bot token for asset upload", func(c *Config) *string { return &c.Discord.Assets.Token }},
+	{"songlink", "song.link API key", func(c *Config) *string { return &c.SongLink.APIKey }},
+	{"slack", "Slack user token", func(c *Config) *string { return &c.Slack.Token }},
+	{"mqtt", "MQTT broker password", func(c *Config) *string { return &c.MQTT.Password }},
+	{"applemusic", "Apple Music developer token", func(c *Config) *string { return &c.AppleMusic.DeveloperToken }},
+	{"applemusic-user", "Apple Music user token", func(c *Config) *string { return &c.AppleMusic.UserToken }},
+	{"cider", "Cider app token", func(c *Config) *string { return &c.Cider.Token }},
+}
+
+// errSecretNotFound means no Keychain item exists for the account
+var errSecretNotFound = errors.New("not in Keychain")
+
+// loadSecrets fills empty credentials from the Keychain; plaintext config values win
+func (c *Config) loadSecrets() {
+	if _, err := exec.LookPath("security"); err != nil {
+		return
+	}
+	for _, slot := range secretSlots {
+		field := slot.field(c)
+		if *field != "" {
+			continue
+		}
+		if secret, err := keychainGet(slot.account); err == nil {
+			*field = secret
+		}
+	}
+}
+
+// keychainGet reads a secret without prompting
+func keychainGet(account string) (string, error) {
+	output, err := exec.Command("security", "find-generic-password",
+		"-s", keychainService, "-a", account, "-w").Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 44 { // errSecItemNotFound
+			return "", errSecretNotFound
+		}
+		return "", fmt.Errorf("keychain lookup failed: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// keychainPrompt stores a secret, letting `security` prompt for it so it never
+// appears in the process list or shell history
+func keychainPrompt(account, label string) error {
+	cmd := exec.Command("security", "add-generic-password", "-U",
+		"-s", keychainService, "-a", account, "-l", fmt.Sprintf("am-bridge %s", label), "-w")
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to store %s in Keychain: %w", label, err)
+	}
+	return nil
+}
+
+// keychainDelete removes a stored secret
+func keychainDelete(account string) error {
+	err := exec.Command("security", "delete-generic-password",
+		"-s", keychainService, "-a", account).Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 44 {
+		return errSecretNotFound
+	}
+	return err
+}
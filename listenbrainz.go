@@ -0,0 +1,139 @@
+// ListenBrainz scrobbling
+// Submits "playing_now" on every track change and a permanent listen once the play
+// session qualifies (half the track or 4 minutes, see PlaySession.Qualifies).
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// listenBrainzURL - Default API root for ListenBrainz
+const listenBrainzURL = "https://api.listenbrainz.org"
+
+// ListenBrainzConfig holds the user token and optional custom API root
+type ListenBrainzConfig struct {
+	Token  string `json:"token"`
+	APIURL string `json:"api_url"`
+}
+
+// lbAdditionalInfo carries client details alongside the track metadata
+type lbAdditionalInfo struct {
+	DurationMs       int64  `json:"duration_ms,omitempty"`
+	MediaPlayer      string `json:"media_player"`
+	SubmissionClient string `json:"submission_client"`
+}
+
+type lbTrackMetadata struct {
+	ArtistName     string           `json:"artist_name"`
+	TrackName      string           `json:"track_name"`
+	ReleaseName    string           `json:"release_name,omitempty"`
+	AdditionalInfo lbAdditionalInfo `json:"additional_info"`
+}
+
+type lbListen struct {
+	ListenedAt    int64           `json:"listened_at,omitempty"`
+	TrackMetadata lbTrackMetadata `json:"track_metadata"`
+}
+
+type lbSubmission struct {
+	ListenType string     `json:"listen_type"`
+	Payload    []lbListen `json:"payload"`
+}
+
+// ListenBrainz submits play sessions to the submit-listens endpoint
+type ListenBrainz struct {
+	token  string
+	apiURL string
+}
+
+// NewListenBrainz returns a submitter, or nil if no token is configured
+func NewListenBrainz(cfg ListenBrainzConfig) *ListenBrainz {
+	if cfg.Token == "" {
+		return nil
+	}
+	apiURL := strings.TrimRight(cfg.APIURL, "/")
+	if apiURL == "" {
+		apiURL = listenBrainzURL
+	}
+	return &ListenBrainz{token: cfg.Token, apiURL: apiURL}
+}
+
+// Observe is called on every poll with the play tracker's view of the current session
+func (lb *ListenBrainz) Observe(update PlayUpdate, state PlayerState) {
+	if lb == nil || update.Current == nil || update.Current.Track.IsStream() || update.Current.Track.Video || update.Current.Track.Audiobook {
+		return
+	}
+	session := update.Current
+
+	if update.Started && state == StatePlaying {
+		lb.submitAsync("playing_now", lbListen{TrackMetadata: lbMetadata(session.Track)})
+		return
+	}
+
+	if !session.Scrobbled && session.Qualifies() {
+		session.Scrobbled = true
+		lb.submitAsync("single", lbListen{
+			ListenedAt:    session.StartedAt.Unix(),
+			TrackMetadata: lbMetadata(session.Track),
+		})
+		log.Printf("📻 Scrobbled to ListenBrainz: %s - %s", session.Track.Name, session.Track.Artist)
+	}
+}
+
+// lbMetadata maps a Track to ListenBrainz track metadata
+func lbMetadata(track *Track) lbTrackMetadata {
+	return lbTrackMetadata{
+		ArtistName:  track.Artist,
+		TrackName:   track.Name,
+		ReleaseName: track.Album,
+		AdditionalInfo: lbAdditionalInfo{
+			DurationMs:       int64(track.Duration * 1000),
+			MediaPlayer:      track.Source,
+			SubmissionClient: "am-bridge",
+		},
+	}
+}
+
+// submitAsync posts a submission in the background, logging failures
+func (lb *ListenBrainz) submitAsync(listenType string, listen lbListen) {
+	goSafe("ListenBrainz "+listenType, func() {
+		if err := lb.submit(listenType, listen); err != nil {
+			log.Printf("⚠️  ListenBrainz %s failed: %v", listenType, err)
+		}
+	})
+}
+
+// submit sends a single listen to the submit-listens endpoint
+func (lb *ListenBrainz) submit(listenType string, listen lbListen) error {
+	body, err := json.Marshal(lbSubmission{
+		ListenType: listenType,
+		Payload:    []lbListen{listen},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, lb.apiURL+"/1/submit-listens", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Token "+lb.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
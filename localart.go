@@ -0,0 +1,264 @@
+// Local artwork upload
+// Tracks that aren't in the Apple Music catalog have no public artwork URL, so the embedded
+// cover is exported via AppleScript and uploaded to an image host Discord can fetch from.
+// Opt-in: nothing leaves the machine unless local_artwork.host is set.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Supported image hosts
+const (
+	HostCatbox    = "catbox"    // Permanent anonymous uploads
+	HostLitterbox = "litterbox" // Temporary (72h) anonymous uploads
+	HostImgur     = "imgur"     // Requires an Imgur client ID
+)
+
+// litterboxLifetime - How long litterbox keeps an upload
+const litterboxLifetime = 72 * time.Hour
+
+const (
+	catboxUploadURL    = "https://catbox.moe/user/api.php"
+	litterboxUploadURL = "https://litterbox.catbox.moe/resources/internals/api.php"
+	imgurUploadURL     = "https://api.imgur.com/3/image"
+)
+
+// LocalArtworkConfig selects where embedded artwork is uploaded
+type LocalArtworkConfig struct {
+	Host          string `json:"host"` // "", "catbox", "litterbox" or "imgur"
+	ImgurClientID string `json:"imgur_client_id"`
+}
+
+// Enabled reports whether local artwork upload is configured
+func (c LocalArtworkConfig) Enabled() bool {
+	return c.Host != ""
+}
+
+// ArtworkUploader exports embedded artwork and uploads it, deduplicating by content hash
+type ArtworkUploader struct {
+	config   LocalArtworkConfig
+	scripts  ScriptRunner
+	mu       sync.Mutex
+	uploaded map[string]ArtworkResult // key: sha256 of image bytes -> hosted cover
+}
+
+// NewArtworkUploader returns an uploader, or nil if uploads are disabled
+func NewArtworkUploader(cfg LocalArtworkConfig, scripts ScriptRunner) *ArtworkUploader {
+	if !cfg.Enabled() {
+		return nil
+	}
+	return &ArtworkUploader{config: cfg, scripts: scripts, uploaded: make(map[string]ArtworkResult)}
+}
+
+// Upload exports the embedded artwork of the track with the given persistent ID and returns
+// its hosted cover, with the time the host deletes it for temporary hosts
+func (u *ArtworkUploader) Upload(ctx context.Context, persistentID string) (ArtworkResult, error) {
+	data, err := ExportArtwork(ctx, u.scripts, persistentID)
+	if err != nil {
+		return ArtworkResult{}, err
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	u.mu.Lock()
+	hosted, exists := u.uploaded[hash]
+	u.mu.Unlock()
+	if exists && !hosted.expired() {
+		return hosted, nil
+	}
+
+	hosted = ArtworkResult{}
+	switch u.config.Host {
+	case HostCatbox:
+		hosted.ArtworkURL, err = uploadCatbox(ctx, catboxUploadURL, data, nil)
+	case HostLitterbox:
+		// Expire it a little early, so nothing links a cover that is about to vanish
+		hosted.Expires = time.Now().Add(litterboxLifetime - time.Hour)
+		hosted.ArtworkURL, err = uploadCatbox(ctx, litterboxUploadURL, data, map[string]string{"time": "72h"})
+	case HostImgur:
+		hosted.ArtworkURL, err = uploadImgur(ctx, u.config.ImgurClientID, data)
+	default:
+		err = fmt.Errorf("unknown artwork host %q", u.config.Host)
+	}
+	if err != nil {
+		return ArtworkResult{}, err
+	}
+
+	u.mu.Lock()
+	u.uploaded[hash] = hosted
+	u.mu.Unlock()
+	return hosted, nil
+}
+
+// ExportArtwork writes the first artwork of the track with the given persistent ID to a temp
+// file and returns its bytes. The track is looked up rather than taken as the current one:
+// a background lookup may run after the player moved on, and would upload the wrong cover.
+func ExportArtwork(ctx context.Context, scripts ScriptRunner, persistentID string) ([]byte, error) {
+	if persistentID == "" {
+		return nil, fmt.Errorf("track has no persistent ID to export its artwork by")
+	}
+	file, err := os.CreateTemp("", "am-bridge-artwork-*")
+	if err != nil {
+		return nil, err
+	}
+	path := file.Name()
+	file.Close()
+	defer os.Remove(path)
+
+	id := appleScriptString(persistentID)
+	script := fmt.Sprintf(`
+		tell application "Music"
+			set theTrack to missing value
+			try
+				if persistent ID of current track is %s then set theTrack to current track
+			end try
+			if theTrack is missing value then
+				set found to (every track of library playlist 1 whose persistent ID is %s)
+				if found is {} then return "gone"
+				set theTrack to item 1 of found
+			end if
+			if (count of artworks of theTrack) is 0 then return "none"
+			set artData to raw data of artwork 1 of theTrack
+		end tell
+		set outFile to open for access POSIX file %s with write permission
+		try
+			set eof outFile to 0
+			write artData to outFile
+			close access outFile
+		on error errMsg
+			close access outFile
+			error errMsg
+		end try
+		return "ok"
+	`, id, id, appleScriptString(path))
+
+	result, err := runAppleScript(ctx, scripts, script)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export artwork: %w", err)
+	}
+	switch result {
+	case "none":
+		return nil, fmt.Errorf("track has no embedded artwork")
+	case "gone":
+		return nil, fmt.Errorf("track %s is no longer in the library", persistentID)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("exported artwork is empty")
+	}
+	return data, nil
+}
+
+// multipartUpload posts a single image file plus extra form fields
+func multipartUpload(ctx context.Context, uploadURL, fileField string, data []byte, fields map[string]string, headers map[string]string) ([]byte, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for key, value := range fields {
+		writer.WriteField(key, value)
+	}
+	part, err := writer.CreateFormFile(fileField, "cover"+imageExtension(data))
+	if err != nil {
+		return nil, err
+	}
+	part.Write(data)
+	writer.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, APITimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("upload status %d", resp.StatusCode)
+	}
+	return respBody, nil
+}
+
+// uploadCatbox uploads to catbox/litterbox, which reply with the bare file URL
+func uploadCatbox(ctx context.Context, uploadURL string, data []byte, extra map[string]string) (string, error) {
+	fields := map[string]string{"reqtype": "fileupload"}
+	for key, value := range extra {
+		fields[key] = value
+	}
+
+	body, err := multipartUpload(ctx, uploadURL, "fileToUpload", data, fields, nil)
+	if err != nil {
+		return "", err
+	}
+
+	hosted := strings.TrimSpace(string(body))
+	if !strings.HasPrefix(hosted, "https://") {
+		return "", fmt.Errorf("unexpected upload response: %s", hosted)
+	}
+	return hosted, nil
+}
+
+// uploadImgur uploads anonymously with the configured client ID
+func uploadImgur(ctx context.Context, clientID string, data []byte) (string, error) {
+	if clientID == "" {
+		return "", fmt.Errorf("local_artwork.imgur_client_id is not set")
+	}
+
+	body, err := multipartUpload(ctx, imgurUploadURL, "image", data, nil,
+		map[string]string{"Authorization": "Client-ID " + clientID})
+	if err != nil {
+		return "", err
+	}
+
+	var result struct {
+		Data struct {
+			Link string `json:"link"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", err
+	}
+	if result.Data.Link == "" {
+		return "", fmt.Errorf("imgur response has no link")
+	}
+	return result.Data.Link, nil
+}
+
+// imageExtension guesses the file extension from magic bytes
+func imageExtension(data []byte) string {
+	if bytes.HasPrefix(data, []byte("\x89PNG")) {
+		return ".png"
+	}
+	return ".jpg"
+}
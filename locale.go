@@ -0,0 +1,200 @@
+// Presence language
+// The text the bridge writes itself ("Paused", "via {playlist}", the default "by {artist}")
+// comes from a small message catalog. presence.locale picks the language; left empty it
+// follows the system's, from LC_ALL/LC_MESSAGES/LANG or macOS's AppleLocale. Keys a
+// language lacks, and languages the catalog lacks, fall back to English.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Message keys
+const (
+	msgBy       = "by"       // Default artist line, "by %s"
+	msgPaused   = "paused"   // State prefix and small image text while paused
+	msgPlaying  = "playing"  // Small image text while playing
+	msgLoved    = "loved"    // Small image text for loved tracks
+	msgVia      = "via"      // Playlist credit, "via %s"
+	msgUpNext   = "up_next"  // Next track, "Up next: %s"
+	msgOn       = "on"       // Station of a stream, "on %s"
+	msgBrowsing = "browsing" // Details of a stopped player with presence.stopped "browsing"
+	msgMasked   = "masked"   // Default privacy.mask_text
+)
+
+// defaultLanguage - Used when neither the config nor the system picks a catalog language
+const defaultLanguage = "en"
+
+// messages holds the catalog, by language and then message key
+var messages = map[string]map[string]string{
+	"en": {
+		msgBy:       "by %s",
+		msgPaused:   "Paused",
+		msgPlaying:  "Playing",
+		msgLoved:    "Loved",
+		msgVia:      "via %s",
+		msgUpNext:   "Up next: %s",
+		msgOn:       "on %s",
+		msgBrowsing: "Browsing library",
+		msgMasked:   "Listening to music",
+	},
+	"de": {
+		msgBy:       "von %s",
+		msgPaused:   "Pausiert",
+		msgPlaying:  "Wiedergabe",
+		msgLoved:    "Favorit",
+		msgVia:      "über %s",
+		msgUpNext:   "Als Nächstes: %s",
+		msgOn:       "auf %s",
+		msgBrowsing: "Stöbert in der Mediathek",
+		msgMasked:   "Hört Musik",
+	},
+	"es": {
+		msgBy:       "de %s",
+		msgPaused:   "En pausa",
+		msgPlaying:  "Reproduciendo",
+		msgLoved:    "Me encanta",
+		msgVia:      "desde %s",
+		msgUpNext:   "A continuación: %s",
+		msgOn:       "en %s",
+		msgBrowsing: "Explorando la biblioteca",
+		msgMasked:   "Escuchando música",
+	},
+	"fr": {
+		msgBy:       "par %s",
+		msgPaused:   "En pause",
+		msgPlaying:  "Lecture en cours",
+		msgLoved:    "J’adore",
+		msgVia:      "via %s",
+		msgUpNext:   "À suivre : %s",
+		msgOn:       "sur %s",
+		msgBrowsing: "Parcourt la bibliothèque",
+		msgMasked:   "Écoute de la musique",
+	},
+	"it": {
+		msgBy:       "di %s",
+		msgPaused:   "In pausa",
+		msgPlaying:  "In riproduzione",
+		msgLoved:    "Preferito",
+		msgVia:      "da %s",
+		msgUpNext:   "Prossimo brano: %s",
+		msgOn:       "su %s",
+		msgBrowsing: "Sfoglia la libreria",
+		msgMasked:   "Ascolta musica",
+	},
+	"ja": {
+		msgBy:       "%s",
+		msgPaused:   "一時停止中",
+		msgPlaying:  "再生中",
+		msgLoved:    "ラブ",
+		msgVia:      "%s から",
+		msgUpNext:   "次の曲：%s",
+		msgOn:       "%s で",
+		msgBrowsing: "ライブラリを閲覧中",
+		msgMasked:   "音楽を聴いています",
+	},
+	"pt": {
+		msgBy:       "de %s",
+		msgPaused:   "Em pausa",
+		msgPlaying:  "Reproduzindo",
+		msgLoved:    "Amei",
+		msgVia:      "via %s",
+		msgUpNext:   "A seguir: %s",
+		msgOn:       "em %s",
+		msgBrowsing: "Navegando na biblioteca",
+		msgMasked:   "Ouvindo música",
+	},
+}
+
+// languageOf reduces a locale such as "de_DE.UTF-8" or "pt-BR" to its language, "de" or "pt"
+func languageOf(locale string) string {
+	language, _, _ := strings.Cut(locale, ".")
+	language, _, _ = strings.Cut(language, "_")
+	language, _, _ = strings.Cut(language, "-")
+	return strings.ToLower(language)
+}
+
+// systemLanguage is the user's language, looked up once: launchd agents usually run
+// without LANG, so macOS's own setting is asked when the environment has none
+var systemLanguage = sync.OnceValue(func() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(name); value != "" && value != "C" && value != "POSIX" {
+			return languageOf(value)
+		}
+	}
+	if output, err := exec.Command("defaults", "read", "-g", "AppleLocale").Output(); err == nil {
+		return languageOf(strings.TrimSpace(string(output)))
+	}
+	return defaultLanguage
+})
+
+// Language returns the catalog language for presence.locale
+func (c PresenceConfig) Language() string {
+	language := languageOf(c.Locale)
+	if language == "" {
+		language = systemLanguage()
+	}
+	if _, ok := messages[language]; !ok {
+		return defaultLanguage
+	}
+	return language
+}
+
+// localize renders a message in language, formatting it with args
+func localize(language, key string, args ...any) string {
+	text, ok := messages[language][key]
+	if !ok {
+		text = messages[defaultLanguage][key]
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(text, args...)
+	}
+	return text
+}
+
+// text renders a message in the configured presence language
+func (b *Bridge) text(key string, args ...any) string {
+	return localize(b.config.Presence.Language(), key, args...)
+}
+
+// localizeDefaults translates the default templates the config still uses; any template
+// that differs from its default is the user's own and left alone
+func (c *Config) localizeDefaults() {
+	language := c.Presence.Language()
+	if language == defaultLanguage {
+		return
+	}
+	english := DefaultConfig().Presence
+	by := localize(language, msgBy, "{artist}")
+	if c.Presence.State == english.State {
+		c.Presence.State = by
+	}
+	if c.Presence.Audiobook.State == english.Audiobook.State {
+		c.Presence.Audiobook.State = strings.Replace(english.Audiobook.State, "by {artist}", by, 1)
+	}
+	if c.Privacy.MaskText == "" {
+		c.Privacy.MaskText = localize(language, msgMasked)
+	}
+}
+
+// validateLocale rejects a presence.locale the catalog has no language for
+func validateLocale(locale string) error {
+	if locale == "" {
+		return nil
+	}
+	if _, ok := messages[languageOf(locale)]; !ok {
+		languages := make([]string, 0, len(messages))
+		for language := range messages {
+			languages = append(languages, language)
+		}
+		slices.Sort(languages)
+		return fmt.Errorf("presence.locale %q has no translation; available: %s", locale, strings.Join(languages, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,332 @@
+// Log files
+// `run --log-file PATH` writes the log to a file that rotates by size or age, keeping the
+// newest --log-keep files (PATH.1 is the most recent), so the daemon can run under launchd
+// for weeks without losing logs or filling the disk. --plain-log drops emoji.
+// `am-bridge logs` reads the file back, asking the running daemon where it is.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// defaultLogFile - Where the bundled LaunchAgent logs, relative to the home directory
+	defaultLogFile = "Library/Logs/am-bridge.log"
+
+	// logFollowInterval - How often `logs -f` checks for new lines
+	logFollowInterval = 500 * time.Millisecond
+)
+
+// Log levels, told apart by the emoji a message starts with
+const (
+	logInfo = iota
+	logWarn
+	logError
+)
+
+// logLevelNames maps -level values to levels
+var logLevelNames = map[string]int{"info": logInfo, "warn": logWarn, "error": logError}
+
+// logEntryStart matches the time every log entry begins with; other lines continue an entry
+var logEntryStart = regexp.MustCompile(`^\d{2}:\d{2}:\d{2} `)
+
+// LogOptions configures the daemon log file
+type LogOptions struct {
+	Path    string        // empty logs to stderr
+	MaxSize int64         // bytes before rotating; 0 disables size rotation
+	MaxAge  time.Duration // age of the current file before rotating; 0 disables
+	Keep    int           // rotated files kept besides the current one
+	Plain   bool          // strip emoji
+}
+
+// rotatingFile is an io.Writer that rotates the file it appends to
+type rotatingFile struct {
+	opts LogOptions
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+// OpenLog returns the writer for the daemon log
+func OpenLog(opts LogOptions) (io.Writer, error) {
+	var w io.Writer = os.Stderr
+	if opts.Path != "" {
+		if err := os.MkdirAll(filepath.Dir(opts.Path), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create log directory: %w", err)
+		}
+		f := &rotatingFile{opts: opts}
+		if err := f.open(); err != nil {
+			return nil, err
+		}
+		w = f
+	}
+	if opts.Plain {
+		w = plainWriter{w}
+	}
+	return w, nil
+}
+
+// open appends to the log file, creating it if needed
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.opts.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	f.file, f.size, f.opened = file, info.Size(), time.Now()
+	return nil
+}
+
+// Write appends p, rotating first when the file is full or old enough
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	full := f.opts.MaxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.opts.MaxSize
+	old := f.opts.MaxAge > 0 && time.Since(f.opened) >= f.opts.MaxAge
+	if full || old {
+		if err := f.rotate(); err != nil {
+			// Keep appending to the current file and try again after another full period
+			fmt.Fprintf(os.Stderr, "log rotation failed: %v\n", err)
+			f.size, f.opened = 0, time.Now()
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate shifts PATH.N-1 to PATH.N, PATH to PATH.1 and starts a new file
+func (f *rotatingFile) rotate() error {
+	path := f.opts.Path
+	os.Remove(fmt.Sprintf("%s.%d", path, f.opts.Keep))
+	for i := f.opts.Keep - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", path, i), fmt.Sprintf("%s.%d", path, i+1))
+	}
+	if f.opts.Keep > 0 {
+		os.Rename(path, path+".1")
+	} else {
+		os.Remove(path)
+	}
+
+	// The renamed file stays open until its replacement is
+	current := f.file
+	if err := f.open(); err != nil {
+		return err
+	}
+	return current.Close()
+}
+
+// plainWriter removes emoji (and the spacing after them) from each log line
+type plainWriter struct {
+	w io.Writer
+}
+
+// Write filters p and reports it as fully written
+func (p plainWriter) Write(b []byte) (int, error) {
+	var out bytes.Buffer
+	out.Grow(len(b))
+	skipSpaces := false
+	for rest := b; len(rest) > 0; {
+		r, size := utf8.DecodeRune(rest)
+		rest = rest[size:]
+		switch {
+		case isEmoji(r):
+			skipSpaces = true
+			continue
+		case skipSpaces && r == ' ':
+			continue
+		}
+		skipSpaces = false
+		out.WriteRune(r)
+	}
+	if _, err := p.w.Write(out.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// isEmoji matches pictographs plus the variation selector and joiner that compose them
+func isEmoji(r rune) bool {
+	return unicode.Is(unicode.So, r) || r == '\uFE0F' || r == '\u200D'
+}
+
+// cmdLogs prints the end of the daemon's log and optionally follows it
+func cmdLogs(args []string) error {
+	fs := flag.NewFlagSet("logs", flag.ExitOnError)
+	follow := fs.Bool("f", false, "keep printing lines as they are written")
+	lines := fs.Int("n", 100, "lines to print from the end of the log")
+	level := fs.String("level", "info", "lowest level to show: info, warn or error")
+	path := fs.String("file", "", "log file to read (default: the running daemon's)")
+	fs.Parse(args)
+
+	minLevel, ok := logLevelNames[*level]
+	if !ok {
+		return fmt.Errorf("-level must be info, warn or error, got %q", *level)
+	}
+	logPath, err := findLogFile(*path)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(logPath)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer file.Close()
+
+	filter := &logFilter{min: minLevel, keep: minLevel == logInfo}
+	tail, err := filter.tail(file, max(*lines, 0))
+	if err != nil {
+		return fmt.Errorf("failed to read log file: %w", err)
+	}
+	for _, line := range tail {
+		fmt.Println(line)
+	}
+	if !*follow {
+		return nil
+	}
+	return followLog(file, logPath, filter)
+}
+
+// findLogFile picks the log to read: the -file flag, the running daemon's log file, or
+// the LaunchAgent's default location when the daemon isn't running
+func findLogFile(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	if resp, err := sendControl("status"); err == nil {
+		if resp.Status.LogFile == "" {
+			return "", errors.New("the daemon logs to stderr; restart it with --log-file to use am-bridge logs")
+		}
+		return resp.Status.LogFile, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	path = filepath.Join(home, defaultLogFile)
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("the daemon isn't running and %s doesn't exist; pass -file", path)
+	}
+	return path, nil
+}
+
+// logFilter keeps entries at or above a level; continuation lines (payloads, stack
+// traces) go with the entry they belong to
+type logFilter struct {
+	min  int
+	keep bool // whether the current entry is shown
+}
+
+// keeps reports whether a line is shown
+func (f *logFilter) keeps(line string) bool {
+	if logEntryStart.MatchString(line) {
+		f.keep = logLevel(line[len("15:04:05 "):]) >= f.min
+	}
+	return f.keep
+}
+
+// logLevel classifies a message by its leading emoji
+func logLevel(message string) int {
+	switch {
+	case strings.HasPrefix(message, "❌"), strings.HasPrefix(message, "💥"):
+		return logError
+	case strings.HasPrefix(message, "⚠️"):
+		return logWarn
+	}
+	return logInfo
+}
+
+// tail reads r to the end, returning the last n lines that pass the filter
+func (f *logFilter) tail(r io.Reader, n int) ([]string, error) {
+	var kept []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for scanner.Scan() {
+		if !f.keeps(scanner.Text()) || n == 0 {
+			continue
+		}
+		if len(kept) == n {
+			kept = kept[1:]
+		}
+		kept = append(kept, scanner.Text())
+	}
+	return kept, scanner.Err()
+}
+
+// followLog prints lines appended to the log until interrupted, reopening the file when
+// it is rotated or truncated
+func followLog(file *os.File, path string, filter *logFilter) error {
+	reader := bufio.NewReader(file)
+	var partial string
+	var next *os.File // the new file after a rotation, read once the old one is drained
+	for {
+		line, err := reader.ReadString('\n')
+		partial += line
+		if err == nil {
+			if text := strings.TrimSuffix(partial, "\n"); filter.keeps(text) {
+				fmt.Println(text)
+			}
+			partial = ""
+			continue
+		}
+		if !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to read log file: %w", err)
+		}
+
+		if next != nil {
+			file.Close()
+			file, next, partial = next, nil, ""
+			reader.Reset(file)
+			continue
+		}
+		time.Sleep(logFollowInterval)
+		if reopened, ok := rotatedLog(file, path); ok {
+			next = reopened
+		}
+	}
+}
+
+// rotatedLog opens path again when it no longer is the open file, or has shrunk
+func rotatedLog(file *os.File, path string) (*os.File, bool) {
+	current, err := os.Stat(path)
+	if err != nil {
+		return nil, false // mid-rotation; try again next time
+	}
+	open, err := file.Stat()
+	if err != nil {
+		return nil, false
+	}
+	offset, err := file.Seek(0, io.SeekCurrent)
+	if err != nil || (os.SameFile(open, current) && current.Size() >= offset) {
+		return nil, false
+	}
+	reopened, err := os.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	return reopened, true
+}
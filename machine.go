@@ -0,0 +1,137 @@
+// Bridge state machine
+// Each poll settles the bridge into one state, and behaviour that belongs to a change of
+// state (clearing when the player quits, starting the pause grace period, resending after
+// Discord reconnects) hangs off the transition instead of the poll's control flow.
+// The machine belongs to the poll goroutine.
+
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// BridgeState is what the poll loop last found
+type BridgeState int
+
+const (
+	BridgeNotRunning   BridgeState = iota // No player running
+	BridgePaused                          // A player is paused
+	BridgePlaying                         // A player is playing
+	BridgeStalled                         // A player is playing but its track can't be read
+	BridgeDisconnected                    // Discord is unreachable; players aren't polled
+	BridgeStopped                         // The last track of the queue ended; nothing plays
+
+	// AnyState matches every state in OnTransition
+	AnyState BridgeState = -1
+)
+
+// String returns the state name for logs
+func (s BridgeState) String() string {
+	switch s {
+	case BridgeNotRunning:
+		return "Not Running"
+	case BridgePaused:
+		return "Paused"
+	case BridgePlaying:
+		return "Playing"
+	case BridgeStalled:
+		return "Stalled"
+	case BridgeDisconnected:
+		return "Discord Disconnected"
+	case BridgeStopped:
+		return "Stopped"
+	default:
+		return "Any"
+	}
+}
+
+// Transition describes a change of state
+type Transition struct {
+	From, To BridgeState
+	Source   Source // player behind the new state (nil when none)
+	Err      error  // why the bridge stalled or disconnected
+}
+
+// transitionHook runs on transitions matching from and to
+type transitionHook struct {
+	from, to BridgeState
+	run      func(Transition)
+}
+
+// stateMachine holds the current state and the hooks attached to its transitions
+type stateMachine struct {
+	state BridgeState
+	hooks []transitionHook
+}
+
+// On attaches run to transitions from one state (or AnyState) to another
+func (m *stateMachine) On(from, to BridgeState, run func(Transition)) {
+	m.hooks = append(m.hooks, transitionHook{from, to, run})
+}
+
+// Enter moves to state, running matching hooks in the order they were attached.
+// Entering the current state does nothing.
+func (m *stateMachine) Enter(t Transition) {
+	if t.To == m.state {
+		return
+	}
+	t.From = m.state
+	m.state = t.To
+	for _, hook := range m.hooks {
+		if (hook.from == AnyState || hook.from == t.From) && (hook.to == AnyState || hook.to == t.To) {
+			hook.run(t)
+		}
+	}
+}
+
+// OnTransition attaches behaviour to a change of bridge state; call before the poll loop starts
+func (b *Bridge) OnTransition(from, to BridgeState, run func(Transition)) {
+	b.machine.On(from, to, run)
+}
+
+// enter moves the bridge to a new state
+func (b *Bridge) enter(state BridgeState, source Source, err error) {
+	b.machine.Enter(Transition{To: state, Source: source, Err: err})
+}
+
+// attachTransitions wires up the built-in behaviour
+func (b *Bridge) attachTransitions() {
+	// Whatever was showing is stale once the player is gone
+	b.OnTransition(AnyState, BridgeNotRunning, func(Transition) {
+		log.Println("💤 No player running")
+		b.ClearPresence()
+		b.remember(nil, StateNotRunning)
+	})
+
+	// A stopped player, or one that ran out of queue, shows nothing instead of the finished
+	// track as paused; the track is kept, since the player is still open
+	b.OnTransition(AnyState, BridgeStopped, func(t Transition) {
+		log.Println("⏹️  Playback stopped")
+		b.publishStopped(t.Source)
+		track, _ := b.Snapshot()
+		b.remember(track, StateStopped)
+	})
+
+	b.OnTransition(AnyState, BridgePaused, func(t Transition) {
+		log.Println("⏸️  Playback paused")
+		b.startPauseGrace(t.Source)
+	})
+	b.OnTransition(BridgePaused, AnyState, func(Transition) {
+		b.pausedAt = time.Time{}
+	})
+
+	b.OnTransition(AnyState, BridgeStalled, func(t Transition) {
+		log.Printf("⚠️  Error getting track info: %v", t.Err)
+	})
+	b.OnTransition(BridgeStalled, AnyState, func(t Transition) {
+		if t.To != BridgeDisconnected {
+			log.Println("✅ Track info readable again")
+		}
+	})
+
+	// A new connection starts without an activity, so the current track is resent
+	b.OnTransition(BridgeDisconnected, AnyState, func(Transition) {
+		b.Refresh()
+	})
+}
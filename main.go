@@ -3,31 +3,41 @@
 // Apple Music metadata to Discord Rich Presence (RPC).
 //
 // Architecture:
-//   - Polls macOS Music app via osascript every 10 seconds
+//   - Polls macOS Music app via osascript every 10 seconds (sooner near track ends, slower when idle)
 //   - Fetches album artwork from iTunes Search API with in-memory caching
 //   - Uses Discord Activity Type 2 (Listening) for native "Listening to" badge
 //   - Sends EndTimestamp once per track change for efficient progress bar rendering
 //
 // Build: go build -ldflags="-s -w" -o am-bridge
-// Run:   ./am-bridge
+// Run:   ./am-bridge            (same as ./am-bridge run; see ./am-bridge help)
+//
+// Menu bar mode (macOS, requires CGO):
+//   go build -tags menubar -ldflags="-s -w" -o am-bridge
+//   ./am-bridge --menubar
 
 package main
 
 import (
+	"container/list"
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
-	"os/exec"
 	"os/signal"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"golang.org/x/text/unicode/norm"
+
 	"am-discord-bridge/discord"
 )
 
@@ -36,17 +46,44 @@ import (
 // ============================================================================
 
 const (
-	// DiscordAppID - Create yours at https://discord.com/developers/applications
+	// DiscordAppID - Default application; override with discord.app_id, --app-id or AM_DISCORD_APP_ID
+	// Create yours at https://discord.com/developers/applications
 	DiscordAppID = "1463599058189946981"
 
-	// PollInterval - How often to check Apple Music state
+	// PollInterval - Default for poll_seconds, how often to check Apple Music state
 	PollInterval = 10 * time.Second
 
+	// IdlePollInterval - Relaxed poll rate once Music has been idle for IdleAfter (never
+	// faster than poll_seconds)
+	IdlePollInterval = 30 * time.Second
+	IdleAfter        = 5 * time.Minute
+
+	// TrackEndGrace - Delay past the expected track end before the one-shot poll
+	TrackEndGrace = time.Second
+
+	// QueueEndSlack - A pause or stop this close to the expected track end means the queue ran out
+	QueueEndSlack = 3 * time.Second
+
+	// SkipSettle - How long a skipped-to track must keep playing before it is published
+	SkipSettle = 3 * time.Second
+
+	// DiscordAbsentLogEvery - How often discord.optional logs that Discord still can't be found
+	DiscordAbsentLogEvery = time.Hour
+
 	// APITimeout - HTTP timeout for iTunes Search API
 	APITimeout = 15 * time.Second
 
+	// AppleScriptTimeout - Deadline for a single osascript call before it is killed
+	AppleScriptTimeout = 5 * time.Second
+
+	// ShutdownTimeout - How long clearing the presence may take on exit before giving up
+	ShutdownTimeout = 5 * time.Second
+
 	// iTunesSearchURL - Base URL for artwork lookups
 	iTunesSearchURL = "https://itunes.apple.com/search"
+
+	// iTunesLookupURL - Base URL for fetching catalog items by store ID
+	iTunesLookupURL = "https://itunes.apple.com/lookup"
 )
 
 // ============================================================================
@@ -60,6 +97,7 @@ const (
 	StateNotRunning PlayerState = iota
 	StatePaused
 	StatePlaying
+	StateStopped // Running with nothing loaded
 )
 
 func (s PlayerState) String() string {
@@ -70,32 +108,135 @@ func (s PlayerState) String() string {
 		return "Paused"
 	case StateNotRunning:
 		return "Not Running"
+	case StateStopped:
+		return "Stopped"
 	default:
 		return "Unknown"
 	}
 }
 
-// Track holds the metadata extracted from Apple Music
+// Track holds the metadata extracted from a player source
 type Track struct {
-	Name           string
-	Artist         string
-	Album          string
-	Duration       float64 // seconds
-	PlayerPosition float64 // seconds
+	Name           string   `json:"name"`
+	Artist         string   `json:"artist"`
+	Album          string   `json:"album"`
+	Duration       float64  `json:"duration"`        // seconds
+	PlayerPosition float64  `json:"player_position"` // seconds
+	Source         string   `json:"source"`          // player the track came from ("Apple Music", "Spotify")
+	ArtworkURL     string   `json:"artwork_url,omitempty"`
+	Playlist       string   `json:"playlist,omitempty"`     // name of the playlist or source being played
+	StreamTitle    string   `json:"stream_title,omitempty"` // now-playing text of a radio station or live stream
+	Local          bool     `json:"local"`                  // not streamed or purchased from the Apple Music catalog
+	StoreID        int64    `json:"store_id,omitempty"`
+	Rated          bool     `json:"-"`                       // the player reported Explicit from the catalog
+	PersistentID   string   `json:"persistent_id,omitempty"` // Music's library ID, for reading the track's own artwork
+	AppleMusicURL  string   `json:"apple_music_url,omitempty"`
+	Genre          string   `json:"genre,omitempty"`
+	Year           int      `json:"year,omitempty"`
+	Composer       string   `json:"composer,omitempty"`
+	Work           string   `json:"work,omitempty"`     // classical work the track belongs to
+	Movement       string   `json:"movement,omitempty"` // movement name within Work
+	Featured       string   `json:"featured,omitempty"` // features stripped from Name/Artist, if any
+	AlbumArtist    string   `json:"album_artist,omitempty"`
+	TrackNumber    int      `json:"track_number,omitempty"` // position on the disc, 0 if unknown
+	TrackCount     int      `json:"track_count,omitempty"`  // tracks on the disc
+	DiscNumber     int      `json:"disc_number,omitempty"`
+	DiscCount      int      `json:"disc_count,omitempty"`
+	Compilation    bool     `json:"compilation,omitempty"` // album marked as a compilation in the library
+	Loved          bool     `json:"loved,omitempty"`       // favorited in the library
+	Explicit       bool     `json:"explicit,omitempty"`    // rated explicit by the catalog or the player
+	SongLink       string   `json:"song_link,omitempty"`   // song.link page for every streaming service
+	Video          bool     `json:"video,omitempty"`       // a show or movie from the TV app, watched rather than heard
+	Show           string   `json:"show,omitempty"`        // TV series of an episode
+	Season         int      `json:"season,omitempty"`
+	Episode        int      `json:"episode,omitempty"`
+	Audiobook      bool     `json:"audiobook,omitempty"` // an audiobook from Books; Name and Album are the book
+	Chapter        string   `json:"chapter,omitempty"`   // chapter title, when Books reports one
+	ChapterNumber  int      `json:"chapter_number,omitempty"`
+	ChapterCount   int      `json:"chapter_count,omitempty"`
+	Shuffle        bool     `json:"shuffle,omitempty"` // the player is shuffling
+	Repeat         string   `json:"repeat,omitempty"`  // RepeatOne or RepeatAll; empty when off
+	Outputs        []string `json:"outputs,omitempty"` // AirPlay devices playing it, besides the Mac itself
+	Next           *Track   `json:"-"`                 // queued after this one, for presence.up_next; nil if unknown
+}
+
+// Repeat modes
+const (
+	RepeatOne = "one" // The track plays again
+	RepeatAll = "all" // The playlist starts over
+)
+
+// normalize converts text fields to Unicode NFC so composed and decomposed forms
+// of the same title compare, cache and search identically
+func (t *Track) normalize() *Track {
+	t.Name = norm.NFC.String(t.Name)
+	t.Artist = norm.NFC.String(t.Artist)
+	t.Album = norm.NFC.String(t.Album)
+	t.AlbumArtist = norm.NFC.String(t.AlbumArtist)
+	t.Playlist = norm.NFC.String(t.Playlist)
+	t.StreamTitle = norm.NFC.String(t.StreamTitle)
+	t.Genre = norm.NFC.String(t.Genre)
+	t.Composer = norm.NFC.String(t.Composer)
+	t.Work = norm.NFC.String(t.Work)
+	t.Movement = norm.NFC.String(t.Movement)
+	t.Show = norm.NFC.String(t.Show)
+	t.Chapter = norm.NFC.String(t.Chapter)
+	return t
 }
 
 // Equals checks if two tracks are the same (ignoring position)
 func (t Track) Equals(other Track) bool {
 	return t.Name == other.Name &&
 		t.Artist == other.Artist &&
-		t.Album == other.Album
+		t.Album == other.Album &&
+		t.StreamTitle == other.StreamTitle &&
+		t.Chapter == other.Chapter && t.ChapterNumber == other.ChapterNumber
+}
+
+// IsStream reports whether the track is a radio station or live stream without a fixed duration
+func (t Track) IsStream() bool {
+	return t.Duration <= 0
+}
+
+// Origin tells where the track plays from: "stream", "local" (a file in the library)
+// or "catalog" (streamed or purchased from Apple Music)
+func (t Track) Origin() string {
+	switch {
+	case t.IsStream():
+		return "stream"
+	case t.Local:
+		return "local"
+	default:
+		return "catalog"
+	}
+}
+
+// variousArtists - Album artist libraries and stores use for compilations
+const variousArtists = "Various Artists"
+
+// IsCompilation reports whether the track comes from a multi-artist compilation
+func (t Track) IsCompilation() bool {
+	return t.Compilation || strings.EqualFold(t.AlbumArtist, variousArtists)
+}
+
+// artworkArtist is the artist to search covers by; "" for compilations, whose cover
+// belongs to the album rather than the track artist
+func (t Track) artworkArtist() string {
+	if t.IsCompilation() {
+		return ""
+	}
+	return t.Artist
 }
 
 // iTunesSearchResult represents the API response structure
 type iTunesSearchResult struct {
 	ResultCount int `json:"resultCount"`
 	Results     []struct {
-		ArtworkURL100 string `json:"artworkUrl100"`
+		ArtworkURL100     string `json:"artworkUrl100"`
+		CollectionID      int64  `json:"collectionId"`
+		CollectionName    string `json:"collectionName"`
+		CollectionViewURL string `json:"collectionViewUrl"`
+		ArtistName        string `json:"artistName"`
 	} `json:"results"`
 }
 
@@ -103,17 +244,115 @@ type iTunesSearchResult struct {
 // Artwork Cache (Thread-Safe)
 // ============================================================================
 
-// ArtworkCache provides thread-safe caching for iTunes artwork URLs
-type ArtworkCache struct {
-	mu    sync.RWMutex
-	cache map[string]string // key: "artist|album" -> value: artworkUrl600
+// lruCache is a thread-safe cache of lookups by key. The least recently used entry is
+// evicted beyond maxEntries, and entries older than ttl, or that expired reports gone,
+// count as missing.
+type lruCache[V any] struct {
+	maxEntries int           // 0 = unbounded
+	ttl        time.Duration // 0 = never expires
+	expired    func(V) bool  // nil if only the TTL ages entries
+
+	mu      sync.Mutex
+	entries map[string]*list.Element // key -> element holding an lruEntry
+	order   *list.List               // most recently used first
+	stats   CacheStats
 }
 
-// NewArtworkCache creates a new artwork cache instance
-func NewArtworkCache() *ArtworkCache {
-	return &ArtworkCache{
-		cache: make(map[string]string),
+// lruEntry is one cached lookup
+type lruEntry[V any] struct {
+	key    string
+	value  V
+	stored time.Time
+}
+
+// CacheStats counts cache activity since start
+type CacheStats struct {
+	Entries   int `json:"entries"`
+	Hits      int `json:"hits"`
+	Misses    int `json:"misses"`
+	Evictions int `json:"evictions"` // dropped for space or age
+}
+
+// newLRUCache creates a cache holding at most maxEntries, each for at most ttl (0 lifts
+// either limit)
+func newLRUCache[V any](maxEntries int, ttl time.Duration, expired func(V) bool) *lruCache[V] {
+	return &lruCache[V]{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		expired:    expired,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// get retrieves a cached lookup if available
+func (c *lruCache[V]) get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, exists := c.entries[key]
+	if exists && c.stale(elem.Value.(*lruEntry[V])) {
+		c.remove(elem)
+		exists = false
+	}
+	if !exists {
+		c.stats.Misses++
+		var zero V
+		return zero, false
+	}
+	c.stats.Hits++
+	c.order.MoveToFront(elem)
+	return elem.Value.(*lruEntry[V]).value, true
+}
+
+// set stores a lookup in the cache
+func (c *lruCache[V]) set(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, exists := c.entries[key]; exists {
+		entry := elem.Value.(*lruEntry[V])
+		entry.value, entry.stored = value, time.Now()
+		c.order.MoveToFront(elem)
+		return
 	}
+	c.entries[key] = c.order.PushFront(&lruEntry[V]{key, value, time.Now()})
+	for c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		c.remove(c.order.Back())
+	}
+}
+
+// stale reports whether an entry outlived the TTL or expired
+func (c *lruCache[V]) stale(entry *lruEntry[V]) bool {
+	return (c.ttl > 0 && time.Since(entry.stored) > c.ttl) || (c.expired != nil && c.expired(entry.value))
+}
+
+// remove evicts an entry; callers hold c.mu
+func (c *lruCache[V]) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*lruEntry[V]).key)
+	c.stats.Evictions++
+}
+
+// Stats returns the current size and hit/miss counts
+func (c *lruCache[V]) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = c.order.Len()
+	return stats
+}
+
+// ArtworkCache caches album artwork lookups (an empty ArtworkURL records a miss). Covers
+// deleted by a temporary host count as missing.
+type ArtworkCache struct {
+	*lruCache[ArtworkResult] // key: "artist|album"
+}
+
+// NewArtworkCache creates a cache holding at most maxEntries, each for at most ttl
+// (0 lifts either limit)
+func NewArtworkCache(maxEntries int, ttl time.Duration) *ArtworkCache {
+	return &ArtworkCache{newLRUCache(maxEntries, ttl, ArtworkResult.expired)}
 }
 
 // cacheKey generates a unique key for artist/album combination
@@ -121,19 +360,14 @@ func (c *ArtworkCache) cacheKey(artist, album string) string {
 	return artist + "|" + album
 }
 
-// Get retrieves a cached artwork URL if available
-func (c *ArtworkCache) Get(artist, album string) (string, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	url, exists := c.cache[c.cacheKey(artist, album)]
-	return url, exists
+// Get retrieves a cached lookup if available
+func (c *ArtworkCache) Get(artist, album string) (ArtworkResult, bool) {
+	return c.get(c.cacheKey(artist, album))
 }
 
-// Set stores an artwork URL in the cache
-func (c *ArtworkCache) Set(artist, album, artworkURL string) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.cache[c.cacheKey(artist, album)] = artworkURL
+// Set stores a lookup in the cache
+func (c *ArtworkCache) Set(artist, album string, result ArtworkResult) {
+	c.set(c.cacheKey(artist, album), result)
 }
 
 // ============================================================================
@@ -141,170 +375,193 @@ func (c *ArtworkCache) Set(artist, album, artworkURL string) {
 // ============================================================================
 
 // runAppleScript executes an AppleScript and returns the trimmed output
-func runAppleScript(script string) (string, error) {
-	cmd := exec.Command("osascript", "-e", script)
-	output, err := cmd.Output()
-	if err != nil {
-		return "", err
-	}
-	return strings.TrimSpace(string(output)), nil
+func runAppleScript(ctx context.Context, scripts ScriptRunner, script string) (string, error) {
+	return scripts.Run(ctx, "AppleScript", script)
 }
 
-// GetPlayerState checks if Music app is running and its playback state
-func GetPlayerState() (PlayerState, error) {
-	// Check if Music app is running
-	script := `tell application "System Events" to (name of processes) contains "Music"`
-	result, err := runAppleScript(script)
-	if err != nil {
-		return StateNotRunning, err
-	}
+// appleScriptQuoter escapes the only two characters special inside an AppleScript string
+var appleScriptQuoter = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
 
-	if result != "true" {
-		return StateNotRunning, nil
-	}
+// appleScriptString quotes s as an AppleScript string literal. Go's %q doesn't: AppleScript
+// has no \u or \x escapes, so any other character is written as it is.
+func appleScriptString(s string) string {
+	return `"` + appleScriptQuoter.Replace(s) + `"`
+}
 
-	// Get player state
-	script = `tell application "Music" to player state as string`
-	result, err = runAppleScript(script)
+// runJXA executes a JavaScript for Automation script and decodes its JSON result into v
+func runJXA(ctx context.Context, scripts ScriptRunner, script string, v any) error {
+	output, err := scripts.Run(ctx, "JavaScript", script)
 	if err != nil {
-		return StateNotRunning, err
+		return err
+	}
+	if err := json.Unmarshal([]byte(output), v); err != nil {
+		return fmt.Errorf("unexpected JXA output %q: %w", output, err)
 	}
+	return nil
+}
 
-	switch result {
-	case "playing":
-		return StatePlaying, nil
-	case "paused":
-		return StatePaused, nil
-	default:
-		return StateNotRunning, nil
+// ============================================================================
+// iTunes API Client
+// ============================================================================
+
+// httpClient is a shared client with timeout for all API requests
+var httpClient = &http.Client{
+	Timeout:   APITimeout,
+	Transport: sharedTransport,
+}
+
+// iTunesStore selects the storefront searched by every iTunes request (US if unset); Reload
+// swaps it under searches already running
+var iTunesStore atomic.Pointer[ITunesConfig]
+
+// ConfigureITunes sets the storefront for subsequent iTunes searches
+func ConfigureITunes(cfg ITunesConfig) {
+	iTunesStore.Store(&cfg)
+}
+
+// iTunesRequestURL builds a search URL for the configured storefront
+func iTunesRequestURL(params url.Values) string {
+	return iTunesEndpointURL(iTunesSearchURL, params)
+}
+
+// iTunesEndpointURL builds a search or lookup URL for the configured storefront
+func iTunesEndpointURL(endpoint string, params url.Values) string {
+	if store := iTunesStore.Load(); store != nil {
+		if store.Country != "" {
+			params.Set("country", store.Country)
+		}
+		if store.Lang != "" {
+			params.Set("lang", store.Lang)
+		}
 	}
+	return fmt.Sprintf("%s?%s", endpoint, params.Encode())
 }
 
-// GetCurrentTrack extracts metadata from the currently playing track
-func GetCurrentTrack() (*Track, error) {
-	// Combined AppleScript for efficiency - single osascript call
-	script := `
-		tell application "Music"
-			set trackName to name of current track
-			set trackArtist to artist of current track
-			set trackAlbum to album of current track
-			set trackDuration to duration of current track
-			set playerPos to player position
-			return trackName & "|||" & trackArtist & "|||" & trackAlbum & "|||" & trackDuration & "|||" & playerPos
-		end tell
-	`
+// errNoITunesResults is returned when a search succeeds but matches nothing
+var errNoITunesResults = errors.New("no results")
 
-	result, err := runAppleScript(script)
+// iTunesAlbumResults - Albums fetched per album search, so the one asked for can be picked out
+const iTunesAlbumResults = 10
+
+// searchITunes performs a single iTunes API album search and returns the album whose
+// names match best; an empty artist matches any
+func searchITunes(ctx context.Context, query, artist string, albums ...string) (ArtworkResult, error) {
+	result, err := queryITunes(ctx, query, "music", "album", iTunesAlbumResults)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get track info: %w", err)
+		return ArtworkResult{}, err
 	}
 
-	parts := strings.Split(result, "|||")
-	if len(parts) != 5 {
-		return nil, fmt.Errorf("unexpected AppleScript output format: %s", result)
+	best, bestScore := -1, 0.0
+	for i, found := range result.Results {
+		if artist != "" && !creditMatches(artist, found.ArtistName) {
+			continue
+		}
+		for _, album := range albums {
+			if score := nameSimilarity(album, found.CollectionName); score >= minMatchScore && score > bestScore {
+				best, bestScore = i, score
+			}
+		}
 	}
-
-	duration, err := strconv.ParseFloat(parts[3], 64)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse duration: %w", err)
+	if best < 0 {
+		return ArtworkResult{}, errNoITunesResults // nothing close enough; the best guess would be another album
 	}
 
-	position, err := strconv.ParseFloat(parts[4], 64)
+	found := result.Results[best]
+	artworkURL, err := highResArtwork(ctx, found.ArtworkURL100)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse position: %w", err)
+		return ArtworkResult{}, err
 	}
-
-	return &Track{
-		Name:           parts[0],
-		Artist:         parts[1],
-		Album:          parts[2],
-		Duration:       duration,
-		PlayerPosition: position,
+	return ArtworkResult{
+		ArtworkURL:   artworkURL,
+		AlbumURL:     found.CollectionViewURL,
+		CollectionID: found.CollectionID,
+		Score:        bestScore,
 	}, nil
 }
 
-// ============================================================================
-// iTunes API Client
-// ============================================================================
-
-// httpClient is a shared client with timeout for all API requests
-var httpClient = &http.Client{
-	Timeout: APITimeout,
+// searchITunesEntity searches one media/entity pair and returns the first artwork URL
+func searchITunesEntity(ctx context.Context, query, media, entity string) (string, error) {
+	result, err := queryITunes(ctx, query, media, entity, 1)
+	if err != nil {
+		return "", err
+	}
+	return highResArtwork(ctx, result.Results[0].ArtworkURL100)
 }
 
-// searchITunes performs a single iTunes API search and returns artwork URL if found
-func searchITunes(query string) (string, error) {
+// queryITunes runs one search, failing with errNoITunesResults when it finds nothing
+func queryITunes(ctx context.Context, query, media, entity string, limit int) (*iTunesSearchResult, error) {
 	params := url.Values{}
 	params.Set("term", query)
-	params.Set("media", "music")
-	params.Set("entity", "album")
-	params.Set("limit", "1")
+	params.Set("media", media)
+	params.Set("entity", entity)
+	params.Set("limit", strconv.Itoa(limit))
 
-	requestURL := fmt.Sprintf("%s?%s", iTunesSearchURL, params.Encode())
+	requestURL := iTunesRequestURL(params)
 
-	resp, err := httpClient.Get(requestURL)
+	resp, err := iTunesGet(ctx, requestURL)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("status %d", resp.StatusCode)
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
 	}
 
 	var result iTunesSearchResult
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if result.ResultCount == 0 || len(result.Results) == 0 {
-		return "", fmt.Errorf("no results")
+		return nil, errNoITunesResults
 	}
-
-	// Transform 100x100 URL to 600x600 for high resolution
-	artworkURL := result.Results[0].ArtworkURL100
-	artworkURL = strings.Replace(artworkURL, "100x100bb", "600x600bb", 1)
-
-	return artworkURL, nil
+	return &result, nil
 }
 
-// FetchArtworkURL queries the iTunes Search API to find album artwork
+// FetchAlbumArtwork queries the iTunes Search API to find album artwork
 // Uses multiple fallback search strategies for better hit rate
-// Returns the 600x600 version of the artwork URL
-func FetchArtworkURL(artist, album string) (string, error) {
-	// Clean up common album name patterns that hurt search
-	cleanAlbum := album
-	// Remove " - Single", " (From ...)" etc.
-	if idx := strings.Index(cleanAlbum, " - Single"); idx != -1 {
-		cleanAlbum = cleanAlbum[:idx]
-	}
-	if idx := strings.Index(cleanAlbum, " (From"); idx != -1 {
-		cleanAlbum = cleanAlbum[:idx]
-	}
+// Returns the 600x600 version of the artwork URL, with the album's Apple Music page
+func FetchAlbumArtwork(ctx context.Context, artist, album string, cleanup []CleanupRule) (ArtworkResult, error) {
+	// Remove the " - Single", " (From ...)" etc. that hurt search
+	cleanAlbum := cleanName(cleanup, album)
 
-	// Strategy 1: artist + clean album name
-	if url, err := searchITunes(fmt.Sprintf("%s %s", artist, cleanAlbum)); err == nil {
-		return url, nil
-	}
+	// Featured artists only add noise to album searches
+	artist = mainArtist(artist)
 
+	// Strategy 1: artist + clean album name
 	// Strategy 2: just the album name (works for well-known albums)
-	if url, err := searchITunes(cleanAlbum); err == nil {
-		return url, nil
+	// Strategy 3: just the artist (finds the album among their best-known ones)
+	// Strategy 4: original album name as fallback
+	// Without an artist (compilations) only the album strategies apply. Every result
+	// must resemble the artist and album asked for, or the strategy counts as a miss.
+	queries := []string{fmt.Sprintf("%s %s", artist, cleanAlbum), cleanAlbum, artist}
+	if artist == "" {
+		queries = []string{cleanAlbum}
 	}
-
-	// Strategy 3: just the artist (will get their most popular album)
-	if url, err := searchITunes(artist); err == nil {
-		return url, nil
+	if cleanAlbum != album {
+		queries = append(queries, album)
 	}
 
-	// Strategy 4: original album name as fallback
-	if cleanAlbum != album {
-		if url, err := searchITunes(album); err == nil {
-			return url, nil
+	var searchErr error
+	for _, query := range queries {
+		result, err := searchITunes(ctx, query, artist, cleanAlbum, album)
+		if err == nil {
+			return result, nil
+		}
+		if errors.Is(err, errCircuitOpen) {
+			return ArtworkResult{}, err // don't try the remaining strategies during an outage
+		}
+		if !errors.Is(err, errNoITunesResults) && !errors.Is(err, errNoArtwork) {
+			searchErr = err
 		}
 	}
 
-	return "", fmt.Errorf("no artwork found for %s - %s", artist, album)
+	// A failed request isn't a miss; report it so callers don't cache it
+	if searchErr != nil {
+		return ArtworkResult{}, searchErr
+	}
+	return ArtworkResult{}, fmt.Errorf("%w for %s - %s", errNoArtwork, artist, album)
 }
 
 // ============================================================================
@@ -313,21 +570,134 @@ func FetchArtworkURL(artist, album string) (string, error) {
 
 // Bridge manages the connection between Apple Music and Discord
 type Bridge struct {
-	cache     *ArtworkCache
-	client    *discord.Client
-	connected bool
-	lastTrack *Track
-	lastState PlayerState
-	mu        sync.Mutex
-}
-
-// NewBridge creates a new Bridge instance
-func NewBridge() *Bridge {
-	return &Bridge{
-		cache:     NewArtworkCache(),
-		client:    discord.NewClient(DiscordAppID),
+	config        *Config
+	sources       *SourceManager
+	cache         *ArtworkCache
+	artwork       ArtworkFetcher
+	catalog       *CatalogCache
+	uploader      *ArtworkUploader
+	songLinks     *SongLinks     // nil unless songlink.enabled
+	assets        *AssetUploader // nil unless discord.assets.enabled
+	client        *discord.Client
+	scripts       ScriptRunner
+	clock         Clock
+	ctx           context.Context // bounds background lookups; cancelled on shutdown
+	fixedArtwork  bool            // artwork supplied by WithArtworkFetcher, kept across reloads
+	presence      *presenceQueue
+	machine       stateMachine     // poll goroutine only
+	automation    scriptHealth     // poll goroutine only
+	notifier      *FailureNotifier // nil unless notify.enabled (poll goroutine only)
+	plays         *PlayTracker
+	scrobbler     *ListenBrainz
+	history       *HistoryStore
+	power         *PowerMonitor
+	sinks         []Sink               // outputs built by NewSinks, replaced on reload
+	extraSinks    []Sink               // outputs added with AddSink (the overlay)
+	reloads       <-chan *Config       // new configs from WatchConfig (nil when not watching)
+	wakes         <-chan time.Duration // sleep durations from WatchWake
+	powerEvents   <-chan powerEvent    // notifications from WatchPower (nil when not watching)
+	connected     bool
+	resumePath    string    // where the published state is saved for RestorePresence; empty disables it
+	discordSeen   time.Time // when discord.optional last logged Discord missing, zero once it connects (poll goroutine only)
+	lastTrack     *Track
+	lastState     PlayerState
+	paused        bool      // presence publishing suspended by the user
+	hiddenBy      string    // what clears the presence by itself (lock, schedule, Focus, sleep), "" if nothing
+	idleSince     time.Time // when playback last stopped (poll goroutine only)
+	pausedAt      time.Time // when a paused presence was published (poll goroutine only)
+	trackEnds     time.Time // when the playing track is due to end, zero if unknown (poll goroutine only)
+	screenLocked  bool      // presence cleared for a locked screen (poll goroutine only)
+	sleepingSince time.Time // when the Mac announced sleep, zero once awake (poll goroutine only)
+	wokeAt        time.Time // when the last wake notification arrived (poll goroutine only)
+	offSchedule   bool      // presence cleared by presence.schedule (poll goroutine only)
+	focused       bool      // presence cleared for a Focus mode (poll goroutine only)
+	focusDenied   bool      // Assertions.json couldn't be read, already logged (poll goroutine only)
+	settling      *Track    // skipped-to track waiting out SkipSettle (poll goroutine only)
+	settledFrom   time.Time // when the settling track started playing
+	artworkFor    *Track    // track waiting on a background artwork lookup
+	ratingFor     *Track    // track hidden until the catalog rates it, for privacy.explicit "hide"
+	mu            sync.Mutex
+}
+
+// NewBridge creates a new Bridge instance. Options replace the default osascript
+// runner, artwork chain and clock.
+func NewBridge(config *Config, opts ...BridgeOption) (*Bridge, error) {
+	appID, err := config.Discord.ActiveAppID()
+	if err != nil {
+		return nil, err
+	}
+
+	ConfigureITunes(config.ITunes)
+	ConfigureAppleMusic(config.AppleMusic, config.ITunes)
+	ConfigureCider(config.Cider)
+	ConfigureTrace(config)
+	if err := ConfigureNetwork(config.Network); err != nil {
+		return nil, err
+	}
+
+	b := &Bridge{
+		config:    config,
+		scripts:   OSAScriptRunner{},
+		clock:     systemClock{},
+		ctx:       context.Background(),
+		cache:     config.Artwork.NewCache(),
+		catalog:   config.Artwork.NewCatalogCache(),
+		songLinks: NewSongLinks(config.SongLink),
+		assets:    NewAssetUploader(config.Discord.Assets),
+		scrobbler: NewListenBrainz(config.ListenBrainz),
+		sinks:     NewSinks(config),
+		history:   OpenHistory(config.History),
+		power:     NewPowerMonitor(config.Power),
+		client:    discord.NewClient(appID),
 		lastState: StateNotRunning,
 	}
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	if b.sources, err = NewSourceManager(config.Sources, b.scripts); err != nil {
+		return nil, err
+	}
+	if !b.fixedArtwork {
+		if b.artwork, err = NewArtworkChain(config.Artwork); err != nil {
+			return nil, err
+		}
+	}
+	b.uploader = NewArtworkUploader(config.LocalArtwork, b.scripts)
+	b.plays = &PlayTracker{clock: b.clock}
+	b.notifier = NewFailureNotifier(config.Notify, b.scripts, b.clock)
+	b.attachTransitions()
+	b.client.SetTransport(config.Discord.Transport)
+	b.client.SetSocket(config.Discord.Socket)
+	if activeTracer != nil {
+		b.client.SetTracer(tracef)
+	}
+	b.presence = newPresenceQueue(func(activity *discord.Activity) error {
+		if activity == nil {
+			// Clears still go out after shutdown starts; cmdRun bounds how long they take
+			return b.client.ClearActivity(context.Background())
+		}
+		return b.client.SetActivity(b.ctx, *activity)
+	})
+	goService("Discord watcher", b.watchDiscord)
+	return b, nil
+}
+
+// watchDiscord surfaces asynchronous Discord failures. A lost connection is marked
+// so the next poll reconnects and republishes the current track.
+func (b *Bridge) watchDiscord() {
+	for err := range b.client.Errors() {
+		if errors.Is(err, discord.ErrConnectionLost) {
+			log.Println("⚠️  Discord connection lost (will reconnect)")
+			b.presence.Reset()
+			b.mu.Lock()
+			b.connected = false
+			b.lastTrack = nil
+			b.mu.Unlock()
+			continue
+		}
+		log.Printf("⚠️  Discord error: %v", err)
+	}
 }
 
 // Connect establishes connection to Discord RPC
@@ -344,7 +714,11 @@ func (b *Bridge) Connect() error {
 	}
 
 	b.connected = true
-	log.Println("✓ Connected to Discord RPC")
+	if ready := b.client.Ready(); ready != nil && ready.User.Username != "" {
+		log.Printf("✓ Connected to Discord RPC as %s (v%d)", ready.User.Handle(), ready.Version)
+	} else {
+		log.Println("✓ Connected to Discord RPC")
+	}
 	return nil
 }
 
@@ -357,13 +731,49 @@ func (b *Bridge) Disconnect() {
 		return
 	}
 
+	b.presence.Reset()
 	b.client.Logout()
 	b.connected = false
 	log.Println("✓ Disconnected from Discord RPC")
 }
 
-// ClearPresence removes the current activity from Discord
+// AddSink publishes to another output alongside Discord and the configured sinks
+func (b *Bridge) AddSink(sink Sink) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.extraSinks = append(b.extraSinks, sink)
+}
+
+// Sinks returns every output, Discord first
+func (b *Bridge) Sinks() []Sink {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Sink{discordSink{b}}, b.otherSinks()...)
+}
+
+// otherSinks returns the outputs besides Discord; callers hold b.mu
+func (b *Bridge) otherSinks() []Sink {
+	return append(append([]Sink(nil), b.sinks...), b.extraSinks...)
+}
+
+// CloseSinks finishes the configured sinks' background work on shutdown
+func (b *Bridge) CloseSinks() {
+	b.mu.Lock()
+	sinks := b.sinks
+	b.sinks = nil
+	b.mu.Unlock()
+	closeSinks(sinks)
+}
+
+// ClearPresence removes the current track from every sink
 func (b *Bridge) ClearPresence() {
+	for _, sink := range b.Sinks() {
+		sink.Clear()
+	}
+}
+
+// clearDiscord removes the current activity from Discord
+func (b *Bridge) clearDiscord() {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
@@ -371,66 +781,523 @@ func (b *Bridge) ClearPresence() {
 		return
 	}
 
-	b.client.ClearActivity()
+	b.artworkFor = nil
+	b.presence.Submit(nil)
 	log.Println("✓ Cleared Discord presence")
 }
 
-// UpdatePresence updates the Discord Rich Presence with current track info
+// SetPresencePaused suspends or resumes publishing to Discord.
+// Pausing clears the current presence; resuming forces a fresh update on the next poll.
+func (b *Bridge) SetPresencePaused(paused bool) {
+	b.mu.Lock()
+	if b.paused == paused {
+		b.mu.Unlock()
+		return
+	}
+	b.paused = paused
+	b.lastTrack = nil
+	b.mu.Unlock()
+
+	if paused {
+		b.ClearPresence()
+		log.Println("🙈 Presence publishing paused")
+	} else {
+		log.Println("👀 Presence publishing resumed")
+	}
+}
+
+// SwitchProfile re-handshakes with the application ID of a named profile.
+// The next poll reconnects and republishes the current track under the new app.
+func (b *Bridge) SwitchProfile(name string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	discordConfig := b.config.Discord
+	discordConfig.Profile = name
+	appID, err := discordConfig.ActiveAppID()
+	if err != nil {
+		return err
+	}
+
+	if b.connected {
+		b.presence.Reset()
+		b.client.ClearActivity(b.ctx)
+		b.client.Logout()
+		b.connected = false
+	}
+	b.client.SetClientID(appID)
+	b.config.Discord.Profile = name
+	b.lastTrack = nil
+
+	log.Printf("🔀 Switched to Discord profile %q (%s)", name, appID)
+	return nil
+}
+
+// Profile returns the active profile name ("" when using app_id or the default)
+func (b *Bridge) Profile() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.config.Discord.Profile
+}
+
+// Connected reports whether the Discord RPC connection is up
+func (b *Bridge) Connected() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.connected
+}
+
+// DiscordUser returns the signed-in Discord handle ("" when disconnected)
+func (b *Bridge) DiscordUser() string {
+	if ready := b.client.Ready(); ready != nil && ready.User.Username != "" {
+		return ready.User.Handle()
+	}
+	return ""
+}
+
+// PresencePaused reports whether presence publishing is suspended
+func (b *Bridge) PresencePaused() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.paused
+}
+
+// HiddenBy reports what is keeping the presence cleared without the user pausing it
+// ("schedule", "Focus", "screen locked", "sleep"), or "" when nothing is
+func (b *Bridge) HiddenBy() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.hiddenBy
+}
+
+// setHiddenBy records what the poll found hiding the presence
+func (b *Bridge) setHiddenBy(reason string) {
+	b.mu.Lock()
+	b.hiddenBy = reason
+	b.mu.Unlock()
+}
+
+// CacheStats reports on the cover cache
+func (b *Bridge) CacheStats() CacheStats {
+	b.mu.Lock()
+	cache := b.cache
+	b.mu.Unlock()
+	return cache.Stats()
+}
+
+// Snapshot returns the last published track (nil if none) and player state
+func (b *Bridge) Snapshot() (*Track, PlayerState) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastTrack, b.lastState
+}
+
+// remember records the last observed track and state
+func (b *Bridge) remember(track *Track, state PlayerState) {
+	b.mu.Lock()
+	b.lastTrack = track
+	b.lastState = state
+	// Saved as shown, so a hidden track doesn't come back on restart or sit on disk
+	shown := b.config.Privacy.Masked(track)
+	if track != nil && (b.ratingFor == track || b.config.Privacy.Blocked(track)) {
+		shown = nil
+	}
+	b.mu.Unlock()
+
+	if b.resumePath != "" {
+		if err := savePresence(b.resumePath, shown, state, b.clock.Now()); err != nil {
+			log.Printf("⚠️  Failed to save presence: %v", err)
+		}
+	}
+}
+
+// UpdatePresence publishes the track to Discord and every other sink
 func (b *Bridge) UpdatePresence(track *Track, state PlayerState) {
+	b.mu.Lock()
+	if b.paused {
+		b.mu.Unlock()
+		return
+	}
+	// The rating comes with the catalog match; until it is known the track stays hidden
+	b.ratingFor = nil
+	unrated := false
+	if b.needsRating(track) {
+		if match, known := b.catalog.Get(track); !known {
+			unrated = true
+			b.rateAsync(track)
+		} else if match != nil {
+			track.Explicit = match.Explicit
+		}
+	}
+	// Privacy rules run before any artwork lookup so blocked tracks never leave the machine
+	blocked := unrated || b.config.Privacy.Blocked(track)
+	b.artworkFor = nil // Any background lookup is for an older update
+	if !blocked {
+		// Every sink gets the cover, whether or not Discord is connected. What is known is
+		// published right away; a cover that needs looking up follows in a second update.
+		artworkURL, known := b.quickArtwork(track)
+		track.ArtworkURL = artworkURL
+		if !known {
+			b.fetchArtworkAsync(track, state)
+		}
+	}
+	masked := b.config.Privacy.Masked(track)
+	b.mu.Unlock()
+
+	for _, sink := range b.Sinks() {
+		shown := masked
+		if _, ok := sink.(discordSink); ok {
+			shown = track // masked as it is rendered, after its lookups
+		}
+		publisher, masks := sink.(blockedPublisher)
+		switch {
+		case !blocked:
+			sink.Publish(shown, state)
+		case masks:
+			publisher.PublishBlocked(track)
+		default:
+			sink.Publish(nil, state)
+		}
+	}
+}
+
+// rateAsync looks up the catalog rating of a track hidden for lack of one, and has the next
+// poll publish it again once it is known. Call with b.mu held.
+func (b *Bridge) rateAsync(track *Track) {
+	b.ratingFor = track
+	resolver := b.artworkResolver()
+	probe := *track
+	ctx := b.ctx
+
+	goSafe("rating lookup", func() {
+		resolver.catalogMatch(ctx, &probe)
+		for _, delay := range artworkRetryDelays {
+			if _, known := resolver.catalog.Get(&probe); known || !b.awaitingRating(track) {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return // shutting down
+			case <-time.After(delay):
+			}
+			resolver.catalogMatch(ctx, &probe)
+		}
+
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if b.ratingFor != track {
+			return // skipped or republished since
+		}
+		b.ratingFor = nil
+		if _, known := resolver.catalog.Get(&probe); !known {
+			log.Printf("🔞 Couldn't rate %s - %s, keeping it hidden", track.Name, track.Artist)
+			return
+		}
+		b.lastTrack = nil // republished with its rating on the next poll
+	})
+}
+
+// awaitingRating reports whether track is still the one waiting for its rating
+func (b *Bridge) awaitingRating(track *Track) bool {
 	b.mu.Lock()
 	defer b.mu.Unlock()
+	return b.ratingFor == track
+}
 
-	if !b.connected {
+// needsRating reports whether hiding explicit tracks waits on the catalog for this one;
+// call with b.mu held
+func (b *Bridge) needsRating(track *Track) bool {
+	return b.config.Privacy.Explicit == ExplicitHide && !track.IsExplicit() &&
+		!track.Local && !track.IsStream() && !track.Video && !track.Audiobook && !track.Rated
+}
+
+// updateDiscord updates the Discord Rich Presence with current track info
+func (b *Bridge) updateDiscord(track *Track, state PlayerState) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.connected || b.paused {
+		return
+	}
+
+	if err := b.useAppFor(track); err != nil {
+		log.Printf("⚠️  %v", err)
+		return
+	}
+
+	// The cover was resolved as far as it is known by UpdatePresence
+	artworkURL := track.ArtworkURL
+	activity := b.buildActivity(track, state, artworkURL)
+
+	queued, err := b.presence.Submit(&activity)
+	if err != nil {
+		log.Printf("⚠️  Failed to update Discord presence: %v", err)
 		return
 	}
+	if queued {
+		log.Println("⏳ Presence update queued (Discord rate limit)")
+	}
 
-	// Fetch or retrieve cached artwork URL
-	artworkURL := ""
-	if cachedURL, exists := b.cache.Get(track.Artist, track.Album); exists {
-		artworkURL = cachedURL
+	if state == StatePaused {
+		log.Printf("⏸️  Showing paused: %s - %s", track.Name, track.Artist)
+	} else if track.IsStream() {
+		log.Printf("📻 Now streaming: %s (%s)", track.StreamTitle, track.Name)
+	} else if track.Video {
+		log.Printf("📺 Now watching: %s", RenderTemplate("{show} · {episode_code} · {name}", track))
+	} else if track.Audiobook {
+		log.Printf("📖 Now listening: %s", RenderTemplate("{name} · {artist} · {chapter}", track))
 	} else {
-		// Fetch synchronously - block until we have artwork
-		// This ensures Discord gets the artwork on first track detection
-		log.Printf("🔍 Fetching artwork for: %s - %s", track.Artist, track.Album)
-		if url, err := FetchArtworkURL(track.Artist, track.Album); err == nil {
-			b.cache.Set(track.Artist, track.Album, url)
-			artworkURL = url
-			log.Printf("📀 Cached artwork: %s", artworkURL)
+		log.Printf("🎵 Now playing: %s - %s (%s)", track.Name, track.Artist, track.Album)
+	}
+	if artworkURL != "" {
+		log.Printf("🖼️  Artwork URL: %s", artworkURL)
+	}
+}
+
+// buildActivity renders a track as a Discord activity. Field masks are applied here rather
+// than before publishing, so the cover and links are still looked up from the real track.
+func (b *Bridge) buildActivity(track *Track, state PlayerState, artworkURL string) discord.Activity {
+	if masked := b.config.Privacy.Masked(track); masked != track {
+		if masked.ArtworkURL != track.ArtworkURL {
+			artworkURL = masked.ArtworkURL
+		}
+		track = masked
+	}
+	if album := cleanName(b.config.Presence.AlbumCleanup, track.Album); album != track.Album {
+		cleaned := *track
+		cleaned.Album = album
+		track = &cleaned
+	}
+	activity := b.trackActivity(track, artworkURL)
+	activity.SmallImage, activity.SmallText = b.smallImage(track, state)
+
+	if track.Loved {
+		switch b.config.Presence.Loved {
+		case LovedInDetails:
+			activity.Details += " " + lovedMark
+		case LovedSmallImage:
+			activity.SmallImage, activity.SmallText = b.config.Presence.SmallImage.Loved, b.text(msgLoved)
+		}
+	}
+
+	// Streams already name their station; everything else can credit the playlist
+	if track.Playlist != "" && !track.IsStream() {
+		via := b.text(msgVia, track.Playlist)
+		switch b.config.Presence.ShowPlaylist {
+		case PlaylistInState:
+			activity.State = appendSegment(activity.State, via)
+		case PlaylistInSmallText:
+			activity.SmallText = appendSegment(activity.SmallText, via)
+		}
+	}
+
+	if modes := track.playModes(); modes != "" {
+		switch b.config.Presence.PlayModes {
+		case PlayModesInState:
+			activity.State = appendSegment(activity.State, modes)
+		case PlayModesInSmallText:
+			activity.SmallText = appendSegment(activity.SmallText, modes)
+		}
+	}
+
+	if len(track.Outputs) > 0 {
+		on := b.text(msgOn, strings.Join(track.Outputs, ", "))
+		switch b.config.Presence.Output {
+		case OutputInState:
+			activity.State = appendSegment(activity.State, on)
+		case OutputInSmallText:
+			activity.SmallText = appendSegment(activity.SmallText, on)
+		}
+	}
+
+	// The next track is only named if it could be shown itself
+	if next := track.Next; next != nil && !track.IsStream() && !b.config.Privacy.Blocked(next) && b.config.Privacy.Masked(next) == next {
+		upNext := b.text(msgUpNext, next.Name)
+		if next.Artist != "" {
+			upNext = b.text(msgUpNext, next.Name+templateSeparator+next.Artist)
+		}
+		switch b.config.Presence.UpNext {
+		case UpNextInSmallText:
+			activity.SmallText = appendSegment(activity.SmallText, upNext)
+		case UpNextInLargeText:
+			activity.LargeText = appendSegment(activity.LargeText, upNext)
+		}
+	}
+
+	// A paused track keeps its details but loses the running progress bar
+	if state == StatePaused {
+		activity.Timestamps = nil
+		if activity.State == "" {
+			activity.State = b.text(msgPaused)
 		} else {
-			log.Printf("⚠️  Artwork fetch failed: %v", err)
+			activity.State = b.text(msgPaused) + templateSeparator + activity.State
 		}
 	}
+	return activity
+}
 
-	// Calculate end timestamp for Discord progress bar
-	// Only Discord handles the animation from here
-	remainingSeconds := track.Duration - track.PlayerPosition
-	endTime := time.Now().Add(time.Duration(remainingSeconds) * time.Second)
+// trackActivity lays out the text, artwork, timestamps and buttons for a track
+func (b *Bridge) trackActivity(track *Track, artworkURL string) discord.Activity {
+	if track.Video {
+		return b.watchingActivity(track, artworkURL)
+	}
+	if track.Audiobook {
+		return b.audiobookActivity(track, artworkURL)
+	}
+
+	// Radio and live streams: show the stream title, no timestamps
+	if track.IsStream() {
+		activity := discord.Activity{
+			Type:       discord.ActivityTypeListening,
+			Details:    track.Name,
+			LargeImage: b.largeImage(artworkURL),
+			LargeText:  track.Name,
+		}
+		if track.StreamTitle != "" {
+			activity.Details = track.StreamTitle
+			activity.State = b.text(msgOn, track.Name)
+		}
+		return activity
+	}
 
 	// Build the activity with Type 2 = Listening
+	presence := b.config.Presence
+	track = applyFeatured(track, presence.Featured)
+	details, state, largeText := presence.Details, presence.State, presence.LargeText
+	if presence.Classical.Applies(track) {
+		details, state = presence.Classical.Details, presence.Classical.State
+	}
+	if track.IsCompilation() && presence.CompilationLargeText != "" {
+		largeText = presence.CompilationLargeText
+	}
+
 	activity := discord.Activity{
 		Type:       discord.ActivityTypeListening, // "Listening to" badge!
-		Details:    track.Name,
-		State:      fmt.Sprintf("by %s", track.Artist),
-		LargeImage: artworkURL,
-		LargeText:  track.Album,
-		Timestamps: &discord.Timestamps{
-			End: &endTime,
-		},
+		Details:    RenderTemplate(details, track),
+		State:      RenderTemplate(state, track),
+		LargeImage: b.largeImage(artworkURL),
+		LargeText:  RenderTemplate(largeText, track),
+		Timestamps: buildTimestamps(track, presence.Timestamps, b.clock.Now()),
+	}
+	if presence.Timestamps == TimestampsText {
+		gauge := renderGauge(presence.Gauge, track.PlayerPosition, track.Duration)
+		activity.State = strings.TrimPrefix(activity.State+templateSeparator+gauge, templateSeparator)
+	}
+	if presence.PartySize && track.TrackNumber > 0 && track.TrackCount >= track.TrackNumber {
+		activity.Party = &discord.Party{Size: track.TrackNumber, Max: track.TrackCount}
+	}
+	activity.Buttons = renderButtons(presence.Buttons, track)
+	if songLink := b.config.SongLink; songLink.Enabled && len(activity.Buttons) < maxPresenceButtons {
+		activity.Buttons = append(activity.Buttons,
+			renderButtons([]ButtonConfig{{Label: songLink.Label, URL: "{song_link}"}}, track)...)
+	}
+	return activity
+}
+
+// smallImage picks the badge image and hover text for the configured mode
+func (b *Bridge) smallImage(track *Track, state PlayerState) (string, string) {
+	cfg := b.config.Presence.SmallImage
+	switch cfg.Mode {
+	case SmallImageLogo:
+		return cfg.LogoFor(track.Source), track.Source
+	case SmallImageState:
+		if state == StatePaused {
+			return cfg.Paused, b.text(msgPaused)
+		}
+		return cfg.Playing, b.text(msgPlaying)
+	default:
+		return "", ""
+	}
+}
+
+// publishBlocked clears or masks the presence for a track hidden by privacy rules
+func (b *Bridge) publishBlocked(track *Track) {
+	if b.config.Privacy.Action != PrivacyMask {
+		b.presence.Submit(nil)
+		log.Println("🙈 Presence hidden by privacy rule")
+		return
 	}
 
-	if err := b.client.SetActivity(activity); err != nil {
+	activity := discord.Activity{
+		Type:    discord.ActivityTypeListening,
+		Details: b.config.Privacy.MaskText,
+	}
+	if _, err := b.presence.Submit(&activity); err != nil {
 		log.Printf("⚠️  Failed to update Discord presence: %v", err)
 		return
 	}
+	log.Println("🙈 Presence masked by privacy rule")
+}
+
+// publishStopped shows a player with nothing loaded as presence.stopped says. Only Discord
+// has a browsing line; the other sinks show nothing either way.
+func (b *Bridge) publishStopped(source Source) {
+	if b.config.Presence.Stopped != StoppedBrowsing || source == nil {
+		b.ClearPresence()
+		return
+	}
 
-	log.Printf("🎵 Now playing: %s - %s (%s)", track.Name, track.Artist, track.Album)
-	if artworkURL != "" {
-		log.Printf("🖼️  Artwork URL: %s", artworkURL)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, sink := range b.otherSinks() {
+		sink.Publish(nil, StateStopped)
 	}
+	if !b.connected || b.paused {
+		return
+	}
+	b.artworkFor = nil
+
+	activity := discord.Activity{
+		Type:       discord.ActivityTypeListening,
+		Details:    b.text(msgBrowsing),
+		LargeImage: b.config.Presence.SmallImage.LogoFor(source.Name()),
+		LargeText:  source.Name(),
+	}
+	if _, err := b.presence.Submit(&activity); err != nil {
+		log.Printf("⚠️  Failed to update Discord presence: %v", err)
+		return
+	}
+	log.Printf("📚 Showing %s as browsing", source.Name())
+}
+
+// buildTimestamps computes the Discord timestamps for the configured mode
+// Sent once per track change; Discord handles the animation from here
+func buildTimestamps(track *Track, mode string, now time.Time) *discord.Timestamps {
+	startTime := now.Add(-time.Duration(track.PlayerPosition * float64(time.Second)))
+	endTime := now.Add(time.Duration((track.Duration - track.PlayerPosition) * float64(time.Second)))
+
+	switch mode {
+	case TimestampsStart:
+		// Elapsed time counting up
+		return &discord.Timestamps{Start: &startTime}
+	case TimestampsBoth:
+		// Full progress bar with total duration
+		return &discord.Timestamps{Start: &startTime, End: &endTime}
+	case TimestampsText:
+		// The position is written into State instead
+		return nil
+	default:
+		// Countdown to the end of the track
+		return &discord.Timestamps{End: &endTime}
+	}
+}
+
+// observePlay feeds the poll result to play tracking, scrobbling and history
+func (b *Bridge) observePlay(track *Track, state PlayerState) PlayUpdate {
+	update := b.plays.Observe(track, state)
+	b.scrobbler.Observe(update, state)
+	// Shows, movies and audiobooks aren't listens
+	if ended := update.Ended; ended != nil && ended.Qualifies() && !ended.Track.Video && !ended.Track.Audiobook {
+		b.history.Record(ended)
+	}
+	return update
 }
 
 // ShouldUpdate determines if a presence update is needed
 func (b *Bridge) ShouldUpdate(track *Track, state PlayerState) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
 	// Always update if state changed
 	if state != b.lastState {
 		return true
@@ -444,99 +1311,497 @@ func (b *Bridge) ShouldUpdate(track *Track, state PlayerState) bool {
 	return false
 }
 
+// settleDelay debounces skip bursts: a track changed to mid-playback is only published
+// once it has played for SkipSettle, so songs skipped past never trigger artwork lookups
+// or Discord updates. Returns how long to wait before re-checking (0 = publish now).
+func (b *Bridge) settleDelay(track *Track, state PlayerState) time.Duration {
+	// Starting, resuming and repeat-one publish immediately; only track-to-track skips wait
+	if b.lastState != StatePlaying || b.lastTrack == nil || track.Equals(*b.lastTrack) {
+		b.settling = nil
+		return 0
+	}
+
+	played := time.Duration(track.PlayerPosition * float64(time.Second))
+	if b.settling == nil || !track.Equals(*b.settling) {
+		b.settling = track
+		b.settledFrom = b.clock.Now().Add(-played)
+	}
+
+	wait := SkipSettle - b.clock.Now().Sub(b.settledFrom)
+	if wait <= 0 || track.IsStream() {
+		b.settling = nil
+		return 0
+	}
+	return wait
+}
+
 // ============================================================================
 // Main Application Loop
 // ============================================================================
 
 func main() {
 	log.SetFlags(log.Ltime)
+
+	if err := dispatch(os.Args[1:]); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+}
+
+// cmdRun runs the bridge daemon until SIGINT/SIGTERM (or Quit in menu bar mode)
+func cmdRun(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	configPath := fs.String("config", DefaultConfigPath(), "path to the JSON config file")
+	menubar := fs.Bool("menubar", false, "show a menu bar icon with the current track and presence controls")
+	replace := fs.Bool("replace", false, "stop an already-running instance and take over")
+	appID := fs.String("app-id", "", "Discord application ID (overrides config and profile)")
+	profile := fs.String("profile", "", "named Discord profile from discord.profiles")
+	dryRun := fs.Bool("dry-run", false, "log presence payloads instead of sending them to Discord")
+	socket := fs.String("discord-socket", "", "path of the Discord IPC socket (default: search the usual places)")
+	fakePlayer := fs.String("fake-player", "", `play "Artist|Album|Track|seconds" on a loop instead of reading real players`)
+	fakeScript := fs.String("fake-script", "", "play the timeline in this file instead of reading real players")
+	logFile := fs.String("log-file", "", "write the log to this file instead of stderr")
+	logMaxSize := fs.Int64("log-max-size", 10, "rotate the log file after this many MB (0 = never)")
+	logMaxAge := fs.Duration("log-max-age", 0, "rotate the log file after this long, e.g. 24h (0 = never)")
+	logKeep := fs.Int("log-keep", 5, "rotated log files to keep")
+	plainLog := fs.Bool("plain-log", false, "leave emoji out of log lines")
+	trace := fs.Bool("trace", false, "log every AppleScript call, HTTP request and Discord frame (credentials redacted)")
+	fs.Parse(args)
+
+	logOutput, err := OpenLog(LogOptions{
+		Path:    *logFile,
+		MaxSize: *logMaxSize << 20,
+		MaxAge:  *logMaxAge,
+		Keep:    max(*logKeep, 0),
+		Plain:   *plainLog,
+	})
+	if err != nil {
+		return err
+	}
+	log.SetOutput(logOutput)
+	if *trace {
+		EnableTrace()
+	}
+
+	var fake *fakeSource
+	switch {
+	case *fakeScript != "":
+		fake, err = loadFakeScript(*fakeScript)
+	case *fakePlayer != "":
+		fake, err = newFakeSource(*fakePlayer)
+	}
+	if err != nil {
+		return err
+	}
+	if fake != nil {
+		sourceFactories[fakeSourceName] = func(ScriptRunner) Source { return fake }
+	}
+
 	log.Println("🍎 Apple Music Discord Bridge starting...")
 
-	bridge := NewBridge()
+	// Flags and environment win over the file on every (re)load
+	load := func() (*Config, error) {
+		config, err := LoadConfig(*configPath)
+		if err != nil {
+			return nil, err
+		}
+		config.Discord.ApplyOverrides(*appID, *profile)
+		if *socket != "" {
+			config.Discord.Socket = *socket
+		}
+		if *dryRun {
+			config.Discord.Transport = discord.TransportDryRun
+		}
+		if fake != nil {
+			config.useFakePlayer()
+		}
+		return config, nil
+	}
+
+	config, err := load()
+	if err != nil {
+		return err
+	}
+
+	lock, err := AcquireInstanceLock(*replace)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
 
-	// Connect to Discord (non-fatal, will retry in loop)
-	if err := bridge.Connect(); err != nil {
-		log.Printf("⚠️  Initial Discord connection failed: %v (will retry)", err)
+	// Cancelling ctx abandons running scripts and lookups and ends the poll loop
+	ctx, quit := context.WithCancel(context.Background())
+	defer quit()
+
+	bridge, err := NewBridge(config, WithContext(ctx))
+	if err != nil {
+		return err
 	}
 
 	// Setup graceful shutdown
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM)
 
-	// Main polling ticker
-	ticker := time.NewTicker(PollInterval)
-	defer ticker.Stop()
+	go func() {
+		sig := <-shutdown
+		log.Printf("\n🛑 Received signal: %v", sig)
+		signal.Stop(shutdown) // a second signal kills the process outright
+		quit()
+	}()
+
+	control, err := StartControlServer(bridge, quit, *logFile)
+	if err != nil {
+		log.Printf("⚠️  %v (status commands unavailable)", err)
+	}
+	defer control.Close()
+
+	overlay, err := StartOverlayServer(config.Overlay)
+	if err != nil {
+		log.Printf("⚠️  %v (overlay unavailable)", err)
+	}
+	defer overlay.Close()
+	if overlay != nil {
+		bridge.AddSink(overlay)
+	}
+	browser, err := StartBrowserServer(config.Browser)
+	if err != nil {
+		log.Printf("⚠️  %v (web player unavailable)", err)
+	}
+	defer browser.Close()
+	bridge.reloads = WatchConfig(*configPath, load)
+	bridge.wakes = WatchWake()
+	bridge.powerEvents = WatchPower(ctx)
+	if fake == nil { // fake plays mustn't replace the real presence saved for a restart
+		if bridge.resumePath, err = presenceStatePath(); err != nil {
+			log.Printf("⚠️  %v (presence won't be restored after a restart)", err)
+		}
+	}
+	StartReports(config.Report, config.History, bridge.history, ctx.Done())
+
+	// Connect to Discord (non-fatal, will retry in loop; discord.optional logs it there)
+	if err := bridge.Connect(); err != nil && !config.Discord.Optional {
+		log.Printf("⚠️  Initial Discord connection failed: %v (will retry)", err)
+	}
+	bridge.RestorePresence()
+
+	if *menubar {
+		err = runMenubar(ctx, bridge, quit)
+	} else {
+		runLoop(ctx, bridge)
+	}
+
+	log.Println("🧹 Cleaning up...")
+
+	// Clear every sink before exit, but don't let a hung Discord or sink hold up the exit
+	cleaned := make(chan struct{})
+	goSafe("cleanup", func() {
+		defer close(cleaned)
+		bridge.ClearPresence()
+		bridge.Disconnect()
+		bridge.CloseSinks()
+	})
+	select {
+	case <-cleaned:
+		log.Println("👋 Goodbye!")
+	case <-time.After(ShutdownTimeout):
+		log.Printf("⚠️  Cleanup still running after %v, exiting anyway", ShutdownTimeout)
+	}
+	return err
+}
+
+// runLoop polls Apple Music until ctx is cancelled, restarting the daemon if the
+// watchdog finds the loop stalled
+func runLoop(ctx context.Context, bridge *Bridge) {
+	pollLoop(ctx, bridge, newWatchdog(ctx.Done()))
+}
+
+// pollLoop polls until ctx is cancelled, rescheduling after every poll
+func pollLoop(ctx context.Context, bridge *Bridge, dog *Watchdog) {
+	var next time.Time
+	panics := 0
+	poll := func() time.Duration {
+		dog.Begin()
+		var delay time.Duration
+		if guard("poll", func() { delay = pollAndUpdate(ctx, bridge) }) {
+			if panics++; panics >= pollPanicLimit {
+				restartDaemon("Poll keeps panicking")
+			}
+			delay = bridge.config.PollEvery()
+		} else {
+			panics = 0
+		}
+		next = time.Now().Add(delay)
+		dog.End(next)
+		return delay
+	}
 
 	// Initial poll
-	pollAndUpdate(bridge)
+	delay := poll()
 
-	log.Printf("⏱️  Polling every %v for changes...", PollInterval)
+	log.Printf("⏱️  Polling every %v for changes...", bridge.config.PollEvery())
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
 
 	// Main event loop
 	for {
 		select {
-		case <-ticker.C:
-			pollAndUpdate(bridge)
+		case <-timer.C:
+			timer.Reset(poll())
+
+		case config := <-bridge.reloads:
+			dog.Begin()
+			if err := bridge.Reload(config); err != nil {
+				log.Printf("⚠️  Config reload failed: %v", err)
+				dog.End(next)
+				continue
+			}
+			timer.Reset(poll())
+
+		case slept := <-bridge.wakes:
+			if time.Since(bridge.wokeAt) < wakeMinSleep {
+				continue // the wake notification already refreshed
+			}
+			// Timestamps published before sleeping are stale; republish right away
+			log.Printf("☀️  Woke after %v asleep, refreshing", slept.Round(time.Second))
+			dog.Begin()
+			bridge.woke()
+			timer.Reset(poll())
+
+		case event := <-bridge.powerEvents:
+			dog.Begin()
+			bridge.powerChanged(event)
+			timer.Reset(poll())
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// nextPollDelay picks the wait before the next poll: right after the expected end of the
+// current track when it's close, stretched when Music has been idle for a long time
+func (b *Bridge) nextPollDelay(track *Track, state PlayerState) time.Duration {
+	if state != StatePlaying {
+		if b.idleSince.IsZero() {
+			b.idleSince = b.clock.Now()
+		}
+		if b.clock.Now().Sub(b.idleSince) >= IdleAfter {
+			return b.power.Stretch(b.config.IdlePollEvery())
+		}
+		return b.power.Stretch(b.config.PollEvery())
+	}
+	b.idleSince = time.Time{}
+
+	if track != nil && track.Duration > 0 {
+		remaining := time.Duration((track.Duration - track.PlayerPosition) * float64(time.Second))
+		// The one-shot poll at the track end is never stretched
+		interval := b.power.Stretch(b.config.PollEvery())
+		if remaining < interval {
+			return max(remaining, 0) + TrackEndGrace
+		}
+		return interval
+	}
+	return b.power.Stretch(b.config.PollEvery())
+}
 
-		case sig := <-shutdown:
-			log.Printf("\n🛑 Received signal: %v", sig)
-			log.Println("🧹 Cleaning up...")
+// startPauseGrace shows the paused track for the configured grace period, or clears it
+// right away without one. Quitting the player still clears immediately.
+func (b *Bridge) startPauseGrace(source Source) {
+	track := b.lastTrack
+	if b.config.Presence.PauseGrace() <= 0 || track == nil {
+		b.ClearPresence()
+		b.remember(track, StatePaused)
+		return
+	}
 
-			// Clear Discord presence before exit
-			bridge.ClearPresence()
-			bridge.Disconnect()
+	// Re-read the track so the paused position is current
+	if current, err := source.CurrentTrack(b.ctx); err == nil {
+		track = current
+	}
+	b.pausedAt = b.clock.Now()
+	b.UpdatePresence(track, StatePaused)
+	b.remember(track, StatePaused)
+}
 
-			log.Println("👋 Goodbye!")
-			os.Exit(0)
-		}
+// pollPaused clears a paused presence once its grace period is over
+func (b *Bridge) pollPaused() time.Duration {
+	delay := b.nextPollDelay(nil, StatePaused)
+	if b.pausedAt.IsZero() {
+		return delay
 	}
+
+	remaining := b.config.Presence.PauseGrace() - b.clock.Now().Sub(b.pausedAt)
+	if remaining <= 0 {
+		log.Println("⌛ Paused too long, clearing presence")
+		b.ClearPresence()
+		b.pausedAt = time.Time{}
+		return delay
+	}
+	return min(delay, remaining)
 }
 
-// pollAndUpdate checks Apple Music state and updates Discord accordingly
-func pollAndUpdate(bridge *Bridge) {
+// pollAndUpdate checks Apple Music state, moves the bridge to the matching state,
+// and returns how long to wait before polling again
+func pollAndUpdate(ctx context.Context, bridge *Bridge) time.Duration {
+	if bridge.asleep() {
+		bridge.setHiddenBy("sleep")
+		return bridge.config.IdlePollEvery() // cleared before sleeping; the wake polls again
+	}
+
 	// Try to connect if we aren't already
-	if !bridge.connected {
-		if err := bridge.Connect(); err != nil {
-			// Don't log spam every 10s, maybe just debug or silence
-			// We'll keep it silent to avoid log flooding unless we want to debug
-			return 
+	if !bridge.Connected() {
+		err := bridge.Connect()
+		switch {
+		case err == nil:
+			bridge.discordFound()
+		case bridge.config.Discord.Optional:
+			bridge.discordAbsent(err)
+		default:
+			// Silent: Discord not running is normal, and retried every poll
+			bridge.enter(BridgeDisconnected, nil, err)
+			bridge.notifier.Failing(failDiscord, err)
+			return bridge.config.PollEvery()
 		}
 	}
+	if bridge.Connected() {
+		bridge.notifier.Recovered(failDiscord)
+	}
 
-	state, err := GetPlayerState()
-	if err != nil {
+	hiddenBy := ""
+	switch {
+	case bridge.pollLocked(ctx):
+		hiddenBy = "screen locked"
+	case bridge.pollScheduled():
+		hiddenBy = "schedule"
+	case bridge.pollFocus():
+		hiddenBy = "Focus"
+	}
+	bridge.setHiddenBy(hiddenBy)
+	if hiddenBy != "" {
+		bridge.observeLocked(ctx)
+		return bridge.power.Stretch(bridge.config.IdlePollEvery())
+	}
+
+	source, state, err := bridge.sources.Select(ctx)
+	if ctx.Err() != nil {
+		return 0 // shutting down; the loop exits without publishing
+	}
+	if err != nil && source == nil {
 		// Also silence this slightly to avoid log flooding in background
-		// log.Printf("⚠️  Error checking player state: %v", err) 
-		return
+		// log.Printf("⚠️  Error checking player state: %v", err)
+		bridge.notifier.Failing(failPlayer, err)
+		return bridge.automation.Failed(err, bridge.config.PollEvery())
+	}
+	if state != StatePlaying {
+		bridge.automation.Recovered()
+		bridge.notifier.Recovered(failPlayer)
+	}
+
+	if state != StatePlaying {
+		queueEnded := bridge.queueEnded(state)
+		bridge.trackEnds = time.Time{}
+		if queueEnded {
+			bridge.observePlay(nil, StateNotRunning) // the last play finished
+			bridge.enter(BridgeStopped, source, nil)
+			return bridge.nextPollDelay(nil, state)
+		}
 	}
 
 	switch state {
 	case StateNotRunning:
-		if bridge.lastState != StateNotRunning {
-			log.Println("💤 Music app not running")
-			bridge.ClearPresence()
-			bridge.lastState = StateNotRunning
-			bridge.lastTrack = nil
-		}
+		bridge.observePlay(nil, state)
+		bridge.enter(BridgeNotRunning, nil, nil)
+
+	case StateStopped:
+		bridge.observePlay(nil, state)
+		bridge.enter(BridgeStopped, source, nil)
 
 	case StatePaused:
-		if bridge.lastState != StatePaused {
-			log.Println("⏸️  Playback paused")
-			bridge.ClearPresence()
-			bridge.lastState = StatePaused
-		}
+		bridge.observePlay(nil, state) // the session carries on; Paused doesn't read the track
+		bridge.enter(BridgePaused, source, nil)
+		return bridge.pollPaused()
 
 	case StatePlaying:
-		track, err := GetCurrentTrack()
+		track, err := source.CurrentTrack(ctx)
+		if ctx.Err() != nil {
+			return 0
+		}
 		if err != nil {
-			log.Printf("⚠️  Error getting track info: %v", err)
-			return
+			bridge.enter(BridgeStalled, source, err)
+			bridge.notifier.Failing(failPlayer, err)
+			return bridge.automation.Failed(err, bridge.config.PollEvery())
+		}
+		bridge.automation.Recovered()
+		bridge.notifier.Recovered(failPlayer)
+		if !bridge.config.Presence.showsOutputs() {
+			track.Outputs = nil // device names only leave the machine when asked for
 		}
+		bridge.enter(BridgePlaying, source, nil)
+		return bridge.pollPlaying(source, track)
+	}
+
+	return bridge.nextPollDelay(nil, state)
+}
+
+// discordAbsent keeps a discord.optional bridge polling for its other sinks while Discord
+// can't be reached, logging the absence at most every DiscordAbsentLogEvery
+func (b *Bridge) discordAbsent(err error) {
+	now := b.clock.Now()
+	if !b.discordSeen.IsZero() && now.Sub(b.discordSeen) < DiscordAbsentLogEvery {
+		return
+	}
+	log.Printf("🔕 Discord not found, publishing to the other sinks only: %v", err)
+	b.discordSeen = now
+}
 
-		if bridge.ShouldUpdate(track, state) {
-			bridge.UpdatePresence(track, state)
-			bridge.lastTrack = track
-			bridge.lastState = state
+// discordFound resends the current track once Discord turns up after being absent
+func (b *Bridge) discordFound() {
+	if b.discordSeen.IsZero() {
+		return
+	}
+	b.discordSeen = time.Time{}
+	b.Refresh()
+}
+
+// queueEnded reports whether playback stopping now means the queue ran out: the track
+// that was playing was due to end, or the bridge already found the queue finished and
+// the player is still running
+func (b *Bridge) queueEnded(state PlayerState) bool {
+	if b.machine.state == BridgeStopped {
+		return state != StateNotRunning
+	}
+	return !b.trackEnds.IsZero() && !b.clock.Now().Before(b.trackEnds.Add(-QueueEndSlack))
+}
+
+// pollPlaying publishes a changed track, debouncing skips
+func (b *Bridge) pollPlaying(source Source, track *Track) time.Duration {
+	update := b.observePlay(track, StatePlaying)
+	b.trackEnds = time.Time{}
+	if track.Duration > 0 && !track.IsStream() {
+		b.trackEnds = b.clock.Now().Add(time.Duration((track.Duration - track.PlayerPosition) * float64(time.Second)))
+	}
+
+	// Repeat-one keeps identical metadata, so only the new session reveals the restart
+	replayed := update.Started && update.Ended != nil && track.Equals(*update.Ended.Track)
+	if replayed {
+		log.Printf("🔁 Track restarted: %s - %s", track.Name, track.Artist)
+	}
+
+	if replayed || b.ShouldUpdate(track, StatePlaying) {
+		if wait := b.settleDelay(track, StatePlaying); wait > 0 {
+			return wait
+		}
+		queue, queued := source.(queueSource)
+		if queued && b.config.Presence.UpNext != UpNextHidden {
+			track.Next = upNext(b.ctx, queue)
+		}
+		b.UpdatePresence(track, StatePlaying)
+		b.remember(track, StatePlaying)
+		if queued {
+			b.prefetchNext(queue, track.Next)
 		}
+	} else if !b.refreshSettings(track) {
+		b.refreshGauge(track)
 	}
+	return b.nextPollDelay(track, StatePlaying)
 }
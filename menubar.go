@@ -0,0 +1,93 @@
+//go:build menubar
+
+// Menu bar companion mode
+// Shows a status-bar item with the current track and presence controls.
+// Built only with -tags menubar because the systray library requires CGO on macOS.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"fyne.io/systray"
+)
+
+// menubarRefresh - How often the menu bar title is refreshed from the bridge
+const menubarRefresh = 2 * time.Second
+
+// runMenubar runs the polling loop behind a menu bar item until ctx is cancelled.
+// Blocks on the main thread as required by AppKit.
+func runMenubar(ctx context.Context, bridge *Bridge, quit func()) error {
+	onReady := func() {
+		systray.SetTitle("♪")
+		systray.SetTooltip("Apple Music Discord Bridge")
+
+		nowPlaying := systray.AddMenuItem("Nothing playing", "")
+		nowPlaying.Disable()
+		systray.AddSeparator()
+		toggle := systray.AddMenuItemCheckbox("Pause Presence", "Stop publishing to Discord", false)
+		quitItem := systray.AddMenuItem("Quit", "Clear presence and exit")
+
+		go runLoop(ctx, bridge)
+
+		go func() {
+			ticker := time.NewTicker(menubarRefresh)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					// Pauses also come from the control socket
+					syncPauseToggle(toggle, bridge)
+					nowPlaying.SetTitle(menubarStatus(bridge))
+
+				case <-toggle.ClickedCh:
+					bridge.SetPresencePaused(!bridge.PresencePaused())
+					syncPauseToggle(toggle, bridge)
+					nowPlaying.SetTitle(menubarStatus(bridge))
+
+				case <-quitItem.ClickedCh:
+					quit()
+
+				case <-ctx.Done():
+					systray.Quit()
+					return
+				}
+			}
+		}()
+	}
+
+	systray.Run(onReady, func() {})
+	return nil
+}
+
+// syncPauseToggle shows the bridge's pause state in the checkbox
+func syncPauseToggle(toggle *systray.MenuItem, bridge *Bridge) {
+	switch paused := bridge.PresencePaused(); {
+	case paused && !toggle.Checked():
+		toggle.Check()
+	case !paused && toggle.Checked():
+		toggle.Uncheck()
+	}
+}
+
+// menubarStatus renders the first menu line from the bridge state
+func menubarStatus(bridge *Bridge) string {
+	track, state := bridge.Snapshot()
+	if track == nil || state == StateNotRunning || state == StateStopped {
+		return "Nothing playing"
+	}
+
+	status := fmt.Sprintf("%s — %s", track.Name, track.Artist)
+	if state == StatePaused {
+		status = "⏸ " + status
+	}
+	if bridge.PresencePaused() {
+		status += " (hidden)"
+	} else if reason := bridge.HiddenBy(); reason != "" {
+		status += fmt.Sprintf(" (hidden: %s)", reason)
+	}
+	return status
+}
@@ -0,0 +1,13 @@
+//go:build !menubar
+
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// runMenubar reports that menu bar support was not compiled in
+func runMenubar(ctx context.Context, bridge *Bridge, quit func()) error {
+	return fmt.Errorf("menu bar mode not available: rebuild with -tags menubar")
+}
@@ -0,0 +1,494 @@
+// MQTT publishing
+// Sends track and state changes to an MQTT broker as retained JSON messages, so home
+// automation (Home Assistant and friends) can react to listening sessions, e.g. dim the
+// lights when an album starts. "<topic>/availability" reads "online" while the bridge is
+// connected and falls back to "offline" through the broker's last will.
+// The client speaks just enough MQTT 3.1.1 for this: CONNECT, QoS 0 PUBLISH and PINGREQ.
+
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// mqttKeepAlive - Keep-alive announced to the broker; a ping goes out when idle this long
+	mqttKeepAlive = 60 * time.Second
+
+	// mqttTimeout - Upper bound for connecting and for each write
+	mqttTimeout = 10 * time.Second
+
+	// mqttMaxBackoff - Longest wait between reconnection attempts
+	mqttMaxBackoff = 5 * time.Minute
+
+	// mqttQueueLimit - Events kept while the broker is unreachable; older ones are dropped
+	mqttQueueLimit = 32
+)
+
+// MQTT event names
+const (
+	mqttTrackChange = "track_change"
+	mqttStateChange = "state_change"
+)
+
+// MQTTConfig publishes playback events to an MQTT broker
+type MQTTConfig struct {
+	Broker   string `json:"broker"`    // e.g. "tcp://homeassistant.local:1883" or "tls://…:8883"; empty disables MQTT
+	Topic    string `json:"topic"`     // Events are published here, retained
+	ClientID string `json:"client_id"` // Must be unique on the broker
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// parseMQTTBroker splits a broker URL into a dial address and whether it uses TLS
+func parseMQTTBroker(broker string) (addr string, useTLS bool, err error) {
+	u, err := url.Parse(broker)
+	if err != nil || u.Host == "" {
+		return "", false, fmt.Errorf("mqtt.broker must look like \"tcp://host:1883\", got %q", broker)
+	}
+	port := "1883"
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "tls", "ssl", "mqtts":
+		useTLS, port = true, "8883"
+	default:
+		return "", false, fmt.Errorf("mqtt.broker scheme must be tcp or tls, got %q", u.Scheme)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	return net.JoinHostPort(u.Hostname(), port), useTLS, nil
+}
+
+// validate checks the broker URL, topic and client ID
+func (c MQTTConfig) validate() error {
+	if c.Broker == "" {
+		return nil
+	}
+	if _, _, err := parseMQTTBroker(c.Broker); err != nil {
+		return err
+	}
+	if c.Topic == "" || strings.ContainsAny(c.Topic, "+#") {
+		return fmt.Errorf("mqtt.topic must be a topic name without wildcards, got %q", c.Topic)
+	}
+	if c.ClientID == "" {
+		return errors.New("mqtt.client_id must not be empty")
+	}
+	return nil
+}
+
+// mqttPayload is the JSON body of each event
+type mqttPayload struct {
+	Event       string  `json:"event"` // track_change or state_change
+	State       string  `json:"state"`
+	Track       string  `json:"track"`
+	Artist      string  `json:"artist"`
+	Album       string  `json:"album"`
+	AlbumArtist string  `json:"album_artist,omitempty"`
+	TrackNumber int     `json:"track_number,omitempty"`
+	TrackCount  int     `json:"track_count,omitempty"`
+	DiscNumber  int     `json:"disc_number,omitempty"`
+	DiscCount   int     `json:"disc_count,omitempty"`
+	Origin      string  `json:"origin,omitempty"` // stream, local or catalog
+	ArtworkURL  string  `json:"artwork_url"`
+	Position    float64 `json:"position"` // seconds into the track when the event was sent
+	Duration    float64 `json:"duration"`
+	Timestamp   int64   `json:"timestamp"`
+}
+
+// sameTrack reports whether two payloads describe the same track
+func (p mqttPayload) sameTrack(other mqttPayload) bool {
+	return p.Track == other.Track && p.Artist == other.Artist && p.Album == other.Album &&
+		p.ArtworkURL == other.ArtworkURL
+}
+
+// MQTTPublisher sends events from a background worker that reconnects as needed
+type MQTTPublisher struct {
+	cfg    MQTTConfig
+	addr   string
+	useTLS bool
+
+	mu    sync.Mutex
+	last  mqttPayload   // newest event queued
+	queue []mqttPayload // events not yet sent
+	wake  chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// NewMQTTPublisher returns nil when no broker is configured
+func NewMQTTPublisher(cfg MQTTConfig) *MQTTPublisher {
+	if cfg.Broker == "" {
+		return nil
+	}
+	m := &MQTTPublisher{
+		cfg:  cfg,
+		wake: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	m.addr, m.useTLS, _ = parseMQTTBroker(cfg.Broker) // checked by Validate
+	m.last.State = StateNotRunning.String()
+	go m.run()
+	return m
+}
+
+// Publish queues an event when the track or the state changed
+func (m *MQTTPublisher) Publish(track *Track, state PlayerState) {
+	if m == nil {
+		return
+	}
+	payload := mqttPayload{State: state.String(), Timestamp: time.Now().Unix()}
+	if track != nil {
+		payload.Track = track.Name
+		payload.Artist = track.Artist
+		payload.Album = track.Album
+		payload.AlbumArtist = track.AlbumArtist
+		payload.TrackNumber, payload.TrackCount = track.TrackNumber, track.TrackCount
+		payload.DiscNumber, payload.DiscCount = track.DiscNumber, track.DiscCount
+		payload.Origin = track.Origin()
+		payload.ArtworkURL = track.ArtworkURL
+		payload.Position = track.PlayerPosition
+		payload.Duration = track.Duration
+	}
+
+	m.mu.Lock()
+	switch {
+	case track != nil && !payload.sameTrack(m.last):
+		payload.Event = mqttTrackChange
+	case payload.State != m.last.State || !payload.sameTrack(m.last):
+		payload.Event = mqttStateChange // includes stopping, which drops the track
+	default:
+		m.mu.Unlock()
+		return
+	}
+	m.last = payload
+	m.queue = append(m.queue, payload)
+	if len(m.queue) > mqttQueueLimit {
+		m.queue = m.queue[len(m.queue)-mqttQueueLimit:]
+	}
+	m.mu.Unlock()
+
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Clear reports that nothing is playing
+func (m *MQTTPublisher) Clear() {
+	m.Publish(nil, StateNotRunning)
+}
+
+// Close sends any queued events, marks the bridge offline and disconnects
+func (m *MQTTPublisher) Close() {
+	if m == nil {
+		return
+	}
+	close(m.stop)
+	<-m.done
+}
+
+// run keeps a connection to the broker and drains the queue into it
+func (m *MQTTPublisher) run() {
+	defer close(m.done)
+
+	var conn *mqttConn
+	backoff := time.Duration(0)
+	failing := false
+	for {
+		var ping <-chan time.Time
+		var dead <-chan struct{}
+		var retry <-chan time.Time
+		if conn != nil {
+			ping = time.After(mqttKeepAlive)
+			dead = conn.dead
+		} else if backoff > 0 {
+			retry = time.After(backoff)
+		}
+
+		select {
+		case <-m.wake:
+		case <-retry:
+		case <-ping:
+			if err := conn.ping(); err != nil {
+				conn.close()
+				conn = nil
+			}
+			continue
+		case <-dead:
+			conn.close()
+			conn = nil
+			continue
+		case <-m.stop:
+			if conn != nil {
+				m.flush(conn) // best effort; nothing is retried on shutdown
+				conn.disconnect(m.cfg.Topic)
+			}
+			return
+		}
+
+		if m.pending() == 0 {
+			continue
+		}
+		if conn == nil {
+			var err error
+			if conn, err = dialMQTT(m.cfg, m.addr, m.useTLS); err != nil {
+				if !failing {
+					log.Printf("⚠️  MQTT broker unreachable, will keep retrying: %v", err)
+					failing = true
+				}
+				backoff = min(max(backoff*2, 5*time.Second), mqttMaxBackoff)
+				continue
+			}
+			if failing {
+				log.Printf("✅ MQTT broker %s reachable again", m.addr)
+			} else {
+				log.Printf("📡 Publishing to MQTT broker %s", m.addr)
+			}
+			failing, backoff = false, 0
+		}
+		if err := m.flush(conn); err != nil {
+			log.Printf("⚠️  MQTT publish failed: %v", err)
+			conn.close()
+			conn = nil
+			backoff = 5 * time.Second
+		}
+	}
+}
+
+// pending returns how many events wait to be sent
+func (m *MQTTPublisher) pending() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.queue)
+}
+
+// flush sends queued events in order, keeping the unsent ones on failure
+func (m *MQTTPublisher) flush(conn *mqttConn) error {
+	for {
+		m.mu.Lock()
+		if len(m.queue) == 0 {
+			m.mu.Unlock()
+			return nil
+		}
+		payload := m.queue[0]
+		m.mu.Unlock()
+
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		if err := conn.publish(m.cfg.Topic, data, true); err != nil {
+			return err
+		}
+
+		m.mu.Lock()
+		if len(m.queue) > 0 && m.queue[0] == payload {
+			m.queue = m.queue[1:]
+		}
+		m.mu.Unlock()
+	}
+}
+
+// mqttConn is one session with the broker
+type mqttConn struct {
+	conn net.Conn
+	dead chan struct{} // closed when the broker hangs up
+}
+
+// dialMQTT connects and waits for the broker to accept the session
+func dialMQTT(cfg MQTTConfig, addr string, useTLS bool) (*mqttConn, error) {
+	dialer := &net.Dialer{Timeout: mqttTimeout}
+	var conn net.Conn
+	var err error
+	if useTLS {
+		host, _, _ := net.SplitHostPort(addr)
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: host})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Now().Add(mqttTimeout))
+	if _, err := conn.Write(connectPacket(cfg)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	reader := bufio.NewReader(conn)
+	kind, body, err := readMQTTPacket(reader)
+	if err == nil && (kind != 0x20 || len(body) != 2) {
+		err = fmt.Errorf("unexpected packet 0x%02x instead of CONNACK", kind)
+	}
+	if err == nil && body[1] != 0 {
+		err = connackError(body[1])
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	c := &mqttConn{conn: conn, dead: make(chan struct{})}
+	if err := c.publish(availabilityTopic(cfg.Topic), []byte("online"), true); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	go c.discard(reader)
+	return c, nil
+}
+
+// discard reads (and ignores) PINGRESP and anything else until the connection ends
+func (c *mqttConn) discard(reader *bufio.Reader) {
+	defer close(c.dead)
+	for {
+		if _, _, err := readMQTTPacket(reader); err != nil {
+			return
+		}
+	}
+}
+
+// publish sends a QoS 0 message
+func (c *mqttConn) publish(topic string, payload []byte, retain bool) error {
+	var header byte = 0x30
+	if retain {
+		header |= 0x01
+	}
+	body := appendMQTTString(nil, topic)
+	body = append(body, payload...)
+	return c.write(mqttPacket(header, body))
+}
+
+// ping keeps an idle session alive
+func (c *mqttConn) ping() error {
+	return c.write([]byte{0xC0, 0x00})
+}
+
+// disconnect marks the bridge offline and ends the session cleanly
+func (c *mqttConn) disconnect(topic string) {
+	c.publish(availabilityTopic(topic), []byte("offline"), true)
+	c.write([]byte{0xE0, 0x00})
+	c.close()
+}
+
+// write sends a packet within mqttTimeout
+func (c *mqttConn) write(packet []byte) error {
+	c.conn.SetWriteDeadline(time.Now().Add(mqttTimeout))
+	_, err := c.conn.Write(packet)
+	return err
+}
+
+// close drops the connection
+func (c *mqttConn) close() {
+	c.conn.Close()
+}
+
+// availabilityTopic is where the online/offline status is retained
+func availabilityTopic(topic string) string {
+	return topic + "/availability"
+}
+
+// connectPacket builds CONNECT with a clean session and an "offline" last will
+func connectPacket(cfg MQTTConfig) []byte {
+	flags := byte(0x02 | 0x04 | 0x20) // clean session, will, retained will
+	if cfg.Username != "" {
+		flags |= 0x80
+		if cfg.Password != "" {
+			flags |= 0x40
+		}
+	}
+
+	body := appendMQTTString(nil, "MQTT")
+	body = append(body, 4, flags) // protocol level 4 is MQTT 3.1.1
+	body = binary.BigEndian.AppendUint16(body, uint16(mqttKeepAlive/time.Second))
+	body = appendMQTTString(body, cfg.ClientID)
+	body = appendMQTTString(body, availabilityTopic(cfg.Topic))
+	body = appendMQTTString(body, "offline")
+	if cfg.Username != "" {
+		body = appendMQTTString(body, cfg.Username)
+		if cfg.Password != "" {
+			body = appendMQTTString(body, cfg.Password)
+		}
+	}
+	return mqttPacket(0x10, body)
+}
+
+// connackError explains a refused CONNECT
+func connackError(code byte) error {
+	reasons := map[byte]string{
+		1: "unsupported protocol version",
+		2: "client ID rejected",
+		3: "server unavailable",
+		4: "bad username or password",
+		5: "not authorized",
+	}
+	if reason, ok := reasons[code]; ok {
+		return fmt.Errorf("broker refused connection: %s", reason)
+	}
+	return fmt.Errorf("broker refused connection (code %d)", code)
+}
+
+// mqttPacket prefixes body with the fixed header and its variable-length size
+func mqttPacket(header byte, body []byte) []byte {
+	packet := []byte{header}
+	for n := len(body); ; {
+		digit := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			digit |= 0x80
+		}
+		packet = append(packet, digit)
+		if n == 0 {
+			break
+		}
+	}
+	return append(packet, body...)
+}
+
+// appendMQTTString appends a length-prefixed UTF-8 string
+func appendMQTTString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// readMQTTPacket reads one packet, returning its type (the fixed header's high nibble) and body
+func readMQTTPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	size, multiplier := 0, 1
+	for i := 0; ; i++ {
+		digit, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		size += int(digit&0x7F) * multiplier
+		if digit&0x80 == 0 {
+			break
+		}
+		if i == 3 {
+			return 0, nil, errors.New("malformed MQTT packet length")
+		}
+		multiplier *= 128
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header & 0xF0, body, nil
+}
@@ -0,0 +1,89 @@
+// Network settings
+// Proxy and TLS trust for outgoing API calls (iTunes, artwork providers, scrobbling, hooks).
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY are honored by default; a configured proxy overrides
+// them, and a CA bundle adds trust for corporate TLS-intercepting proxies. max_qps caps the
+// rate of artwork and metadata lookups, see httpcache.go.
+
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync/atomic"
+)
+
+// NetworkConfig configures the shared HTTP client
+type NetworkConfig struct {
+	Proxy    string  `json:"proxy"`     // e.g. "http://proxy.corp:8080"; empty uses the environment
+	CABundle string  `json:"ca_bundle"` // PEM file trusted in addition to the system roots
+	MaxQPS   float64 `json:"max_qps"`   // Lookups per second across all providers; 0 is unlimited
+}
+
+// apiTransport lets ConfigureNetwork swap the transport under in-flight requests
+type apiTransport struct {
+	current atomic.Pointer[http.Transport]
+}
+
+// RoundTrip sends the request through the current transport, tracing it under --trace
+func (t *apiTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return tracingTransport{t.current.Load()}.RoundTrip(req)
+}
+
+// sharedTransport backs httpClient
+var sharedTransport = func() *apiTransport {
+	t := &apiTransport{}
+	t.current.Store(http.DefaultTransport.(*http.Transport).Clone())
+	return t
+}()
+
+// newTransport builds a transport for the config
+func newTransport(cfg NetworkConfig) (*http.Transport, error) {
+	if cfg.MaxQPS < 0 {
+		return nil, fmt.Errorf("network.max_qps must not be negative, got %v", cfg.MaxQPS)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network.proxy: %w", err)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("network.proxy must be an http://, https:// or socks5:// URL, got %q", cfg.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if cfg.CABundle != "" {
+		pem, err := os.ReadFile(cfg.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read network.ca_bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("network.ca_bundle %s contains no PEM certificates", cfg.CABundle)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	return transport, nil
+}
+
+// ConfigureNetwork applies proxy and CA settings to subsequent API requests
+func ConfigureNetwork(cfg NetworkConfig) error {
+	transport, err := newTransport(cfg)
+	if err != nil {
+		return err
+	}
+	sharedTransport.current.Store(transport)
+	lookupTransport.SetQPS(cfg.MaxQPS)
+	return nil
+}
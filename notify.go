@@ -0,0 +1,96 @@
+// Failure notifications
+// A daemon started by launchd has nobody watching its log. When Discord or the player
+// stays unreachable for notify.after_minutes, a macOS notification says so once, and
+// another says when it works again.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Things the notifier watches
+const (
+	failDiscord = "Discord"
+	failPlayer  = "the player"
+)
+
+// NotifyConfig enables notifications for persistent failures
+type NotifyConfig struct {
+	Enabled      bool    `json:"enabled"`
+	AfterMinutes float64 `json:"after_minutes"` // How long a failure lasts before it's reported
+}
+
+// After returns how long a failure may last before it's reported
+func (n NotifyConfig) After() time.Duration {
+	return time.Duration(n.AfterMinutes * float64(time.Minute))
+}
+
+// failure is one ongoing outage
+type failure struct {
+	since    time.Time
+	notified bool
+}
+
+// FailureNotifier turns long outages into notifications (poll goroutine only)
+type FailureNotifier struct {
+	config   NotifyConfig
+	scripts  ScriptRunner
+	clock    Clock
+	failures map[string]*failure
+}
+
+// NewFailureNotifier returns nil when notifications are disabled
+func NewFailureNotifier(cfg NotifyConfig, scripts ScriptRunner, clock Clock) *FailureNotifier {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &FailureNotifier{config: cfg, scripts: scripts, clock: clock, failures: make(map[string]*failure)}
+}
+
+// Failing records that what is unreachable, notifying once it has been for long enough
+func (n *FailureNotifier) Failing(what string, err error) {
+	if n == nil {
+		return
+	}
+
+	f, ok := n.failures[what]
+	if !ok {
+		f = &failure{since: n.clock.Now()}
+		n.failures[what] = f
+	}
+	down := n.clock.Now().Sub(f.since)
+	if f.notified || down < n.config.After() {
+		return
+	}
+
+	f.notified = true
+	n.post(fmt.Sprintf("Can't reach %s", what),
+		fmt.Sprintf("Not working for %v: %v", down.Round(time.Minute), err))
+}
+
+// Recovered ends an outage, saying so if it was reported
+func (n *FailureNotifier) Recovered(what string) {
+	if n == nil {
+		return
+	}
+	if f, ok := n.failures[what]; ok && f.notified {
+		n.post(fmt.Sprintf("Reaching %s again", what), "Presence updates have resumed")
+	}
+	delete(n.failures, what)
+}
+
+// post shows a notification without holding up the poll
+func (n *FailureNotifier) post(title, message string) {
+	log.Printf("🔔 %s: %s", title, message)
+	script := fmt.Sprintf(`display notification %s with title "am-bridge" subtitle %s`,
+		appleScriptString(message), appleScriptString(title))
+	goSafe("notification", func() {
+		if _, err := runAppleScript(context.Background(), n.scripts, script); err != nil {
+			log.Printf("⚠️  Failed to post notification: %v", err)
+		}
+	})
+}
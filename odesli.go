@@ -0,0 +1,169 @@
+// song.link universal links
+// Odesli (song.link) turns the Apple Music link of a catalog track into one page linking it
+// on every streaming service, shown as a "Listen on your platform" button. The free tier
+// allows 10 requests a minute without an API key: lookups beyond the budget are skipped and
+// retried on a later update, and answers (including misses) are cached for the session.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	// odesliEndpoint - Odesli links API
+	odesliEndpoint = "https://api.song.link/v1-alpha.1/links"
+
+	// odesliFreeLimit - Requests allowed per odesliWindow without an API key
+	odesliFreeLimit = 10
+	odesliWindow    = time.Minute
+)
+
+// errSongLinkLimited means the request budget is spent; the lookup is retried later
+var errSongLinkLimited = errors.New("song.link rate limit reached")
+
+// SongLinkConfig enables the song.link button
+type SongLinkConfig struct {
+	Enabled bool   `json:"enabled"`
+	Label   string `json:"label"`   // Button template, e.g. "Listen on your platform"
+	APIKey  string `json:"api_key"` // Optional; lifts the free-tier request limit
+}
+
+// odesliResponse is the part of the links response the bridge uses
+type odesliResponse struct {
+	PageURL string `json:"pageUrl"`
+}
+
+// SongLinks resolves and caches song.link pages by Apple Music URL
+type SongLinks struct {
+	apiKey string
+
+	mu      sync.Mutex
+	pages   map[string]string // Apple Music URL -> song.link page ("" = unknown to Odesli)
+	sent    []time.Time       // request times within the current window (free tier only)
+	blocked time.Time         // no requests until then after a 429
+}
+
+// NewSongLinks returns nil when song.link is disabled
+func NewSongLinks(cfg SongLinkConfig) *SongLinks {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &SongLinks{apiKey: cfg.APIKey, pages: make(map[string]string)}
+}
+
+// Cached returns the page for an Apple Music URL and whether it is known. Tracks
+// without an Apple Music URL, or a disabled SongLinks, have nothing to look up.
+func (s *SongLinks) Cached(trackURL string) (page string, known bool) {
+	if s == nil || trackURL == "" {
+		return "", true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	page, known = s.pages[trackURL]
+	return page, known
+}
+
+// Lookup returns the cached or freshly resolved page ("" if none or not available yet)
+func (s *SongLinks) Lookup(ctx context.Context, trackURL string) string {
+	if page, known := s.Cached(trackURL); known {
+		return page
+	}
+
+	page, err := s.fetch(ctx, trackURL)
+	if errors.Is(err, errSongLinkLimited) {
+		return "" // quietly retried on a later update
+	}
+	if err != nil {
+		log.Printf("⚠️  song.link lookup failed: %v", err)
+		return ""
+	}
+
+	s.mu.Lock()
+	s.pages[trackURL] = page
+	s.mu.Unlock()
+	if page != "" {
+		log.Printf("🔗 song.link: %s", page)
+	}
+	return page
+}
+
+// allow spends one request from the budget, reporting false when none is left
+func (s *SongLinks) allow(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Before(s.blocked) {
+		return false
+	}
+	if s.apiKey != "" {
+		return true
+	}
+	kept := s.sent[:0]
+	for _, t := range s.sent {
+		if now.Sub(t) < odesliWindow {
+			kept = append(kept, t)
+		}
+	}
+	s.sent = kept
+	if len(s.sent) >= odesliFreeLimit {
+		return false
+	}
+	s.sent = append(s.sent, now)
+	return true
+}
+
+// fetch asks Odesli for the page of an Apple Music URL; "" with a nil error is a miss
+func (s *SongLinks) fetch(ctx context.Context, trackURL string) (string, error) {
+	if !s.allow(time.Now()) {
+		return "", errSongLinkLimited
+	}
+
+	params := url.Values{}
+	params.Set("url", trackURL)
+	if s.apiKey != "" {
+		params.Set("key", s.apiKey)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, odesliEndpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := lookupClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusTooManyRequests:
+		// The limit is per address, so other clients may have spent it; back off a window
+		s.mu.Lock()
+		s.blocked = time.Now().Add(odesliWindow)
+		s.mu.Unlock()
+		log.Println("⏳ song.link rate limit hit, pausing lookups for a minute")
+		return "", errSongLinkLimited
+	case http.StatusBadRequest, http.StatusNotFound:
+		return "", nil // not a track Odesli can resolve
+	default:
+		return "", fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	var result odesliResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	if !isWebURL(result.PageURL) {
+		return "", nil
+	}
+	return result.PageURL, nil
+}
@@ -0,0 +1,229 @@
+// OBS overlay
+// A tiny localhost web server for streamers: "/" is a transparent now-playing card to add
+// as a browser source, "/now" is the current snapshot as JSON and "/events" pushes every
+// change as Server-Sent Events.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// overlayKeepAlive - Interval between SSE comments that keep proxies from timing out
+const overlayKeepAlive = 30 * time.Second
+
+// OverlayConfig enables the overlay server
+type OverlayConfig struct {
+	Addr string `json:"addr"` // e.g. "127.0.0.1:7879"; empty disables the overlay
+}
+
+// overlaySnapshot is the JSON document served to overlay clients
+type overlaySnapshot struct {
+	State     string `json:"state"`
+	Track     *Track `json:"track,omitempty"`
+	UpdatedAt int64  `json:"updated_at"` // unix ms, lets the page extrapolate the position
+}
+
+// OverlayServer serves the now-playing page and its event feed
+type OverlayServer struct {
+	server  *http.Server
+	mu      sync.Mutex
+	current []byte
+	clients map[chan []byte]struct{}
+}
+
+// StartOverlayServer listens on cfg.Addr; it returns nil when the overlay is disabled
+func StartOverlayServer(cfg OverlayConfig) (*OverlayServer, error) {
+	if cfg.Addr == "" {
+		return nil, nil
+	}
+
+	listener, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start overlay server: %w", err)
+	}
+
+	s := &OverlayServer{clients: make(map[chan []byte]struct{})}
+	s.current, _ = json.Marshal(overlaySnapshot{State: StateNotRunning.String(), UpdatedAt: time.Now().UnixMilli()})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.handlePage)
+	mux.HandleFunc("GET /now", s.handleNow)
+	mux.HandleFunc("GET /events", s.handleEvents)
+	s.server = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("⚠️  Overlay server stopped: %v", err)
+		}
+	}()
+	log.Printf("📺 Overlay at http://%s/", listener.Addr())
+	return s, nil
+}
+
+// Close shuts the server down and disconnects every client
+func (s *OverlayServer) Close() {
+	if s == nil {
+		return
+	}
+	s.server.Close()
+}
+
+// Clear shows that nothing is playing
+func (s *OverlayServer) Clear() {
+	s.Publish(nil, StateNotRunning)
+}
+
+// Publish pushes a new snapshot to every connected client
+func (s *OverlayServer) Publish(track *Track, state PlayerState) {
+	if s == nil {
+		return
+	}
+
+	data, err := json.Marshal(overlaySnapshot{State: state.String(), Track: track, UpdatedAt: time.Now().UnixMilli()})
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current = data
+	for ch := range s.clients {
+		// Slow clients only ever need the latest snapshot
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- data
+	}
+}
+
+// snapshot returns the latest encoded snapshot
+func (s *OverlayServer) snapshot() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.current
+}
+
+// handleNow returns the current snapshot
+func (s *OverlayServer) handleNow(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Write(s.snapshot())
+}
+
+// handleEvents streams snapshots as Server-Sent Events, starting with the current one
+func (s *OverlayServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	ch := make(chan []byte, 1)
+	s.mu.Lock()
+	s.clients[ch] = struct{}{}
+	ch <- s.current
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, ch)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	keepAlive := time.NewTicker(overlayKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case data := <-ch:
+			fmt.Fprintf(w, "data: %s\n\n", data)
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// handlePage serves the overlay card
+func (s *OverlayServer) handlePage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, overlayPage)
+}
+
+// overlayPage is a self-contained browser source; the background is transparent for OBS
+const overlayPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>am-bridge overlay</title>
+<style>
+  html, body { margin: 0; background: transparent; font-family: -apple-system, "Helvetica Neue", sans-serif; }
+  #card { display: none; align-items: center; gap: 16px; padding: 16px; width: 480px;
+          background: rgba(20, 20, 20, 0.8); border-radius: 16px; color: #fff; }
+  #card.visible { display: flex; }
+  #art { width: 96px; height: 96px; border-radius: 8px; object-fit: cover; background: #333; }
+  #info { flex: 1; min-width: 0; }
+  #name { font-size: 20px; font-weight: 600; }
+  #artist, #album { font-size: 15px; opacity: 0.75; }
+  #name, #artist, #album { white-space: nowrap; overflow: hidden; text-overflow: ellipsis; }
+  #bar { height: 4px; margin-top: 10px; background: rgba(255, 255, 255, 0.25); border-radius: 2px; }
+  #progress { height: 100%; width: 0; background: #fa2d48; border-radius: 2px; }
+</style>
+</head>
+<body>
+<div id="card">
+  <img id="art" alt="">
+  <div id="info">
+    <div id="name"></div>
+    <div id="artist"></div>
+    <div id="album"></div>
+    <div id="bar"><div id="progress"></div></div>
+  </div>
+</div>
+<script>
+  const $ = (id) => document.getElementById(id);
+  let now = null;
+
+  function render() {
+    const track = now && now.track;
+    $("card").classList.toggle("visible", !!track && now.state !== "Not Running");
+    if (!track) return;
+    $("name").textContent = track.stream_title || track.name;
+    $("artist").textContent = track.artist || "";
+    $("album").textContent = track.album || "";
+    if (track.artwork_url) $("art").src = track.artwork_url; else $("art").removeAttribute("src");
+    $("bar").style.display = track.duration > 0 ? "block" : "none";
+  }
+
+  function tick() {
+    const track = now && now.track;
+    if (!track || !(track.duration > 0)) return;
+    let position = track.player_position;
+    if (now.state === "Playing") position += (Date.now() - now.updated_at) / 1000;
+    $("progress").style.width = Math.min(100, 100 * position / track.duration) + "%";
+  }
+
+  new EventSource("/events").onmessage = (event) => {
+    now = JSON.parse(event.data);
+    render();
+    tick();
+  };
+  setInterval(tick, 500);
+</script>
+</body>
+</html>
+`
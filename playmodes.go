@@ -0,0 +1,82 @@
+// Shuffle and repeat
+// Music and Spotify report their shuffle and repeat settings with every track. Templates
+// render them with {shuffle} (🔀) and {repeat} (🔁, or 🔂 for repeat-one), and
+// presence.play_modes adds the marks to the State line or the badge's hover text. A mode
+// toggled mid-track, like a switch of AirPlay devices, re-sends the presence on the next
+// poll.
+
+package main
+
+import (
+	"cmp"
+	"log"
+	"slices"
+	"strings"
+)
+
+// repeatMark renders the repeat mode ("" when off)
+func (t Track) repeatMark() string {
+	switch t.Repeat {
+	case RepeatOne:
+		return repeatOneMark
+	case RepeatAll:
+		return repeatMark
+	}
+	return ""
+}
+
+// playModes renders the modes that are on, e.g. "🔀 🔁" ("" when none is)
+func (t Track) playModes() string {
+	var marks []string
+	if t.Shuffle {
+		marks = append(marks, shuffleMark)
+	}
+	if mark := t.repeatMark(); mark != "" {
+		marks = append(marks, mark)
+	}
+	return strings.Join(marks, " ")
+}
+
+// refreshSettings re-sends the playing track when its shuffle or repeat setting or its
+// AirPlay devices changed since it was published, reporting whether it did
+func (b *Bridge) refreshSettings(track *Track) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.lastTrack == nil {
+		return false
+	}
+	modes := track.Shuffle != b.lastTrack.Shuffle || track.Repeat != b.lastTrack.Repeat
+	outputs := !slices.Equal(track.Outputs, b.lastTrack.Outputs)
+	if !modes && !outputs {
+		return false
+	}
+	if modes {
+		shuffle := "off"
+		if track.Shuffle {
+			shuffle = "on"
+		}
+		log.Printf("🔀 Play modes changed: shuffle %s, repeat %s", shuffle, cmp.Or(track.Repeat, "off"))
+	}
+	if outputs {
+		log.Printf("🔈 Now playing on: %s", cmp.Or(strings.Join(track.Outputs, ", "), "this Mac"))
+	}
+
+	// Published tracks are read outside the lock (status, sinks), so update a copy
+	updated := *b.lastTrack
+	updated.Shuffle, updated.Repeat, updated.Outputs = track.Shuffle, track.Repeat, track.Outputs
+	updated.PlayerPosition = track.PlayerPosition
+	b.lastTrack = &updated
+	if b.paused || b.config.Privacy.Blocked(&updated) {
+		return true
+	}
+	shown := b.config.Privacy.Masked(&updated)
+	for _, sink := range b.otherSinks() {
+		sink.Publish(shown, StatePlaying)
+	}
+	if b.connected {
+		activity := b.buildActivity(&updated, StatePlaying, updated.ArtworkURL)
+		b.presence.Submit(&activity)
+	}
+	return true
+}
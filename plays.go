@@ -0,0 +1,93 @@
+// Play session tracking
+// Accumulates actual listening time per track across polls, so scrobblers and the
+// history store agree on when a track started, how long it played, and whether it counts.
+
+package main
+
+import "time"
+
+// listenMaxThreshold - Play time after which a listen always counts
+const listenMaxThreshold = 4 * time.Minute
+
+// replayWindow - A same-track position jump back into the first seconds this long
+// (from further in than that) is a restart: repeat-one or a manual replay
+const replayWindow = 15 * time.Second
+
+// PlaySession is one continuous stay on a track (pauses included, play time only counted)
+type PlaySession struct {
+	Track     *Track
+	StartedAt time.Time
+	Listened  time.Duration
+	Scrobbled bool
+}
+
+// Qualifies reports whether the session has played long enough to count as a listen.
+// Radio and live streams never count since they have no fixed track.
+func (s *PlaySession) Qualifies() bool {
+	return !s.Track.IsStream() && s.Listened >= listenThreshold(s.Track)
+}
+
+// listenThreshold returns how long a track must play before it counts as a listen:
+// half its duration or 4 minutes, whichever comes first
+func listenThreshold(track *Track) time.Duration {
+	half := time.Duration(track.Duration/2) * time.Second
+	if half <= 0 || half > listenMaxThreshold {
+		return listenMaxThreshold
+	}
+	return half
+}
+
+// PlayUpdate is the result of observing one poll
+type PlayUpdate struct {
+	Current *PlaySession // nil when nothing is loaded
+	Ended   *PlaySession // session that finished on this poll, if any
+	Started bool         // Current began on this poll
+}
+
+// PlayTracker turns poll observations into play sessions
+type PlayTracker struct {
+	clock    Clock // nil uses the system clock
+	current  *PlaySession
+	lastSeen time.Time
+}
+
+// Observe is called on every poll with the current track (nil when nothing is loaded)
+func (p *PlayTracker) Observe(track *Track, state PlayerState) PlayUpdate {
+	now := time.Now()
+	if p.clock != nil {
+		now = p.clock.Now()
+	}
+
+	// Accumulate play time since the previous poll, only while playing the same track
+	if p.current != nil && state == StatePlaying && track != nil && track.Equals(*p.current.Track) {
+		p.current.Listened += now.Sub(p.lastSeen)
+	}
+	p.lastSeen = now
+
+	// Pausing carries the session on; a paused poll doesn't necessarily read the track, and
+	// the position last seen is no sign of a restart
+	if state == StatePaused && p.current != nil && (track == nil || track.Equals(*p.current.Track)) {
+		return PlayUpdate{Current: p.current}
+	}
+
+	if track == nil || state == StateNotRunning {
+		ended := p.current
+		p.current = nil
+		return PlayUpdate{Ended: ended}
+	}
+
+	if p.current == nil || !track.Equals(*p.current.Track) || (state == StatePlaying && restarted(p.current.Track, track)) {
+		ended := p.current
+		p.current = &PlaySession{Track: track, StartedAt: now}
+		return PlayUpdate{Current: p.current, Ended: ended, Started: true}
+	}
+
+	p.current.Track = track
+	return PlayUpdate{Current: p.current}
+}
+
+// restarted reports whether the same track jumped back to its beginning
+func restarted(prev, next *Track) bool {
+	window := replayWindow.Seconds()
+	return next.PlayerPosition < window && prev.PlayerPosition-next.PlayerPosition > window
+}
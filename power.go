@@ -0,0 +1,101 @@
+// Power awareness
+// On battery or in Low Power Mode the poll interval is stretched and, optionally, artwork
+// lookups are limited to the cache. Power state comes from pmset and is re-checked once a minute.
+
+package main
+
+import (
+	"log"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// powerCheckInterval - How long a pmset reading is trusted
+const powerCheckInterval = time.Minute
+
+// PowerConfig controls battery-aware behaviour
+type PowerConfig struct {
+	Enabled        bool    `json:"enabled"`
+	PollMultiplier float64 `json:"poll_multiplier"` // Poll interval factor while saving power
+	PauseArtwork   bool    `json:"pause_artwork"`   // Only use cached artwork while saving power
+}
+
+// PowerMonitor caches whether the Mac is saving power
+type PowerMonitor struct {
+	config  PowerConfig
+	mu      sync.Mutex
+	checked time.Time
+	saving  bool
+}
+
+// NewPowerMonitor returns nil when power awareness is disabled
+func NewPowerMonitor(cfg PowerConfig) *PowerMonitor {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &PowerMonitor{config: cfg}
+}
+
+// Saving reports whether the Mac is on battery or in Low Power Mode
+func (p *PowerMonitor) Saving() bool {
+	if p == nil {
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if time.Since(p.checked) < powerCheckInterval {
+		return p.saving
+	}
+	p.checked = time.Now()
+
+	saving := onBatteryPower() || lowPowerMode()
+	if saving != p.saving {
+		if saving {
+			log.Printf("🔋 Saving power, polling %.0fx less often", p.config.PollMultiplier)
+		} else {
+			log.Println("🔌 On AC power, normal polling resumed")
+		}
+	}
+	p.saving = saving
+	return saving
+}
+
+// Stretch lengthens a poll delay while saving power
+func (p *PowerMonitor) Stretch(delay time.Duration) time.Duration {
+	if !p.Saving() || p.config.PollMultiplier <= 1 {
+		return delay
+	}
+	return time.Duration(float64(delay) * p.config.PollMultiplier)
+}
+
+// ArtworkPaused reports whether artwork lookups should be skipped
+func (p *PowerMonitor) ArtworkPaused() bool {
+	return p != nil && p.config.PauseArtwork && p.Saving()
+}
+
+// onBatteryPower parses "Now drawing from 'Battery Power'" from pmset
+func onBatteryPower() bool {
+	output, err := exec.Command("pmset", "-g", "batt").Output()
+	if err != nil {
+		return false
+	}
+	return strings.Contains(string(output), "'Battery Power'")
+}
+
+// lowPowerMode looks for "lowpowermode 1" in the active power settings
+func lowPowerMode() bool {
+	output, err := exec.Command("pmset", "-g").Output()
+	if err != nil {
+		return false
+	}
+	for line := range strings.Lines(string(output)) {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "lowpowermode" {
+			return fields[1] == "1"
+		}
+	}
+	return false
+}
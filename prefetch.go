@@ -0,0 +1,58 @@
+// Next track
+// When a track starts, the bridge asks the player what comes next. presence.up_next names
+// it in the presence, and its cover is looked up in the background, so the next presence
+// is published with its art at once instead of following a moment later. Only the caches
+// are filled; nothing is published for the next track until it plays.
+
+package main
+
+import (
+	"context"
+	"log"
+)
+
+// upNext returns the track queued after the current one, nil when the player can't say
+func upNext(ctx context.Context, queue queueSource) *Track {
+	next, err := queue.NextTrack(ctx)
+	if err != nil {
+		log.Printf("⚠️  Couldn't read the next track: %v", err)
+		return nil
+	}
+	return next
+}
+
+// prefetchNext warms the artwork caches for the track queued after the current one. next
+// is that track when presence.up_next already asked for it.
+func (b *Bridge) prefetchNext(queue queueSource, next *Track) {
+	b.mu.Lock()
+	config := b.config
+	resolver := b.artworkResolver()
+	b.mu.Unlock()
+	if !config.Artwork.Prefetch || b.power.ArtworkPaused() {
+		return
+	}
+	// Embedded covers go to a third-party host, so only those of tracks that play are
+	// uploaded; the next one's is uploaded then, rather than an album search standing in
+	uploads := resolver.uploader != nil
+	resolver.uploader = nil
+
+	ctx := b.ctx
+	goSafe("artwork prefetch", func() {
+		if config.Presence.UpNext == UpNextHidden {
+			next = upNext(ctx, queue)
+		}
+		if next == nil {
+			return // the cover is looked up when the track plays, as without pre-fetching
+		}
+		// Blocked tracks never leave the machine, here as when they play
+		if next.ArtworkURL != "" || next.IsStream() || next.Video || next.Audiobook ||
+			(next.Local && uploads) || config.Privacy.Blocked(next) {
+			return
+		}
+		if _, known := resolver.cached(next); known {
+			return
+		}
+		log.Printf("⏭️  Pre-fetching artwork for the next track: %s - %s", next.Name, next.Artist)
+		resolver.resolve(ctx, next)
+	})
+}
@@ -0,0 +1,111 @@
+// Presence preview
+// `am-bridge preview --track "Airbag" --artist "Radiohead" --album "OK Computer"` publishes a
+// one-off activity through the normal rendering and artwork pipeline, so template and config
+// changes can be checked in Discord without waiting for a real track change. Discord drops
+// an activity when its connection closes, so the preview stays up until Ctrl-C or -hold.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"am-discord-bridge/discord"
+)
+
+// cmdPreview shows a made-up track on the Discord profile
+func cmdPreview(args []string) error {
+	fs := flag.NewFlagSet("preview", flag.ExitOnError)
+	configPath := fs.String("config", DefaultConfigPath(), "path to the JSON config file")
+	appID := fs.String("app-id", "", "Discord application ID (overrides config and profile)")
+	profile := fs.String("profile", "", "named Discord profile from discord.profiles")
+	dryRun := fs.Bool("dry-run", false, "log the payload instead of sending it to Discord")
+	hold := fs.Duration("hold", 0, "clear the preview after this long (default: until Ctrl-C)")
+	name := fs.String("track", "", "track name (required)")
+	artist := fs.String("artist", "", "artist")
+	album := fs.String("album", "", "album")
+	duration := fs.Float64("duration", 240, "track length in seconds; 0 previews a live stream")
+	position := fs.Float64("position", 0, "playback position in seconds")
+	paused := fs.Bool("paused", false, "preview the paused presence")
+	playlist := fs.String("playlist", "", "playlist being played, for presence.show_playlist")
+	genre := fs.String("genre", "", "genre, e.g. Classical")
+	composer := fs.String("composer", "", "composer, for classical templates")
+	loved := fs.Bool("loved", false, "mark the track as loved")
+	local := fs.Bool("local", false, "treat the track as a local file (no catalog lookup)")
+	source := fs.String("source", "Apple Music", "player name shown by {source}")
+	fs.Parse(args)
+
+	if *name == "" {
+		return errors.New("preview needs at least -track")
+	}
+
+	config, err := LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	config.Discord.ApplyOverrides(*appID, *profile)
+	if *dryRun {
+		config.Discord.Transport = discord.TransportDryRun
+	}
+	// A preview is never a real listen
+	config.History.Enabled = false
+	config.ListenBrainz = ListenBrainzConfig{}
+	config.Hooks = HookConfig{}
+	config.Slack = SlackConfig{}
+	config.MQTT.Broker = ""
+
+	if _, err := sendControl("status"); err == nil && !*dryRun {
+		fmt.Fprintln(os.Stderr, "Note: the running daemon may replace the preview; 'am-bridge pause' it first")
+	}
+
+	bridge, err := NewBridge(config)
+	if err != nil {
+		return err
+	}
+	if err := bridge.Connect(); err != nil {
+		return fmt.Errorf("failed to connect to Discord: %w", err)
+	}
+	defer bridge.Disconnect()
+
+	track := (&Track{
+		Name:           *name,
+		Artist:         *artist,
+		Album:          *album,
+		Duration:       *duration,
+		PlayerPosition: *position,
+		Playlist:       *playlist,
+		Genre:          *genre,
+		Composer:       *composer,
+		Loved:          *loved,
+		Local:          *local,
+		Source:         *source,
+	}).normalize()
+	state := StatePlaying
+	if *paused {
+		state = StatePaused
+	}
+	bridge.UpdatePresence(track, state)
+
+	shutdown := make(chan os.Signal, 1)
+	signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM)
+	var expired <-chan time.Time
+	if *hold > 0 {
+		expired = time.After(*hold)
+		log.Printf("👀 Previewing for %v", *hold)
+	} else {
+		log.Println("👀 Previewing; press Ctrl-C to clear")
+	}
+	select {
+	case <-shutdown:
+	case <-expired:
+	}
+
+	bridge.ClearPresence()
+	return nil
+}
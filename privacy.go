@@ -0,0 +1,335 @@
+// Privacy rules
+// Block or allow rules (glob by default, where * and ? match "/" too; regex when "regex": true) decide whether a track
+// may be shown on Discord. Blocked tracks either clear the presence or show a generic mask.
+// Field masks are finer: they hide or replace single fields of every track they match
+// before it reaches an output, leaving the rest of the presence as it was. With
+// "explicit": "hide", tracks rated explicit are blocked too. AirPlay devices matching
+// "hide_outputs" are left out of the output list everywhere it is shown.
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Privacy actions for blocked tracks
+const (
+	PrivacyClear = "clear" // Remove the presence entirely
+	PrivacyMask  = "mask"  // Show a generic "Listening to music" presence
+)
+
+// Explicit-content settings
+const (
+	ExplicitShow = "show" // Explicit tracks are shown like any other
+	ExplicitHide = "hide" // Explicit tracks are blocked, as the privacy action says
+)
+
+// explicitMarker matches the tags stores and rips add to explicit titles
+var explicitMarker = regexp.MustCompile(`(?i)[\[(]explicit[\])]|🅴`)
+
+// compileGlob turns a case-insensitive glob into an anchored regexp. Unlike filepath.Match,
+// * and ? also match "/": names aren't paths ("AC/DC", "Sleep / ASMR"). [a-z], [^a-z]
+// (or [!a-z]) and \ escapes work as in filepath.Match.
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	var re strings.Builder
+	re.WriteString("(?is)^")
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; r {
+		case '*':
+			re.WriteString(".*")
+		case '?':
+			re.WriteString(".")
+		case '\\':
+			if i++; i == len(runes) {
+				return nil, filepath.ErrBadPattern
+			}
+			re.WriteString(regexp.QuoteMeta(string(runes[i])))
+		case '[':
+			i++
+			re.WriteByte('[')
+			if i < len(runes) && (runes[i] == '^' || runes[i] == '!') {
+				re.WriteByte('^')
+				i++
+			}
+			start := i
+			for ; i < len(runes) && runes[i] != ']'; i++ {
+				switch c := runes[i]; c {
+				case '\\':
+					if i++; i == len(runes) {
+						return nil, filepath.ErrBadPattern
+					}
+					re.WriteString(regexp.QuoteMeta(string(runes[i])))
+				case '-':
+					re.WriteRune(c)
+				default:
+					re.WriteString(regexp.QuoteMeta(string(c)))
+				}
+			}
+			if i == len(runes) || i == start {
+				return nil, filepath.ErrBadPattern // unclosed or empty class
+			}
+			re.WriteByte(']')
+		default:
+			re.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	re.WriteString("$")
+	compiled, err := regexp.Compile(re.String())
+	if err != nil {
+		return nil, filepath.ErrBadPattern // e.g. a reversed range
+	}
+	return compiled, nil
+}
+
+// PrivacyRule matches one track field against a pattern
+type PrivacyRule struct {
+	Field   string `json:"field"`   // "artist", "album", "track", "playlist" or "any"
+	Pattern string `json:"pattern"` // Case-insensitive glob, or regex when Regex is set
+	Regex   bool   `json:"regex"`
+
+	re *regexp.Regexp
+}
+
+// FieldMask hides or replaces one field of the tracks it applies to
+type FieldMask struct {
+	Field string        `json:"field"` // "track", "artist", "album", "playlist", "genre", "composer", "artwork" or "links"
+	With  string        `json:"with"`  // Replacement, e.g. "—"; empty hides the field
+	When  []PrivacyRule `json:"when"`  // Tracks matching any of these; empty means every track
+}
+
+// PrivacyConfig holds the block/allow lists and the field masks
+type PrivacyConfig struct {
+	Block      []PrivacyRule `json:"block"`
+	Allow      []PrivacyRule `json:"allow"`  // When non-empty, only matching tracks are shown
+	Action     string        `json:"action"` // "clear" (default) or "mask"
+	MaskText   string        `json:"mask_text"`
+	MaskFields []FieldMask   `json:"mask_fields"`
+	Explicit   string        `json:"explicit"` // "show" (default) or "hide"
+	// HideOutputs are case-insensitive globs of AirPlay device names never shown
+	HideOutputs []string `json:"hide_outputs"`
+
+	hideOutputs []*regexp.Regexp
+}
+
+// compile validates every rule and prepares its regex
+func (c *PrivacyConfig) compile() error {
+	switch c.Action {
+	case "":
+		c.Action = PrivacyClear
+	case PrivacyClear, PrivacyMask:
+	default:
+		return fmt.Errorf("privacy.action must be %q or %q, got %q", PrivacyClear, PrivacyMask, c.Action)
+	}
+	if c.MaskText == "" {
+		c.MaskText = localize(defaultLanguage, msgMasked)
+	}
+	switch c.Explicit {
+	case "":
+		c.Explicit = ExplicitShow
+	case ExplicitShow, ExplicitHide:
+	default:
+		return fmt.Errorf("privacy.explicit must be %q or %q, got %q", ExplicitShow, ExplicitHide, c.Explicit)
+	}
+
+	c.hideOutputs = nil
+	for _, pattern := range c.HideOutputs {
+		re, err := compileGlob(pattern)
+		if err != nil {
+			return fmt.Errorf("privacy.hide_outputs %q: %w", pattern, err)
+		}
+		c.hideOutputs = append(c.hideOutputs, re)
+	}
+
+	rulesets := [][]PrivacyRule{c.Block, c.Allow}
+	for _, mask := range c.MaskFields {
+		switch mask.Field {
+		case "track", "artist", "album", "playlist", "genre", "composer", "artwork", "links":
+		default:
+			return fmt.Errorf("privacy.mask_fields field must be track, artist, album, playlist, genre, composer, artwork or links, got %q", mask.Field)
+		}
+		rulesets = append(rulesets, mask.When)
+	}
+	for _, rules := range rulesets {
+		for i := range rules {
+			if err := rules[i].compile(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// compile checks the field name and pattern syntax
+func (r *PrivacyRule) compile() error {
+	switch r.Field {
+	case "artist", "album", "track", "playlist", "any":
+	default:
+		return fmt.Errorf("privacy rule field must be artist, album, track, playlist or any, got %q", r.Field)
+	}
+
+	re, err := r.matcher()
+	if err != nil {
+		return fmt.Errorf("privacy rule %q: %w", r.Pattern, err)
+	}
+	r.re = re
+	return nil
+}
+
+// matcher compiles the pattern, as a regex or a glob
+func (r *PrivacyRule) matcher() (*regexp.Regexp, error) {
+	if r.Regex {
+		return regexp.Compile("(?i)" + r.Pattern)
+	}
+	return compileGlob(r.Pattern)
+}
+
+// Matches reports whether the rule applies to the track
+func (r *PrivacyRule) Matches(track *Track) bool {
+	var values []string
+	switch r.Field {
+	case "artist":
+		values = []string{track.Artist}
+	case "album":
+		values = []string{track.Album}
+	case "track":
+		values = []string{track.Name}
+	case "playlist":
+		values = []string{track.Playlist}
+	case "any":
+		values = []string{track.Name, track.Artist, track.Album, track.Playlist}
+	}
+
+	re := r.re
+	if re == nil {
+		// Not compiled by Validate, as for rules built in code
+		var err error
+		if re, err = r.matcher(); err != nil {
+			return false
+		}
+	}
+	for _, value := range values {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// applies reports whether the mask is for this track
+func (m *FieldMask) applies(track *Track) bool {
+	if len(m.When) == 0 {
+		return true
+	}
+	for i := range m.When {
+		if m.When[i].Matches(track) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply overwrites the masked field. artist also covers the album artist and features;
+// links clears every URL that leads to the track, whatever With says.
+func (m *FieldMask) apply(track *Track) {
+	switch m.Field {
+	case "track":
+		track.Name = m.With
+	case "artist":
+		track.Artist, track.AlbumArtist, track.Featured = m.With, m.With, ""
+	case "album":
+		track.Album = m.With
+	case "playlist":
+		track.Playlist = m.With
+	case "genre":
+		track.Genre = m.With
+	case "composer":
+		track.Composer = m.With
+	case "artwork":
+		track.ArtworkURL = m.With
+	case "links":
+		track.AppleMusicURL, track.SongLink = "", ""
+	}
+}
+
+// Masked returns the track as outputs may show it: a copy with the field masks applied,
+// or the track itself when no mask applies. Rules in "when" see the real fields.
+func (c *PrivacyConfig) Masked(track *Track) *Track {
+	if track == nil {
+		return nil
+	}
+	var masked *Track
+	for i := range c.MaskFields {
+		if !c.MaskFields[i].applies(track) {
+			continue
+		}
+		if masked == nil {
+			copied := *track
+			masked = &copied
+		}
+		c.MaskFields[i].apply(masked)
+	}
+	if outputs := c.shownOutputs(track.Outputs); len(outputs) != len(track.Outputs) {
+		if masked == nil {
+			copied := *track
+			masked = &copied
+		}
+		masked.Outputs = outputs
+	}
+	if masked == nil {
+		return track
+	}
+	return masked
+}
+
+// shownOutputs drops the devices matching hide_outputs
+func (c *PrivacyConfig) shownOutputs(outputs []string) []string {
+	hidden := c.hideOutputs
+	if len(hidden) != len(c.HideOutputs) {
+		// Not compiled by Validate, as for configs built in code
+		hidden = nil
+		for _, pattern := range c.HideOutputs {
+			if re, err := compileGlob(pattern); err == nil {
+				hidden = append(hidden, re)
+			}
+		}
+	}
+	var shown []string
+	for _, output := range outputs {
+		if !slices.ContainsFunc(hidden, func(re *regexp.Regexp) bool { return re.MatchString(output) }) {
+			shown = append(shown, output)
+		}
+	}
+	return shown
+}
+
+// IsExplicit reports whether the track is rated explicit or tagged so in its title or album
+func (t *Track) IsExplicit() bool {
+	return t.Explicit || explicitMarker.MatchString(t.Name) || explicitMarker.MatchString(t.Album)
+}
+
+// Blocked reports whether the track must not be shown as-is
+func (c *PrivacyConfig) Blocked(track *Track) bool {
+	if c.Explicit == ExplicitHide && track.IsExplicit() {
+		return true
+	}
+	for i := range c.Block {
+		if c.Block[i].Matches(track) {
+			return true
+		}
+	}
+
+	if len(c.Allow) == 0 {
+		return false
+	}
+	for i := range c.Allow {
+		if c.Allow[i].Matches(track) {
+			return false
+		}
+	}
+	return true
+}
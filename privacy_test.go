@@ -0,0 +1,133 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompileGlob(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"AC*", "AC/DC", true},
+		{"*asmr*", "Sleep / ASMR", true},
+		{"ac/dc", "AC/DC", true},
+		{"Lo-Fi*", "lo-fi beats", true},
+		{"*Bedroom*", "Bedroom / Upstairs", true},
+		{"?BBA", "ABBA", true},
+		{"?BBA", "BBA", false},
+		{"[a-c]*", "Björk", true},
+		{"[^a-c]*", "Björk", false},
+		{"[!a-c]*", "Drake", true},
+		{"Sigur R?s", "Sigur Rós", true},
+		{`\*NSYNC`, "*NSYNC", true},
+		{`\*NSYNC`, "NSYNC", false},
+		{"a.b", "axb", false},
+		{"(Live)*", "(live) at Wembley", true},
+		{"AC*", "The AC/DC Tribute", false},
+		{"", "", true},
+		{"", "x", false},
+	}
+	for _, tt := range tests {
+		re, err := compileGlob(tt.pattern)
+		if err != nil {
+			t.Errorf("compileGlob(%q): %v", tt.pattern, err)
+			continue
+		}
+		if got := re.MatchString(tt.name); got != tt.want {
+			t.Errorf("glob %q on %q = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCompileGlobInvalid(t *testing.T) {
+	for _, pattern := range []string{"[abc", "[]", `trailing\`, "[z-a]", `[a\`} {
+		if _, err := compileGlob(pattern); err == nil {
+			t.Errorf("compileGlob(%q) accepted an invalid glob", pattern)
+		}
+	}
+}
+
+func TestPrivacyBlocked(t *testing.T) {
+	acdc := &Track{Name: "Thunderstruck", Artist: "AC/DC", Album: "The Razors Edge"}
+	asmr := &Track{Name: "Rain", Artist: "Calm", Playlist: "Sleep / ASMR"}
+	jazz := &Track{Name: "So What", Artist: "Miles Davis", Album: "Kind of Blue", Playlist: "Jazz"}
+	explicit := &Track{Name: "Song [Explicit]", Artist: "X"}
+
+	tests := []struct {
+		name    string
+		privacy PrivacyConfig
+		track   *Track
+		want    bool
+	}{
+		{"glob across a slash", PrivacyConfig{Block: []PrivacyRule{{Field: "artist", Pattern: "AC*"}}}, acdc, true},
+		{"any field keyword", PrivacyConfig{Block: []PrivacyRule{{Field: "any", Pattern: "*ASMR*"}}}, asmr, true},
+		{"no match", PrivacyConfig{Block: []PrivacyRule{{Field: "artist", Pattern: "AC*"}}}, jazz, false},
+		{"regex", PrivacyConfig{Block: []PrivacyRule{{Field: "album", Pattern: `^kind of`, Regex: true}}}, jazz, true},
+		{"allow list admits", PrivacyConfig{Allow: []PrivacyRule{{Field: "playlist", Pattern: "jazz"}}}, jazz, false},
+		{"allow list refuses", PrivacyConfig{Allow: []PrivacyRule{{Field: "playlist", Pattern: "jazz"}}}, acdc, true},
+		{"block wins over allow", PrivacyConfig{
+			Block: []PrivacyRule{{Field: "artist", Pattern: "miles*"}},
+			Allow: []PrivacyRule{{Field: "playlist", Pattern: "jazz"}},
+		}, jazz, true},
+		{"explicit hidden", PrivacyConfig{Explicit: ExplicitHide}, explicit, true},
+		{"explicit shown", PrivacyConfig{}, explicit, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			privacy := tt.privacy
+			if err := privacy.compile(); err != nil {
+				t.Fatal(err)
+			}
+			if got := privacy.Blocked(tt.track); got != tt.want {
+				t.Errorf("Blocked = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrivacyRuleUncompiled(t *testing.T) {
+	// Rules built in code never pass through Validate
+	rule := PrivacyRule{Field: "artist", Pattern: "AC*"}
+	if !rule.Matches(&Track{Artist: "AC/DC"}) {
+		t.Error("uncompiled rule AC* doesn't match AC/DC")
+	}
+}
+
+func TestPrivacyRuleValidation(t *testing.T) {
+	tests := []struct {
+		rule PrivacyRule
+		ok   bool
+	}{
+		{PrivacyRule{Field: "artist", Pattern: "AC/DC*"}, true},
+		{PrivacyRule{Field: "artist", Pattern: "[abc"}, false},
+		{PrivacyRule{Field: "artist", Pattern: "(", Regex: true}, false},
+		{PrivacyRule{Field: "label", Pattern: "x"}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.rule.compile(); (err == nil) != tt.ok {
+			t.Errorf("compile(%+v) = %v, want ok %v", tt.rule, err, tt.ok)
+		}
+	}
+}
+
+func TestPrivacyHideOutputs(t *testing.T) {
+	privacy := PrivacyConfig{HideOutputs: []string{"*HomePod*", "Sam's AirPods*"}}
+	if err := privacy.compile(); err != nil {
+		t.Fatal(err)
+	}
+	track := &Track{Name: "x", Outputs: []string{"Kitchen", "Bedroom / HomePod mini", "Sam's AirPods Pro", "Living Room TV"}}
+	masked := privacy.Masked(track)
+	if got := strings.Join(masked.Outputs, ", "); got != "Kitchen, Living Room TV" {
+		t.Errorf("shown outputs = %q, want Kitchen, Living Room TV", got)
+	}
+	if len(track.Outputs) != 4 {
+		t.Errorf("Masked changed the real track's outputs to %q", track.Outputs)
+	}
+
+	uncompiled := PrivacyConfig{HideOutputs: []string{"*HomePod*"}}
+	if got := uncompiled.shownOutputs([]string{"Bedroom / HomePod"}); len(got) != 0 {
+		t.Errorf("uncompiled hide_outputs showed %q", got)
+	}
+}
@@ -0,0 +1,119 @@
+// Presence rate limiting
+// Discord accepts roughly 5 SET_ACTIVITY calls per 20 seconds and silently drops the rest.
+// Updates beyond the budget are coalesced: only the newest pending activity is sent once
+// the window frees up, so rapid skipping ends on the track the user actually settled on.
+
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"am-discord-bridge/discord"
+)
+
+const (
+	// presenceBurst - SET_ACTIVITY calls allowed per presenceWindow
+	presenceBurst  = 5
+	presenceWindow = 20 * time.Second
+)
+
+// presenceQueue serializes activity updates under Discord's rate limit
+type presenceQueue struct {
+	mu         sync.Mutex
+	sent       []time.Time       // send times within the current window
+	pending    *discord.Activity // newest deferred update (nil activity = clear)
+	hasPending bool
+	timer      *time.Timer
+	send       func(*discord.Activity) error
+}
+
+// newPresenceQueue creates a queue delivering through send (nil activity = clear)
+func newPresenceQueue(send func(*discord.Activity) error) *presenceQueue {
+	return &presenceQueue{send: send}
+}
+
+// Submit sends the activity now if the budget allows, otherwise replaces any pending
+// update with it. Returns queued=true when deferred; err only reports immediate sends.
+func (q *presenceQueue) Submit(activity *discord.Activity) (queued bool, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.prune(time.Now())
+	if !q.hasPending && len(q.sent) < presenceBurst {
+		return false, q.sendLocked(activity)
+	}
+
+	q.pending = activity
+	q.hasPending = true
+	q.scheduleLocked()
+	return true, nil
+}
+
+// Reset drops any pending update, e.g. after disconnecting
+func (q *presenceQueue) Reset() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.pending = nil
+	q.hasPending = false
+	if q.timer != nil {
+		q.timer.Stop()
+		q.timer = nil
+	}
+}
+
+// flush sends the pending update once the window allows it
+func (q *presenceQueue) flush() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.timer = nil
+	if !q.hasPending {
+		return
+	}
+
+	q.prune(time.Now())
+	if len(q.sent) >= presenceBurst {
+		q.scheduleLocked()
+		return
+	}
+
+	activity := q.pending
+	q.pending = nil
+	q.hasPending = false
+	if err := q.sendLocked(activity); err != nil {
+		log.Printf("⚠️  Failed to send queued Discord presence: %v", err)
+		return
+	}
+	log.Println("✓ Sent queued Discord presence")
+}
+
+// sendLocked delivers an activity and records it against the budget
+func (q *presenceQueue) sendLocked(activity *discord.Activity) error {
+	q.sent = append(q.sent, time.Now())
+	return q.send(activity)
+}
+
+// scheduleLocked arms the flush timer for when the oldest send leaves the window
+func (q *presenceQueue) scheduleLocked() {
+	if q.timer != nil {
+		return
+	}
+	delay := time.Duration(0)
+	if len(q.sent) >= presenceBurst {
+		delay = time.Until(q.sent[0].Add(presenceWindow))
+	}
+	q.timer = time.AfterFunc(delay, q.flush)
+}
+
+// prune forgets sends older than the window
+func (q *presenceQueue) prune(now time.Time) {
+	cutoff := now.Add(-presenceWindow)
+	i := 0
+	for i < len(q.sent) && q.sent[i].Before(cutoff) {
+		i++
+	}
+	q.sent = q.sent[i:]
+}
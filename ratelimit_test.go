@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"am-discord-bridge/discord"
+)
+
+// recordingSend collects what a presenceQueue delivers
+type recordingSend struct {
+	mu   sync.Mutex
+	sent []*discord.Activity
+	err  error
+}
+
+func (r *recordingSend) send(activity *discord.Activity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, activity)
+	return r.err
+}
+
+func (r *recordingSend) details() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var details []string
+	for _, activity := range r.sent {
+		if activity == nil {
+			details = append(details, "<clear>")
+		} else {
+			details = append(details, activity.Details)
+		}
+	}
+	return details
+}
+
+// expireWindow stops the flush timer and ages every send out of the window, as if
+// presenceWindow had passed
+func expireWindow(q *presenceQueue) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.timer != nil {
+		q.timer.Stop()
+		q.timer = nil
+	}
+	for i := range q.sent {
+		q.sent[i] = q.sent[i].Add(-presenceWindow - time.Second)
+	}
+}
+
+func TestPresenceQueueCoalesces(t *testing.T) {
+	rec := &recordingSend{}
+	q := newPresenceQueue(rec.send)
+	defer q.Reset()
+
+	names := []string{"1", "2", "3", "4", "5", "6", "7", "8"}
+	for i, name := range names {
+		queued, err := q.Submit(&discord.Activity{Details: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := i >= presenceBurst; queued != want {
+			t.Errorf("Submit(%s) queued = %v, want %v", name, queued, want)
+		}
+	}
+	if got := rec.details(); len(got) != presenceBurst {
+		t.Fatalf("sent %q before the window freed up, want the first %d", got, presenceBurst)
+	}
+
+	// Only the newest deferred update goes out
+	expireWindow(q)
+	q.flush()
+	got := rec.details()
+	if len(got) != presenceBurst+1 || got[presenceBurst] != "8" {
+		t.Errorf("sent %q, want 1-5 then 8", got)
+	}
+}
+
+func TestPresenceQueueKeepsOrderBehindPending(t *testing.T) {
+	rec := &recordingSend{}
+	q := newPresenceQueue(rec.send)
+	defer q.Reset()
+
+	for range presenceBurst + 1 {
+		q.Submit(&discord.Activity{Details: "old"})
+	}
+	expireWindow(q)
+	// The budget is free again, but a newer update mustn't overtake the pending one
+	if queued, _ := q.Submit(nil); !queued {
+		t.Fatal("update sent ahead of the pending one")
+	}
+	q.flush()
+	if got := rec.details(); got[len(got)-1] != "<clear>" {
+		t.Errorf("last sent %q, want the clear", got[len(got)-1])
+	}
+}
+
+func TestPresenceQueueBudgetStillSpent(t *testing.T) {
+	rec := &recordingSend{}
+	q := newPresenceQueue(rec.send)
+	defer q.Reset()
+
+	for range presenceBurst + 1 {
+		q.Submit(&discord.Activity{Details: "x"})
+	}
+	// A flush before the window frees up sends nothing and re-arms itself
+	q.mu.Lock()
+	q.timer.Stop()
+	q.timer = nil
+	q.mu.Unlock()
+	q.flush()
+	if got := rec.details(); len(got) != presenceBurst {
+		t.Errorf("early flush sent %d, want %d", len(got), presenceBurst)
+	}
+	q.mu.Lock()
+	rearmed := q.timer != nil && q.hasPending
+	q.mu.Unlock()
+	if !rearmed {
+		t.Error("early flush dropped the pending update")
+	}
+}
+
+func TestPresenceQueueReset(t *testing.T) {
+	rec := &recordingSend{}
+	q := newPresenceQueue(rec.send)
+
+	for range presenceBurst + 1 {
+		q.Submit(&discord.Activity{Details: "x"})
+	}
+	q.Reset()
+	q.flush()
+	if got := rec.details(); len(got) != presenceBurst {
+		t.Errorf("sent %d after Reset, want the pending update dropped", len(got))
+	}
+}
+
+func TestPresenceQueueSendError(t *testing.T) {
+	rec := &recordingSend{err: errors.New("pipe closed")}
+	q := newPresenceQueue(rec.send)
+	defer q.Reset()
+
+	if queued, err := q.Submit(nil); queued || err == nil {
+		t.Errorf("Submit = %v, %v; want the immediate send's error", queued, err)
+	}
+}
+
+func TestPresenceQueuePrune(t *testing.T) {
+	now := time.Now()
+	q := &presenceQueue{sent: []time.Time{
+		now.Add(-presenceWindow - time.Minute),
+		now.Add(-presenceWindow - time.Second),
+		now.Add(-time.Second),
+		now,
+	}}
+	q.prune(now)
+	if len(q.sent) != 2 || !q.sent[0].Equal(now.Add(-time.Second)) {
+		t.Errorf("prune kept %v, want the last two sends", q.sent)
+	}
+}
@@ -0,0 +1,154 @@
+// Hot config reload
+// The daemon re-reads its config on SIGHUP or when the file's modification time changes,
+// and applies it on the poll goroutine without dropping the Discord connection.
+
+package main
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// configWatchInterval - How often the config file is checked for changes
+const configWatchInterval = 2 * time.Second
+
+// WatchConfig delivers a freshly loaded config whenever the file at path changes or the
+// process receives SIGHUP. Invalid edits are logged and skipped, keeping the current config.
+func WatchConfig(path string, load func() (*Config, error)) <-chan *Config {
+	reloads := make(chan *Config, 1)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	goService("config watcher", func() {
+		modTime := configModTime(path)
+		ticker := time.NewTicker(configWatchInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-hup:
+				log.Println("🔄 SIGHUP received, reloading config")
+				modTime = configModTime(path)
+			case <-ticker.C:
+				current := configModTime(path)
+				if current.Equal(modTime) {
+					continue
+				}
+				modTime = current
+				log.Println("🔄 Config file changed, reloading")
+			}
+
+			config, err := load()
+			if err != nil {
+				log.Printf("⚠️  Config reload failed: %v (keeping current settings)", err)
+				continue
+			}
+
+			// Only the newest config matters if the poll loop hasn't picked up the last one
+			select {
+			case <-reloads:
+			default:
+			}
+			reloads <- config
+		}
+	})
+	return reloads
+}
+
+// configModTime returns the file's modification time (zero if it doesn't exist)
+func configModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// Reload swaps in a new config. It must run on the poll goroutine, which owns sources.
+// Changing the Discord app or transport reconnects; history, overlay and the browser
+// endpoint need a restart.
+func (b *Bridge) Reload(config *Config) error {
+	sources, err := NewSourceManager(config.Sources, b.scripts)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	old := b.config
+	b.mu.Unlock()
+	// The chain holds each provider's cache, so it is only rebuilt when it would differ
+	var artwork *ArtworkChain
+	if !config.Artwork.sameChain(old.Artwork) || config.AppleMusic != old.AppleMusic {
+		if artwork, err = NewArtworkChain(config.Artwork); err != nil {
+			return err
+		}
+	}
+	appID, err := config.Discord.ActiveAppID()
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	oldAppID, _ := old.Discord.ActiveAppID()
+	if appID != oldAppID || config.Discord.Transport != old.Discord.Transport || config.Discord.Socket != old.Discord.Socket {
+		if b.connected {
+			b.presence.Reset()
+			b.client.ClearActivity(b.ctx)
+			b.client.Logout()
+			b.connected = false
+		}
+		b.client.SetClientID(appID)
+		b.client.SetTransport(config.Discord.Transport)
+		b.client.SetSocket(config.Discord.Socket)
+	}
+	if config.Artwork.CacheSize != old.Artwork.CacheSize || config.Artwork.CacheTTLHours != old.Artwork.CacheTTLHours ||
+		!sameCleanup(config.Artwork.SearchCleanup, old.Artwork.SearchCleanup) { // misses may be found with the new rules
+		b.cache = config.Artwork.NewCache()
+	}
+	if config.AppleMusic != old.AppleMusic || config.Artwork.CacheSize != old.Artwork.CacheSize ||
+		config.Artwork.CacheTTLHours != old.Artwork.CacheTTLHours {
+		b.catalog = config.Artwork.NewCatalogCache() // misses may resolve with the new token
+	}
+	if config.Discord.Assets != old.Discord.Assets {
+		b.assets = NewAssetUploader(config.Discord.Assets)
+	}
+	if config.SongLink != old.SongLink {
+		b.songLinks = NewSongLinks(config.SongLink)
+	}
+	if config.Slack != old.Slack || config.MQTT != old.MQTT || config.Hooks != old.Hooks {
+		go closeSinks(b.sinks) // clears the old outputs without holding up the reload
+		b.sinks = NewSinks(config)
+	}
+	if config.ListenBrainz != old.ListenBrainz {
+		b.scrobbler = NewListenBrainz(config.ListenBrainz)
+	}
+	if config.History != old.History || config.Overlay != old.Overlay || config.Browser != old.Browser {
+		log.Println("⚠️  history, overlay and browser changes apply after a restart")
+	}
+
+	ConfigureITunes(config.ITunes)
+	ConfigureAppleMusic(config.AppleMusic, config.ITunes)
+	ConfigureCider(config.Cider)
+	ConfigureTrace(config)
+	if err := ConfigureNetwork(config.Network); err != nil {
+		log.Printf("⚠️  Keeping previous network settings: %v", err)
+	}
+	b.sources = sources
+	if artwork != nil && !b.fixedArtwork {
+		b.artwork = artwork
+	}
+	b.uploader = NewArtworkUploader(config.LocalArtwork, b.scripts)
+	b.power = NewPowerMonitor(config.Power)
+	if config.Notify != old.Notify {
+		b.notifier = NewFailureNotifier(config.Notify, b.scripts, b.clock)
+	}
+	b.config = config
+	b.lastTrack = nil // Republish the current track with the new settings
+
+	log.Println("✅ Config reloaded")
+	return nil
+}
@@ -0,0 +1,354 @@
+// Listening reports
+// Summarizes a day or week of listening history ("This week: 214 tracks, top artist: Radiohead").
+// `am-bridge report` prints one on demand; with report.webhook_url set the daemon posts each
+// finished period, remembering the last one sent so reports missed while asleep go out on start.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Report periods
+const (
+	ReportDaily  = "day"
+	ReportWeekly = "week"
+)
+
+const (
+	// reportTopN - Entries per ranking in a report
+	reportTopN = 5
+
+	// reportRetry - Wait before re-posting a report whose webhook failed
+	reportRetry = 10 * time.Minute
+)
+
+// ReportConfig schedules listening reports
+type ReportConfig struct {
+	Period     string `json:"period"`      // "day" or "week"
+	WebhookURL string `json:"webhook_url"` // Posts each finished period; empty disables posting
+}
+
+// ListeningReport is the summary of one period
+type ListeningReport struct {
+	Period     string
+	From, To   time.Time
+	Plays      int
+	Listened   time.Duration
+	Artists    int
+	TopArtists []RankedEntry
+	TopTracks  []RankedEntry
+	current    bool // period still in progress ("This week" rather than "Last week")
+}
+
+// periodStart returns the start of the day or week (Monday) containing t, in local time
+func periodStart(period string, t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	if period == ReportWeekly {
+		// Weekday is 0 for Sunday; weeks start on Monday
+		day = day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	}
+	return day
+}
+
+// nextPeriod returns the start of the period after the one starting at start
+func nextPeriod(period string, start time.Time) time.Time {
+	if period == ReportWeekly {
+		return start.AddDate(0, 0, 7)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// BuildReport summarizes listens in [from, to)
+func BuildReport(history *HistoryStore, period string, from, to time.Time) (*ListeningReport, error) {
+	report := &ListeningReport{Period: period, From: from, To: to}
+
+	var err error
+	report.Plays, report.Listened, report.Artists, err = history.Totals(from, to)
+	if err != nil {
+		return nil, err
+	}
+	if report.TopArtists, err = history.Top("artist", from, to, reportTopN); err != nil {
+		return nil, err
+	}
+	if report.TopTracks, err = history.Top("track", from, to, reportTopN); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// Title names the period relative to now ("This week", "Yesterday", "Week of Oct 5")
+func (r *ListeningReport) Title() string {
+	switch {
+	case r.Period == ReportWeekly && r.current:
+		return "This week"
+	case r.Period == ReportWeekly:
+		return "Week of " + r.From.Format("Jan 2")
+	case r.current:
+		return "Today"
+	case r.To.Equal(periodStart(ReportDaily, time.Now())):
+		return "Yesterday"
+	default:
+		return r.From.Format("Mon Jan 2")
+	}
+}
+
+// Summary is the one-line headline of the report
+func (r *ListeningReport) Summary() string {
+	if r.Plays == 0 {
+		return fmt.Sprintf("%s: no listens", r.Title())
+	}
+	summary := fmt.Sprintf("%s: %d tracks, %s across %d artists",
+		r.Title(), r.Plays, formatListened(r.Listened), r.Artists)
+	if len(r.TopArtists) > 0 {
+		summary += ", top artist: " + r.TopArtists[0].Name
+	}
+	return summary
+}
+
+// String renders the headline followed by the rankings
+func (r *ListeningReport) String() string {
+	var b strings.Builder
+	b.WriteString(r.Summary())
+	b.WriteString("\n")
+
+	for _, ranking := range []struct {
+		title   string
+		entries []RankedEntry
+	}{
+		{"Top artists", r.TopArtists},
+		{"Top tracks", r.TopTracks},
+	} {
+		if len(ranking.entries) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s\n", ranking.title)
+		for i, entry := range ranking.entries {
+			fmt.Fprintf(&b, "  %d. %s — %d plays\n", i+1, entry.Name, entry.Plays)
+		}
+	}
+	return b.String()
+}
+
+// formatListened renders a listening time as "14h 32m" or "45m"
+func formatListened(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+	return fmt.Sprintf("%dh %dm", d/time.Hour, (d%time.Hour)/time.Minute)
+}
+
+// reportEntry is a ranking line in the webhook payload
+type reportEntry struct {
+	Name            string `json:"name"`
+	Plays           int    `json:"plays"`
+	ListenedSeconds int64  `json:"listened_seconds"`
+}
+
+// reportPayload is the JSON body posted to report.webhook_url. "content" and "text"
+// carry the rendered report so Discord and Slack webhooks display it as-is.
+type reportPayload struct {
+	Event           string        `json:"event"`
+	Period          string        `json:"period"`
+	From            int64         `json:"from"`
+	To              int64         `json:"to"`
+	Plays           int           `json:"plays"`
+	ListenedSeconds int64         `json:"listened_seconds"`
+	Artists         int           `json:"artists"`
+	TopArtists      []reportEntry `json:"top_artists"`
+	TopTracks       []reportEntry `json:"top_tracks"`
+	Content         string        `json:"content"`
+	Text            string        `json:"text"`
+}
+
+// payload converts the report for the webhook
+func (r *ListeningReport) payload() reportPayload {
+	entries := func(ranked []RankedEntry) []reportEntry {
+		out := make([]reportEntry, 0, len(ranked))
+		for _, e := range ranked {
+			out = append(out, reportEntry{e.Name, e.Plays, int64(e.Listened.Seconds())})
+		}
+		return out
+	}
+
+	text := r.String()
+	return reportPayload{
+		Event:           "listening_report",
+		Period:          r.Period,
+		From:            r.From.Unix(),
+		To:              r.To.Unix(),
+		Plays:           r.Plays,
+		ListenedSeconds: int64(r.Listened.Seconds()),
+		Artists:         r.Artists,
+		TopArtists:      entries(r.TopArtists),
+		TopTracks:       entries(r.TopTracks),
+		Content:         text,
+		Text:            text,
+	}
+}
+
+// Post sends the report to a webhook
+func (r *ListeningReport) Post(webhookURL string) error {
+	return postJSON(webhookURL, r.payload())
+}
+
+// reportState records the end of the last period posted
+type reportState struct {
+	LastSent time.Time `json:"last_sent"`
+}
+
+// reportStatePath keeps the state next to the history database
+func reportStatePath(cfg HistoryConfig) string {
+	return filepath.Join(filepath.Dir(historyPath(cfg)), "report-state.json")
+}
+
+// loadReportState reads the state file; a missing file yields the zero state
+func loadReportState(path string) (reportState, error) {
+	var state reportState
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	return state, json.Unmarshal(data, &state)
+}
+
+// save writes the state file
+func (s reportState) save(path string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// StartReports posts a report for every finished period until stop is closed.
+// Does nothing without a webhook or history.
+func StartReports(cfg ReportConfig, historyCfg HistoryConfig, history *HistoryStore, stop <-chan struct{}) {
+	if cfg.WebhookURL == "" || history == nil {
+		return
+	}
+	statePath := reportStatePath(historyCfg)
+
+	goService("reports", func() {
+		state, err := loadReportState(statePath)
+		if err != nil {
+			log.Printf("⚠️  Report state unreadable, starting over: %v", err)
+		}
+		if state.LastSent.IsZero() {
+			// First run: start with the current period rather than reporting on old history
+			state.LastSent = periodStart(cfg.Period, time.Now())
+			if err := state.save(statePath); err != nil {
+				log.Printf("⚠️  Failed to save report state: %v", err)
+			}
+		}
+
+		for {
+			wait := time.Until(nextPeriod(cfg.Period, periodStart(cfg.Period, time.Now())))
+			if due := periodStart(cfg.Period, time.Now()); state.LastSent.Before(due) {
+				if err := postDueReport(cfg, history, state.LastSent); err != nil {
+					log.Printf("⚠️  Listening report failed: %v", err)
+					wait = reportRetry
+				} else {
+					state.LastSent = nextPeriod(cfg.Period, state.LastSent)
+					if err := state.save(statePath); err != nil {
+						log.Printf("⚠️  Failed to save report state: %v", err)
+					}
+					continue // catch up on further missed periods
+				}
+			}
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-stop:
+				timer.Stop()
+				return
+			}
+		}
+	})
+}
+
+// postDueReport builds and posts the report for the period starting at from
+func postDueReport(cfg ReportConfig, history *HistoryStore, from time.Time) error {
+	report, err := BuildReport(history, cfg.Period, from, nextPeriod(cfg.Period, from))
+	if err != nil {
+		return err
+	}
+	if err := report.Post(cfg.WebhookURL); err != nil {
+		return err
+	}
+	log.Printf("📊 Posted listening report: %s", report.Summary())
+	return nil
+}
+
+// cmdReport prints (and optionally posts) the listening report for a period
+func cmdReport(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	configPath := fs.String("config", DefaultConfigPath(), "path to the JSON config file")
+	period := fs.String("period", "", "day or week (default report.period)")
+	last := fs.Bool("last", false, "report on the previous, finished period")
+	post := fs.Bool("post", false, "also post the report to report.webhook_url")
+	asJSON := fs.Bool("json", false, "print the webhook payload instead of text")
+	fs.Parse(args)
+
+	config, err := LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if *period == "" {
+		*period = config.Report.Period
+	}
+	if *period != ReportDaily && *period != ReportWeekly {
+		return fmt.Errorf("unknown period %q (want day or week)", *period)
+	}
+	if *post && config.Report.WebhookURL == "" {
+		return fmt.Errorf("-post needs report.webhook_url in %s", *configPath)
+	}
+	if err := ConfigureNetwork(config.Network); err != nil {
+		return err
+	}
+
+	history, err := openHistoryForCommand(*configPath)
+	if err != nil {
+		return err
+	}
+
+	from, to := periodStart(*period, time.Now()), time.Now()
+	if *last {
+		to = from
+		from = periodStart(*period, from.Add(-time.Second))
+	}
+	report, err := BuildReport(history, *period, from, to)
+	if err != nil {
+		return err
+	}
+	report.current = !*last
+
+	if *asJSON {
+		if err := printJSON(report.payload()); err != nil {
+			return err
+		}
+	} else {
+		fmt.Print(report.String())
+	}
+
+	if *post {
+		if err := report.Post(config.Report.WebhookURL); err != nil {
+			return fmt.Errorf("failed to post report: %w", err)
+		}
+		fmt.Fprintln(os.Stderr, "Posted to", config.Report.WebhookURL)
+	}
+	return nil
+}
@@ -0,0 +1,283 @@
+// Artwork resolution
+// Covers come from the source itself, the exact catalog match, an uploaded embedded cover
+// or the album provider chain. Lookups that need the network run in the background: the
+// presence is published at once without art and updated when the cover arrives.
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+)
+
+// artworkRetryDelays - Backoff between background lookups that failed without an answer
+var artworkRetryDelays = []time.Duration{2 * time.Second, 5 * time.Second, 15 * time.Second}
+
+// artworkResolver holds the lookups used for one track. It is captured under Bridge.mu
+// so a background fetch keeps working with the chain it started with across a Reload.
+type artworkResolver struct {
+	cache    *ArtworkCache
+	catalog  *CatalogCache
+	chain    ArtworkFetcher
+	uploader *ArtworkUploader
+	links    *SongLinks
+}
+
+// artworkResolver captures the current lookups; call with b.mu held
+func (b *Bridge) artworkResolver() artworkResolver {
+	return artworkResolver{b.cache, b.catalog, b.artwork, b.uploader, b.songLinks}
+}
+
+// quickArtwork resolves artwork from what is already known, without network lookups.
+// known is false when a background fetch is needed.
+func (b *Bridge) quickArtwork(track *Track) (url string, known bool) {
+	// Sources that know their artwork (Spotify) skip lookups entirely
+	if track.ArtworkURL != "" {
+		return track.ArtworkURL, true
+	}
+
+	// Radio stations, live streams, videos and audiobooks have no album to search for
+	if track.IsStream() || track.Video || track.Audiobook {
+		return "", true
+	}
+
+	url, known = b.artworkResolver().cached(track)
+
+	// While saving power only artwork that is already cached is used
+	if b.power.ArtworkPaused() {
+		return url, true
+	}
+	return url, known
+}
+
+// fetchArtworkAsync resolves the cover in the background and republishes the presence
+// when it arrives, unless the track has changed in the meantime. Call with b.mu held.
+func (b *Bridge) fetchArtworkAsync(track *Track, state PlayerState) {
+	b.artworkFor = track
+	resolver := b.artworkResolver()
+	probe := *track // the lookups fill in catalog fields; copied back under the lock
+
+	ctx := b.ctx
+
+	goSafe("artwork lookup", func() {
+		url := resolver.resolve(ctx, &probe)
+		for _, delay := range artworkRetryDelays {
+			if _, known := resolver.cached(&probe); known || !b.awaitingArtwork(track) {
+				break
+			}
+			// Nothing was cached, so the lookup failed rather than missed; try again
+			select {
+			case <-ctx.Done():
+				return // shutting down
+			case <-time.After(delay):
+			}
+			url = resolver.resolve(ctx, &probe)
+		}
+		b.artworkResolved(track, &probe, state, url)
+	})
+}
+
+// awaitingArtwork reports whether track is still the one waiting for a cover
+func (b *Bridge) awaitingArtwork(track *Track) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.artworkFor == track
+}
+
+// artworkResolved republishes the presence with the cover found for track
+func (b *Bridge) artworkResolved(track, probe *Track, state PlayerState, url string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.artworkFor != track {
+		return // skipped, cleared or republished since
+	}
+	b.artworkFor = nil
+
+	if url == "" {
+		url = track.ArtworkURL // keep any stand-in cover
+	}
+	if url == track.ArtworkURL && probe.AppleMusicURL == track.AppleMusicURL && probe.SongLink == track.SongLink &&
+		probe.Genre == track.Genre && probe.Composer == track.Composer && probe.Year == track.Year {
+		return // nothing new to show
+	}
+
+	// Published tracks are read outside the lock (status, sinks), so update a copy
+	updated := *track
+	updated.StoreID = probe.StoreID
+	updated.AppleMusicURL = probe.AppleMusicURL
+	updated.SongLink = probe.SongLink
+	updated.Genre, updated.Composer, updated.Year = probe.Genre, probe.Composer, probe.Year
+	updated.Explicit = probe.Explicit
+	updated.ArtworkURL = url
+	// The catalog may have just rated the track explicit
+	blocked := b.config.Privacy.Blocked(&updated)
+	if b.lastTrack == track {
+		b.lastTrack = &updated
+		state = b.lastState
+		if !b.paused {
+			shown := b.config.Privacy.Masked(&updated)
+			for _, sink := range b.otherSinks() {
+				if publisher, masks := sink.(blockedPublisher); blocked && masks {
+					publisher.PublishBlocked(&updated)
+				} else if blocked {
+					sink.Publish(nil, state)
+				} else {
+					sink.Publish(shown, state)
+				}
+			}
+		}
+	}
+	track = &updated
+	if !b.connected || b.paused {
+		return
+	}
+	if blocked {
+		b.publishBlocked(track)
+		return
+	}
+
+	activity := b.buildActivity(track, state, url)
+	if _, err := b.presence.Submit(&activity); err != nil {
+		log.Printf("⚠️  Failed to update Discord presence with artwork: %v", err)
+		return
+	}
+	if url != "" {
+		log.Printf("🖼️  Artwork URL: %s", url)
+	}
+}
+
+// resolve picks the best artwork URL for a track ("" if none), looking it up as needed
+func (r artworkResolver) resolve(ctx context.Context, track *Track) string {
+	// Catalog tracks get exact artwork and a deep link; local files fall through to album search
+	if !track.Local {
+		if match := r.catalogMatch(ctx, track); match != nil {
+			match.apply(track)
+			track.SongLink = r.links.Lookup(ctx, match.TrackURL)
+			if match.ArtworkURL != "" {
+				return match.ArtworkURL
+			}
+		}
+	}
+
+	// Local files prefer their embedded cover when an upload host is configured
+	if track.Local && r.uploader != nil {
+		if url := r.local(ctx, track); url != "" {
+			return url
+		}
+	}
+
+	// Fetch or retrieve cached artwork URL
+	return r.album(ctx, track)
+}
+
+// cached resolves artwork without any network lookups. known is false when the
+// catalog, song.link or album lookup resolve would make hasn't been answered yet.
+func (r artworkResolver) cached(track *Track) (url string, known bool) {
+	catalogKnown := true
+	if !track.Local {
+		match, exists := r.catalog.Get(track)
+		catalogKnown = exists
+		if match != nil {
+			match.apply(track)
+			// The song.link page is looked up alongside the artwork
+			track.SongLink, catalogKnown = r.links.Cached(match.TrackURL)
+			if match.ArtworkURL != "" {
+				return match.ArtworkURL, catalogKnown
+			}
+		}
+	}
+
+	// An album cover can stand in until the catalog answers
+	album, known := r.cache.Get(track.artworkArtist(), track.Album)
+	album.link(track)
+	return album.ArtworkURL, known && catalogKnown
+}
+
+// album returns the cached or freshly searched album artwork URL ("" if none)
+func (r artworkResolver) album(ctx context.Context, track *Track) string {
+	artist := track.artworkArtist()
+	if cached, exists := r.cache.Get(artist, track.Album); exists {
+		cached.link(track)
+		return cached.ArtworkURL
+	}
+
+	log.Printf("🔍 Fetching artwork for: %s - %s", cmp.Or(artist, variousArtists), track.Album)
+	result, err := r.chain.Fetch(ctx, artist, track.Album)
+	if primary := primaryArtist(track); errors.Is(err, errNoArtwork) && artist != "" && primary != artist {
+		// A joint credit may only be known by its first artist
+		result, err = r.chain.Fetch(ctx, primary, track.Album)
+	}
+	if err != nil {
+		if !errors.Is(err, errCircuitOpen) { // the outage was logged once already
+			log.Printf("⚠️  Artwork fetch failed: %v", err)
+		}
+		// Only a definite miss is remembered; network failures are retried
+		if errors.Is(err, errNoArtwork) {
+			r.cache.Set(artist, track.Album, ArtworkResult{})
+		}
+		return ""
+	}
+
+	r.cache.Set(artist, track.Album, result)
+	if result.Score > 0 && result.Score < 1 {
+		log.Printf("📀 Cached artwork (%.0f%% match): %s", result.Score*100, result.ArtworkURL)
+	} else {
+		log.Printf("📀 Cached artwork: %s", result.ArtworkURL)
+	}
+	result.link(track)
+	return result.ArtworkURL
+}
+
+// link gives a track without a song link the album's Apple Music page, so the same
+// lookup that found the cover feeds the "Listen on Apple Music" button
+func (a ArtworkResult) link(track *Track) {
+	if track.AppleMusicURL == "" && strings.HasPrefix(a.AlbumURL, "https://music.apple.com/") {
+		track.AppleMusicURL = a.AlbumURL
+	}
+}
+
+// local returns the hosted URL for a local track's embedded cover ("" if unavailable)
+func (r artworkResolver) local(ctx context.Context, track *Track) string {
+	artist := track.artworkArtist()
+	// An album search that found nothing shares the key; the embedded cover is still worth uploading
+	if cached, exists := r.cache.Get(artist, track.Album); exists && cached.ArtworkURL != "" {
+		return cached.ArtworkURL
+	}
+
+	log.Printf("📤 Uploading embedded artwork for: %s - %s", track.Artist, track.Album)
+	hosted, err := r.uploader.Upload(ctx, track.PersistentID)
+	if err != nil {
+		log.Printf("⚠️  Embedded artwork upload failed: %v", err)
+		return ""
+	}
+
+	r.cache.Set(artist, track.Album, hosted)
+	log.Printf("📀 Cached artwork: %s", hosted.ArtworkURL)
+	return hosted.ArtworkURL
+}
+
+// catalogMatch returns the cached or freshly resolved catalog match (nil if none)
+func (r artworkResolver) catalogMatch(ctx context.Context, track *Track) *CatalogMatch {
+	if match, exists := r.catalog.Get(track); exists {
+		return match
+	}
+
+	match, err := ResolveCatalogTrack(ctx, track)
+	if errors.Is(err, errNoCatalogMatch) {
+		// Remember the miss; transient errors are retried next time
+		r.catalog.Set(track, nil)
+	}
+	if err != nil {
+		if !errors.Is(err, errCircuitOpen) {
+			log.Printf("🔎 Catalog lookup failed, using album search: %v", err)
+		}
+		return nil
+	}
+	r.catalog.Set(track, match)
+	return match
+}
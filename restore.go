@@ -0,0 +1,113 @@
+// Presence restore
+// Every published state is saved to ~/.cache/am-bridge/presence.json. A daemon that
+// restarts mid-song (a crash, an update, a relaunch by launchd) shows the saved track
+// again straight away, its position advanced by the time it was down, so Discord never
+// drops to nothing in between. The first poll then finds the same track and leaves it
+// be, or replaces it if the player has moved on.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// resumeMaxAge - A saved presence older than this is from another listening session
+const resumeMaxAge = 10 * time.Minute
+
+// savedPresence is the contents of presence.json
+type savedPresence struct {
+	Track   *Track    `json:"track"` // nil when nothing was shown
+	Playing bool      `json:"playing"`
+	Saved   time.Time `json:"saved"`
+}
+
+// presenceStatePath returns where the last published presence is saved
+func presenceStatePath() (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "presence.json"), nil
+}
+
+// savePresence records the published state for the next start. Pass the track as it was
+// shown (masked, or nil when blocked): the file is only readable by the user but outlives
+// the privacy rules of the run that wrote it.
+func savePresence(path string, track *Track, state PlayerState, now time.Time) error {
+	data, err := json.Marshal(savedPresence{Track: track, Playing: state == StatePlaying, Saved: now})
+	if err != nil {
+		return err
+	}
+	// Written beside and renamed, so a crash mid-write leaves the previous file intact
+	tmp, err := os.CreateTemp(filepath.Dir(path), "presence-*.tmp") // 0600
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// loadPresence reads the saved state; a missing file yields no track
+func loadPresence(path string) (savedPresence, error) {
+	var saved savedPresence
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return saved, nil
+	}
+	if err != nil {
+		return saved, err
+	}
+	return saved, json.Unmarshal(data, &saved)
+}
+
+// resumed returns the saved track as it should be playing now, or nil when it wasn't
+// playing, is too old, or would have ended since
+func (s savedPresence) resumed(now time.Time) *Track {
+	down := now.Sub(s.Saved)
+	if s.Track == nil || !s.Playing || down < 0 || down > resumeMaxAge {
+		return nil
+	}
+	track := *s.Track
+	if !track.IsStream() {
+		track.PlayerPosition += down.Seconds()
+		if track.PlayerPosition >= track.Duration {
+			return nil
+		}
+	}
+	return &track
+}
+
+// RestorePresence republishes the track saved by the previous run, if it should still be
+// playing. Call before the poll loop starts.
+func (b *Bridge) RestorePresence() {
+	if b.resumePath == "" {
+		return
+	}
+	saved, err := loadPresence(b.resumePath)
+	if err != nil {
+		log.Printf("⚠️  Saved presence unreadable, not restoring it: %v", err)
+		return
+	}
+	track := saved.resumed(b.clock.Now())
+	if track == nil {
+		return
+	}
+
+	log.Printf("♻️  Restoring presence: %s - %s", track.Name, track.Artist)
+	b.UpdatePresence(track, StatePlaying)
+	b.remember(track, StatePlaying)
+	// A player found not running on the first poll then clears it like any other stop
+	b.enter(BridgePlaying, nil, nil)
+}
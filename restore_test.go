@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSavedPresenceResumed(t *testing.T) {
+	saved := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	song := &Track{Name: "Airbag", Artist: "Radiohead", Duration: 284, PlayerPosition: 100}
+	radio := &Track{Name: "Live", Artist: "Station", StreamTitle: "Now on air", PlayerPosition: 5000}
+
+	tests := []struct {
+		name     string
+		presence savedPresence
+		down     time.Duration
+		position float64 // -1 = not resumed
+	}{
+		{"restarted mid-song", savedPresence{song, true, saved}, 30 * time.Second, 130},
+		{"just restarted", savedPresence{song, true, saved}, 0, 100},
+		{"song over since", savedPresence{song, true, saved}, 184 * time.Second, -1},
+		{"too old", savedPresence{song, true, saved}, resumeMaxAge + time.Second, -1},
+		{"at the age limit", savedPresence{&Track{Name: "Long", Duration: 3600}, true, saved}, resumeMaxAge, resumeMaxAge.Seconds()},
+		{"paused", savedPresence{song, false, saved}, 30 * time.Second, -1},
+		{"nothing shown", savedPresence{nil, true, saved}, 30 * time.Second, -1},
+		{"saved in the future", savedPresence{song, true, saved}, -time.Minute, -1},
+		{"stream keeps its position", savedPresence{radio, true, saved}, time.Minute, 5000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			track := tt.presence.resumed(saved.Add(tt.down))
+			switch {
+			case tt.position < 0 && track != nil:
+				t.Errorf("resumed %+v, want nothing", track)
+			case tt.position >= 0 && track == nil:
+				t.Error("not resumed")
+			case track != nil && track.PlayerPosition != tt.position:
+				t.Errorf("position = %v, want %v", track.PlayerPosition, tt.position)
+			}
+		})
+	}
+	if song.PlayerPosition != 100 {
+		t.Errorf("resumed moved the saved track to %v", song.PlayerPosition)
+	}
+}
+
+func TestSavePresence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "presence.json")
+	now := time.Now().Round(0)
+	track := &Track{Name: "Airbag", Artist: "Radiohead", Duration: 284}
+	if err := savePresence(path, track, StatePlaying, now); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("presence.json mode = %v, want 0600", mode)
+	}
+	saved, err := loadPresence(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Track == nil || saved.Track.Name != "Airbag" || !saved.Playing || !saved.Saved.Equal(now) {
+		t.Errorf("loaded %+v", saved)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "*.tmp")); len(leftovers) > 0 {
+		t.Errorf("temp files left behind: %v", leftovers)
+	}
+
+	missing, err := loadPresence(filepath.Join(t.TempDir(), "none.json"))
+	if err != nil || missing.Track != nil {
+		t.Errorf("missing file = %+v, %v; want no track", missing, err)
+	}
+}
+
+func TestRememberSavesShownTrack(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	config := DefaultConfig()
+	config.Privacy.Block = []PrivacyRule{{Field: "artist", Pattern: "secret*"}}
+	config.Privacy.MaskFields = []FieldMask{{Field: "album", With: "—"}}
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	bridge, err := NewBridge(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bridge.resumePath = filepath.Join(t.TempDir(), "presence.json")
+
+	bridge.remember(&Track{Name: "Airbag", Artist: "Radiohead", Album: "OK Computer"}, StatePlaying)
+	saved, err := loadPresence(bridge.resumePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Track == nil || saved.Track.Album != "—" || saved.Track.Name != "Airbag" {
+		t.Errorf("saved %+v, want the album masked", saved.Track)
+	}
+
+	bridge.remember(&Track{Name: "Diary", Artist: "Secret Band"}, StatePlaying)
+	if saved, _ := loadPresence(bridge.resumePath); saved.Track != nil {
+		t.Errorf("saved blocked track %+v, want none", saved.Track)
+	}
+}
@@ -0,0 +1,136 @@
+// Presence schedule
+// presence.schedule keeps the daemon running but off the air at set times: "hide" rules
+// clear the presence while they match (work hours, say), and "only" rules, when given,
+// limit publishing to the times they match. Checked on every poll in local time; play
+// tracking, scrobbling and history carry on while hidden.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+// clockRange is a daily time range in minutes since midnight; from == to is all day
+type clockRange struct {
+	from, to int
+}
+
+// parseClockRange turns "09:00-18:00" into a clockRange; "" is all day
+func parseClockRange(s string) (clockRange, error) {
+	if s == "" {
+		return clockRange{}, nil
+	}
+	start, end, ok := strings.Cut(s, "-")
+	if !ok {
+		return clockRange{}, fmt.Errorf("must look like \"09:00-18:00\", got %q", s)
+	}
+	var minutes [2]int
+	for i, part := range []string{start, end} {
+		t, err := time.Parse("15:04", strings.TrimSpace(part))
+		if err != nil {
+			return clockRange{}, fmt.Errorf("must look like \"09:00-18:00\", got %q", s)
+		}
+		minutes[i] = t.Hour()*60 + t.Minute()
+	}
+	return clockRange{minutes[0], minutes[1]}, nil
+}
+
+// Contains reports whether t's time of day falls inside the range
+func (r clockRange) Contains(t time.Time) bool {
+	if r.from == r.to {
+		return true
+	}
+	minutes := t.Hour()*60 + t.Minute()
+	if r.from < r.to {
+		return minutes >= r.from && minutes < r.to
+	}
+	return minutes >= r.from || minutes < r.to // overnight range such as 22:00-06:00
+}
+
+// scheduleDays maps day names to the weekdays they cover
+var scheduleDays = map[string][]time.Weekday{
+	"mon": {time.Monday}, "tue": {time.Tuesday}, "wed": {time.Wednesday}, "thu": {time.Thursday},
+	"fri": {time.Friday}, "sat": {time.Saturday}, "sun": {time.Sunday},
+	"weekdays": {time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+	"weekends": {time.Saturday, time.Sunday},
+}
+
+// ScheduleRule matches a time range on some days
+type ScheduleRule struct {
+	Days  []string `json:"days"`  // "mon".."sun", "weekdays" or "weekends"; empty is every day
+	Hours string   `json:"hours"` // Local time range such as "09:00-17:00"; empty is all day
+}
+
+// validate checks the day names and time range
+func (r ScheduleRule) validate() error {
+	for _, day := range r.Days {
+		if _, ok := scheduleDays[strings.ToLower(day)]; !ok {
+			return fmt.Errorf("unknown day %q (use mon..sun, weekdays or weekends)", day)
+		}
+	}
+	if _, err := parseClockRange(r.Hours); err != nil {
+		return fmt.Errorf("hours %w", err)
+	}
+	return nil
+}
+
+// Matches reports whether t falls on one of the days and inside the hours
+func (r ScheduleRule) Matches(t time.Time) bool {
+	if len(r.Days) > 0 && !slices.ContainsFunc(r.Days, func(day string) bool {
+		return slices.Contains(scheduleDays[strings.ToLower(day)], t.Weekday())
+	}) {
+		return false
+	}
+	hours, _ := parseClockRange(r.Hours) // checked by Validate
+	return hours.Contains(t)
+}
+
+// ScheduleConfig decides when the presence is published
+type ScheduleConfig struct {
+	Hide []ScheduleRule `json:"hide"` // Hidden while any of these match
+	Only []ScheduleRule `json:"only"` // If set, published only while one of these matches
+}
+
+// validate checks every rule
+func (s ScheduleConfig) validate() error {
+	for i, rule := range s.Hide {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("presence.schedule.hide[%d]: %w", i, err)
+		}
+	}
+	for i, rule := range s.Only {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("presence.schedule.only[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Hidden reports whether the schedule keeps the presence off at t
+func (s ScheduleConfig) Hidden(t time.Time) bool {
+	matches := func(rule ScheduleRule) bool { return rule.Matches(t) }
+	if slices.ContainsFunc(s.Hide, matches) {
+		return true
+	}
+	return len(s.Only) > 0 && !slices.ContainsFunc(s.Only, matches)
+}
+
+// pollScheduled clears the presence while the schedule hides it. It returns true when
+// the poll should skip publishing.
+func (b *Bridge) pollScheduled() bool {
+	hidden := b.config.Presence.Schedule.Hidden(b.clock.Now())
+	switch {
+	case hidden && !b.offSchedule:
+		log.Println("🗓️  Outside the presence schedule, clearing presence")
+		b.ClearPresence()
+	case !hidden && b.offSchedule:
+		log.Println("🗓️  Back inside the presence schedule")
+		b.Refresh()
+	}
+	b.offSchedule = hidden
+	return hidden
+}
@@ -0,0 +1,93 @@
+// Injection seams
+// The bridge reaches osascript, the artwork providers and the wall clock through these
+// interfaces, so the poll and update logic can run against fakes and embedders can
+// substitute their own implementations (a caching script runner, a private artwork service).
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ScriptRunner executes OSA scripts
+type ScriptRunner interface {
+	// Run executes script in an OSA language ("AppleScript" or "JavaScript") and returns its
+	// trimmed output, giving up when ctx is done
+	Run(ctx context.Context, language, script string) (string, error)
+}
+
+// ArtworkFetcher finds a public cover for an album. *ArtworkChain is the default;
+// errors wrapping errNoArtwork are definite misses, anything else is retried.
+type ArtworkFetcher interface {
+	Fetch(ctx context.Context, artist, album string) (ArtworkResult, error)
+}
+
+// Clock tells the time for playback tracking and presence timestamps
+type Clock interface {
+	Now() time.Time
+}
+
+// OSAScriptRunner runs scripts through osascript. Hung processes (modal dialogs,
+// stalled Apple Events) are killed after AppleScriptTimeout.
+type OSAScriptRunner struct{}
+
+// Run executes the script with osascript -l language; cancelling ctx kills it
+func (OSAScriptRunner) Run(ctx context.Context, language, script string) (output string, err error) {
+	start := time.Now()
+	defer func() { traceScript(language, script, output, err, time.Since(start)) }()
+	ctx, cancel := context.WithTimeout(ctx, AppleScriptTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "osascript", "-l", language, "-e", script)
+	cmd.WaitDelay = time.Second
+	stdout, err := cmd.Output()
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return "", &ScriptError{errAETimeout, fmt.Sprintf("osascript timed out after %v", AppleScriptTimeout)}
+	case context.Canceled:
+		return "", fmt.Errorf("osascript: %w", ctx.Err())
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		return "", parseScriptError(string(exitErr.Stderr))
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(stdout)), nil
+}
+
+// systemClock is the real wall clock
+type systemClock struct{}
+
+// Now returns time.Now()
+func (systemClock) Now() time.Time { return time.Now() }
+
+// BridgeOption overrides one of the bridge's dependencies
+type BridgeOption func(*Bridge)
+
+// WithScriptRunner replaces osascript for every source and the artwork uploader
+func WithScriptRunner(scripts ScriptRunner) BridgeOption {
+	return func(b *Bridge) { b.scripts = scripts }
+}
+
+// WithArtworkFetcher replaces the configured provider chain; it is kept across reloads
+func WithArtworkFetcher(fetcher ArtworkFetcher) BridgeOption {
+	return func(b *Bridge) { b.artwork, b.fixedArtwork = fetcher, true }
+}
+
+// WithContext ties background artwork lookups to ctx, so cancelling it abandons
+// lookups still in flight
+func WithContext(ctx context.Context) BridgeOption {
+	return func(b *Bridge) { b.ctx = ctx }
+}
+
+// WithClock replaces the wall clock used for play tracking and timestamps
+func WithClock(clock Clock) BridgeOption {
+	return func(b *Bridge) { b.clock = clock }
+}
@@ -0,0 +1,140 @@
+// Self-test
+// `am-bridge selftest [-script file]` runs the whole pipeline on the config without Discord
+// or a real player: a scripted fake player feeds the bridge, which publishes to a fake
+// Discord client (discord/discordtest) that checks every frame the way Discord does.
+// Each presence is printed as sent, and the command fails if Discord would have refused
+// the connection or any update, so template, button and privacy changes can be tried
+// before they go live.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"am-discord-bridge/discord"
+	"am-discord-bridge/discord/discordtest"
+)
+
+// selftestSlack - Time after the script's last step for the bridge to publish it
+const selftestSlack = SkipSettle + 3*time.Second
+
+// selftestScript is played when -script isn't given: a track, a skip, a pause, then the
+// player stopping and quitting
+const selftestScript = `
+0   play   Radiohead|OK Computer|Airbag|284
+8   play   Massive Attack|Mezzanine|Teardrop|330
+16  pause
+22  play
+28  stop
+32  quit
+`
+
+// cmdSelftest plays a script through the bridge into a fake Discord client
+func cmdSelftest(args []string) error {
+	fs := flag.NewFlagSet("selftest", flag.ExitOnError)
+	configPath := fs.String("config", DefaultConfigPath(), "path to the JSON config file")
+	appID := fs.String("app-id", "", "Discord application ID (overrides config and profile)")
+	profile := fs.String("profile", "", "named Discord profile from discord.profiles")
+	script := fs.String("script", "", "fake player script (default: a built-in 30-second timeline)")
+	fs.Parse(args)
+
+	fake, err := parseFakeScript("built-in script", strings.NewReader(selftestScript))
+	if *script != "" {
+		fake, err = loadFakeScript(*script)
+	}
+	if err != nil {
+		return err
+	}
+
+	config, err := LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	config.Discord.ApplyOverrides(*appID, *profile)
+	config.useFakePlayer()
+	config.PollSeconds = 1 // catch every step of the script
+
+	server, err := discordtest.NewServer()
+	if err != nil {
+		return err
+	}
+	defer server.Close()
+	config.Discord.Transport = discord.TransportIPC
+	config.Discord.Socket = server.Socket
+	config.Discord.Optional = false
+
+	sourceFactories[fakeSourceName] = func(ScriptRunner) Source { return fake }
+	ctx, cancel := context.WithTimeout(context.Background(), fake.Length()+selftestSlack)
+	defer cancel()
+	bridge, err := NewBridge(config, WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	if err := bridge.Connect(); err != nil {
+		for _, hello := range server.Handshakes() {
+			if len(hello.Problems) > 0 {
+				return fmt.Errorf("Discord would refuse the connection: %s", strings.Join(hello.Problems, "; "))
+			}
+		}
+		return err
+	}
+
+	fmt.Printf("🧪 Playing the script for %v...\n", fake.Length()+selftestSlack)
+	fake.started = time.Now()
+	runLoop(ctx, bridge)
+	bridge.ClearPresence()
+	bridge.Disconnect()
+
+	return reportSelftest(server.Commands(), fake.started)
+}
+
+// reportSelftest prints every command the fake client received and fails if any was refused
+func reportSelftest(commands []discordtest.Command, started time.Time) error {
+	fmt.Println()
+	rejected := 0
+	for _, command := range commands {
+		fmt.Printf("%6.1fs  %s\n", command.Received.Sub(started).Seconds(), describeActivity(command.Activity))
+		for _, problem := range command.Problems {
+			fmt.Printf("         ❌ %s\n", problem)
+		}
+		if len(command.Problems) > 0 {
+			rejected++
+		}
+	}
+	fmt.Println()
+
+	switch {
+	case len(commands) == 0:
+		return errors.New("nothing was sent to Discord")
+	case rejected > 0:
+		return fmt.Errorf("Discord would reject %d of %d updates", rejected, len(commands))
+	}
+	fmt.Printf("✅ All %d updates would be accepted by Discord\n", len(commands))
+	return nil
+}
+
+// describeActivity summarizes an activity on one line
+func describeActivity(activity *discordtest.Activity) string {
+	if activity == nil {
+		return "(cleared)"
+	}
+	parts := []string{activity.Details}
+	for _, text := range []string{activity.State, activity.Assets.LargeText, activity.Assets.SmallText} {
+		if text != "" {
+			parts = append(parts, text)
+		}
+	}
+	line := strings.Join(parts, " · ")
+	if t := activity.Timestamps; t != nil && t.End != nil {
+		line += fmt.Sprintf("  (ends %s)", time.UnixMilli(*t.End).Format(time.TimeOnly))
+	}
+	for _, button := range activity.Buttons {
+		line += fmt.Sprintf("  [%s]", button.Label)
+	}
+	return line
+}
@@ -0,0 +1,114 @@
+// Presence sinks
+// Every output that shows the current track is a Sink: Discord, the OBS overlay, the Slack
+// status and the track-change hooks. The bridge fans each update out to all of them, so a
+// new output only needs a constructor and a line in NewSinks.
+
+package main
+
+import "sync"
+
+// Sink is an output the now-playing track is published to
+type Sink interface {
+	// Publish shows the track in the given state; track is nil when nothing may be shown
+	// (no player, or hidden by privacy rules). It must not block on the network.
+	Publish(track *Track, state PlayerState)
+	// Clear hides whatever the sink shows
+	Clear()
+}
+
+// blockedPublisher is implemented by sinks with their own way of showing a track hidden
+// by privacy rules; other sinks are handed nil
+type blockedPublisher interface {
+	PublishBlocked(track *Track)
+}
+
+// sinkCloser is implemented by sinks holding background work to finish on shutdown
+type sinkCloser interface {
+	Close()
+}
+
+// NewSinks builds the outputs enabled in the config, besides Discord and the overlay
+func NewSinks(config *Config) []Sink {
+	var sinks []Sink
+	if slack := NewSlackStatus(config.Slack); slack != nil {
+		sinks = append(sinks, slack)
+	}
+	if mqtt := NewMQTTPublisher(config.MQTT); mqtt != nil {
+		sinks = append(sinks, mqtt)
+	}
+	if config.Hooks.Enabled() {
+		sinks = append(sinks, &hookSink{cfg: config.Hooks})
+	}
+	return sinks
+}
+
+// closeSinks finishes each sink's background work
+func closeSinks(sinks []Sink) {
+	for _, sink := range sinks {
+		if closer, ok := sink.(sinkCloser); ok {
+			closer.Close()
+		}
+	}
+}
+
+// discordSink publishes through the bridge's Discord connection
+type discordSink struct {
+	b *Bridge
+}
+
+// Publish shows the track on Discord
+func (d discordSink) Publish(track *Track, state PlayerState) {
+	if track == nil {
+		d.Clear()
+		return
+	}
+	d.b.updateDiscord(track, state)
+}
+
+// PublishBlocked clears or masks the presence as the privacy action says
+func (d discordSink) PublishBlocked(track *Track) {
+	b := d.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.connected || b.paused {
+		return
+	}
+	b.artworkFor = nil
+	b.publishBlocked(track)
+}
+
+// Clear removes the Discord activity
+func (d discordSink) Clear() {
+	d.b.clearDiscord()
+}
+
+// hookSink fires the track-change hooks when a new track starts playing
+type hookSink struct {
+	cfg HookConfig
+
+	mu   sync.Mutex
+	last *Track // last track the hooks fired for
+}
+
+// Publish fires the hooks for a track other than the last one
+func (h *hookSink) Publish(track *Track, state PlayerState) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if track == nil {
+		h.last = nil
+		return
+	}
+	if state != StatePlaying || (h.last != nil && track.Equals(*h.last)) {
+		return
+	}
+	h.last = track
+	FireHooks(h.cfg, track, state)
+}
+
+// Clear forgets the last track, so playing it again later is a change
+func (h *hookSink) Clear() {
+	h.mu.Lock()
+	h.last = nil
+	h.mu.Unlock()
+}
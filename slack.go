@@ -0,0 +1,294 @@
+// Slack status
+// Mirrors the playing track to the user's Slack status ("🎵 Airbag — Radiohead") with a
+// user token that has the users.profile:read and users.profile:write scopes. The status
+// expires on its own shortly after the track ends, and is cleared when playback stops,
+// outside the configured working hours, and on shutdown. A status the user set themselves
+// is never overwritten or cleared.
+// Updates are coalesced to one every slackMinInterval to stay within Slack's rate limits.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// slackAPI - Slack Web API base URL
+	slackAPI = "https://slack.com/api"
+
+	// slackMinInterval - Minimum spacing between status updates
+	slackMinInterval = 15 * time.Second
+
+	// slackExpiryGrace - How long past the track's end the status lingers if never cleared
+	slackExpiryGrace = 2 * time.Minute
+
+	// slackStreamExpiry - Status lifetime for streams, which have no end
+	slackStreamExpiry = 2 * time.Hour
+
+	// maxSlackStatus - Slack's status text limit in characters
+	maxSlackStatus = 100
+)
+
+// SlackConfig mirrors the track to a Slack status
+type SlackConfig struct {
+	Token string `json:"token"` // User token (xoxp-…); empty disables Slack
+	Emoji string `json:"emoji"` // e.g. ":headphones:"
+	Text  string `json:"text"`  // Template, e.g. "{name} — {artist}"
+	// WorkingHours limits updates to a local time range such as "09:00-18:00"; empty is always
+	WorkingHours string `json:"working_hours"`
+	// WorkdaysOnly also skips Saturdays and Sundays
+	WorkdaysOnly bool `json:"workdays_only"`
+}
+
+// slackStatus is one status to set; empty text clears it
+type slackStatus struct {
+	text    string
+	expires time.Time
+}
+
+// SlackStatus publishes statuses from a background worker
+type SlackStatus struct {
+	cfg   SlackConfig
+	hours clockRange
+
+	mu      sync.Mutex
+	want    slackStatus // newest status requested
+	pending bool
+	shown   string // status text last set by the bridge ("" when none)
+	wake    chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewSlackStatus returns nil when no token is configured
+func NewSlackStatus(cfg SlackConfig) *SlackStatus {
+	if cfg.Token == "" {
+		return nil
+	}
+	s := &SlackStatus{
+		cfg:  cfg,
+		wake: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	s.hours, _ = parseClockRange(cfg.WorkingHours) // checked by Validate
+	go s.run()
+	return s
+}
+
+// Publish mirrors the track; anything but a playing track (or a nil one) clears the status
+func (s *SlackStatus) Publish(track *Track, state PlayerState) {
+	if s == nil {
+		return
+	}
+	now := time.Now()
+	if track == nil || state != StatePlaying || !s.working(now) {
+		s.request(slackStatus{})
+		return
+	}
+
+	text := RenderTemplate(s.cfg.Text, track)
+	if runes := []rune(text); len(runes) > maxSlackStatus {
+		text = string(runes[:maxSlackStatus-1]) + "…"
+	}
+	expires := now.Add(slackStreamExpiry)
+	if !track.IsStream() {
+		remaining := time.Duration((track.Duration - track.PlayerPosition) * float64(time.Second))
+		expires = now.Add(max(remaining, 0) + slackExpiryGrace)
+	}
+	s.request(slackStatus{text, expires})
+}
+
+// Clear removes the bridge's status
+func (s *SlackStatus) Clear() {
+	if s == nil {
+		return
+	}
+	s.request(slackStatus{})
+}
+
+// Close clears the bridge's status and stops the worker
+func (s *SlackStatus) Close() {
+	if s == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+}
+
+// working reports whether t falls inside the configured working hours
+func (s *SlackStatus) working(t time.Time) bool {
+	if s.cfg.WorkdaysOnly && (t.Weekday() == time.Saturday || t.Weekday() == time.Sunday) {
+		return false
+	}
+	return s.hours.Contains(t)
+}
+
+// request replaces any status still waiting to be sent
+func (s *SlackStatus) request(status slackStatus) {
+	s.mu.Lock()
+	if status.text == s.want.text {
+		s.mu.Unlock()
+		return
+	}
+	s.want, s.pending = status, true
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run sends requested statuses, at most one per slackMinInterval
+func (s *SlackStatus) run() {
+	defer close(s.done)
+	for {
+		select {
+		case <-s.wake:
+		case <-s.stop:
+			s.send(slackStatus{})
+			return
+		}
+
+		s.mu.Lock()
+		status, pending := s.want, s.pending
+		s.pending = false
+		s.mu.Unlock()
+		if !pending {
+			continue
+		}
+
+		wait := slackMinInterval
+		if retry, err := s.send(status); err != nil {
+			log.Printf("⚠️  Slack status update failed: %v", err)
+			wait = max(wait, retry)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-s.stop:
+			timer.Stop()
+			s.send(slackStatus{})
+			return
+		}
+	}
+}
+
+// send sets or clears the status, leaving alone any status the user set themselves.
+// retry is Slack's Retry-After when rate limited.
+func (s *SlackStatus) send(status slackStatus) (retry time.Duration, err error) {
+	s.mu.Lock()
+	shown := s.shown
+	s.mu.Unlock()
+	if status.text == "" && shown == "" {
+		return 0, nil
+	}
+
+	current, retry, err := s.currentStatus()
+	if err != nil {
+		return retry, err
+	}
+	if current != "" && current != shown {
+		s.setShown("")
+		return 0, nil // "In a meeting" and the like win over music
+	}
+	if status.text == "" && current == "" {
+		s.setShown("") // already expired
+		return 0, nil
+	}
+
+	profile := map[string]any{
+		"status_text":       status.text,
+		"status_emoji":      "",
+		"status_expiration": 0,
+	}
+	if status.text != "" {
+		profile["status_emoji"] = s.cfg.Emoji
+		profile["status_expiration"] = status.expires.Unix()
+	}
+	if retry, err := s.call("users.profile.set", map[string]any{"profile": profile}, nil); err != nil {
+		return retry, err
+	}
+	s.setShown(status.text)
+	return 0, nil
+}
+
+// setShown records the status text the bridge last set
+func (s *SlackStatus) setShown(text string) {
+	s.mu.Lock()
+	s.shown = text
+	s.mu.Unlock()
+}
+
+// currentStatus reads the user's status text
+func (s *SlackStatus) currentStatus() (string, time.Duration, error) {
+	var result struct {
+		Profile struct {
+			StatusText string `json:"status_text"`
+		} `json:"profile"`
+	}
+	retry, err := s.call("users.profile.get", nil, &result)
+	return result.Profile.StatusText, retry, err
+}
+
+// call invokes a Web API method with a JSON body (nil for none) and decodes the reply
+func (s *SlackStatus) call(method string, body any, result any) (retry time.Duration, err error) {
+	var payload []byte
+	if body != nil {
+		if payload, err = json.Marshal(body); err != nil {
+			return 0, err
+		}
+	}
+	req, err := http.NewRequest(http.MethodPost, slackAPI+"/"+method, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.cfg.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		seconds, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		retry = time.Duration(max(seconds, 1)) * time.Second
+		return retry, fmt.Errorf("rate limited, retrying in %v", retry)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return 0, err
+	}
+	var reply struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &reply); err != nil {
+		return 0, err
+	}
+	if !reply.OK {
+		return 0, fmt.Errorf("%s: %s", method, reply.Error)
+	}
+	if result != nil {
+		return 0, json.Unmarshal(data, result)
+	}
+	return 0, nil
+}
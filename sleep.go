@@ -0,0 +1,245 @@
+// Sleep, wake and screen lock
+// A JXA helper subscribes to NSWorkspace's will-sleep and did-wake notifications and the
+// screen lock ones, so the presence is cleared before the Mac sleeps and polling pauses
+// until it wakes. Without CGO the helper is a separate osascript process; if it can't
+// run, or the Mac sleeps without warning (a dead battery, a forced shutdown), there is
+// no clear before sleep. Wakes are still caught: Go's monotonic clock stops while a Mac
+// sleeps but the wall clock keeps going, so a gap between the two reveals a wake. A
+// locked screen is read from the IORegistry and treated like being away: the presence is
+// cleared until unlock.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	wakeCheckInterval = 5 * time.Second
+	wakeMinSleep      = 30 * time.Second // Smaller gaps are clock adjustments, not sleep
+	ioregTimeout      = 2 * time.Second  // Deadline for the screen lock check
+
+	// sleepGrace - How long polling stays paused after a will-sleep notification. The
+	// monotonic clock stops during sleep, so this only runs out when a sleep was cancelled
+	// or the wake notification was lost.
+	sleepGrace = 30 * time.Second
+
+	// powerWatchMinRun - A helper exiting sooner can't subscribe, and isn't restarted
+	powerWatchMinRun = time.Minute
+)
+
+// powerEvent is a notification forwarded by the power watcher
+type powerEvent string
+
+const (
+	powerWillSleep powerEvent = "sleep"
+	powerDidWake   powerEvent = "wake"
+	powerLocked    powerEvent = "lock"
+	powerUnlocked  powerEvent = "unlock"
+)
+
+// powerEventsScript prints one powerEvent per line as macOS posts the notifications
+const powerEventsScript = `
+	ObjC.import("Cocoa");
+	const events = {
+		NSWorkspaceWillSleepNotification: "sleep",
+		NSWorkspaceDidWakeNotification: "wake",
+		"com.apple.screenIsLocked": "lock",
+		"com.apple.screenIsUnlocked": "unlock",
+	};
+	const out = $.NSFileHandle.fileHandleWithStandardOutput;
+	ObjC.registerSubclass({
+		name: "AMBridgePowerObserver",
+		methods: {
+			"notify:": {
+				types: ["void", ["id"]],
+				implementation: function (note) {
+					out.writeData($(events[note.name.js] + "\n").dataUsingEncoding($.NSUTF8StringEncoding));
+				},
+			},
+		},
+	});
+	const observer = $.AMBridgePowerObserver.alloc.init;
+	const workspace = $.NSWorkspace.sharedWorkspace.notificationCenter;
+	const distributed = $.NSDistributedNotificationCenter.defaultCenter;
+	for (const name in events) {
+		const center = name.startsWith("NSWorkspace") ? workspace : distributed;
+		center.addObserverSelectorNameObject(observer, "notify:", name, $());
+	}
+	out.writeData($("ready\n").dataUsingEncoding($.NSUTF8StringEncoding));
+	$.NSRunLoop.currentRunLoop.run;
+`
+
+// WatchPower forwards sleep, wake and screen lock notifications until ctx is cancelled.
+// The channel stays silent when the helper can't subscribe.
+func WatchPower(ctx context.Context) <-chan powerEvent {
+	events := make(chan powerEvent, 4)
+	goService("power watcher", func() {
+		for ctx.Err() == nil {
+			started := time.Now()
+			err := watchPowerOnce(ctx, events)
+			if ctx.Err() != nil {
+				return
+			}
+			if time.Since(started) < powerWatchMinRun {
+				log.Printf("⚠️  Sleep notifications unavailable (%v), presence won't be cleared before sleep", err)
+				return
+			}
+			time.Sleep(serviceRestartDelay)
+		}
+	})
+	return events
+}
+
+// watchPowerOnce runs the helper and forwards its events until it exits
+func watchPowerOnce(ctx context.Context, events chan<- powerEvent) error {
+	cmd := exec.CommandContext(ctx, "osascript", "-l", "JavaScript", "-e", powerEventsScript)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		if event, ok := parsePowerEvent(scanner.Text()); ok {
+			select {
+			case events <- event:
+			case <-ctx.Done():
+			}
+		}
+	}
+	if err := cmd.Wait(); err != nil {
+		return err
+	}
+	return errors.New("helper exited")
+}
+
+// parsePowerEvent reads one line of the helper's output
+func parsePowerEvent(line string) (powerEvent, bool) {
+	switch event := powerEvent(strings.TrimSpace(line)); event {
+	case powerWillSleep, powerDidWake, powerLocked, powerUnlocked:
+		return event, true
+	}
+	return "", false
+}
+
+// WatchWake reports how long the machine slept, shortly after every wake
+func WatchWake() <-chan time.Duration {
+	wakes := make(chan time.Duration, 1)
+	goService("wake watcher", func() {
+		last := time.Now()
+		ticker := time.NewTicker(wakeCheckInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			now := time.Now()
+			slept, ok := sleepGap(now.Round(0).Sub(last.Round(0)), now.Sub(last))
+			last = now
+			if !ok {
+				continue
+			}
+			select {
+			case wakes <- slept:
+			default:
+			}
+		}
+	})
+	return wakes
+}
+
+// sleepGap is how long the Mac slept between two checks wall seconds apart by the wall
+// clock and elapsed apart by the monotonic one; ok is false for mere clock adjustments
+func sleepGap(wall, elapsed time.Duration) (slept time.Duration, ok bool) {
+	slept = wall - elapsed
+	return slept, slept >= wakeMinSleep
+}
+
+// powerChanged handles a notification from WatchPower; the caller polls right after.
+// Lock and unlock need nothing more: the poll reads the lock state itself.
+func (b *Bridge) powerChanged(event powerEvent) {
+	switch event {
+	case powerWillSleep:
+		log.Println("💤 Going to sleep, clearing presence")
+		b.sleepingSince = time.Now()
+		b.ClearPresence()
+		b.Refresh()
+	case powerDidWake:
+		log.Println("☀️  Woke up, refreshing")
+		b.woke()
+	}
+}
+
+// woke ends the pause for sleep and has the next poll republish with fresh timestamps
+func (b *Bridge) woke() {
+	b.sleepingSince = time.Time{}
+	b.wokeAt = time.Now()
+	b.Refresh()
+}
+
+// asleep reports whether the Mac announced it is going to sleep, so polls should wait
+func (b *Bridge) asleep() bool {
+	return !b.sleepingSince.IsZero() && time.Since(b.sleepingSince) < sleepGrace
+}
+
+// screenLocked asks the IORegistry whether the console session is locked
+func screenLocked(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, ioregTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, "ioreg", "-n", "Root", "-d1").Output()
+	if err != nil {
+		return false
+	}
+	return strings.Contains(string(output), `"CGSSessionScreenIsLocked"=Yes`)
+}
+
+// Refresh forgets the published track so the next poll republishes it with fresh timestamps
+func (b *Bridge) Refresh() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastTrack = nil
+}
+
+// pollLocked clears the presence while the screen is locked. It returns true when the
+// poll should skip publishing.
+func (b *Bridge) pollLocked(ctx context.Context) bool {
+	if !b.config.Presence.ClearOnLock {
+		return false
+	}
+
+	locked := screenLocked(ctx)
+	switch {
+	case locked && !b.screenLocked:
+		log.Println("🔒 Screen locked, clearing presence")
+		b.ClearPresence()
+	case !locked && b.screenLocked:
+		log.Println("🔓 Screen unlocked")
+		b.Refresh()
+	}
+	b.screenLocked = locked
+	return locked
+}
+
+// observeLocked keeps play tracking (history, scrobbling) going behind a locked screen
+// or while the presence is hidden by the schedule or a Focus
+func (b *Bridge) observeLocked(ctx context.Context) {
+	source, state, _ := b.sources.Select(ctx)
+	if ctx.Err() != nil {
+		return // shutting down, not a stopped player
+	}
+	var track *Track
+	if source != nil {
+		track, _ = source.CurrentTrack(ctx)
+	}
+	if track != nil && !b.config.Presence.showsOutputs() {
+		track.Outputs = nil
+	}
+	b.observePlay(track, state)
+}
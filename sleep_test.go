@@ -0,0 +1,94 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSleepGap(t *testing.T) {
+	tests := []struct {
+		name          string
+		wall, elapsed time.Duration
+		slept         time.Duration
+		ok            bool
+	}{
+		{"awake", 5 * time.Second, 5 * time.Second, 0, false},
+		{"slept", 10*time.Minute + 5*time.Second, 5 * time.Second, 10 * time.Minute, true},
+		{"exactly the minimum", wakeMinSleep + 5*time.Second, 5 * time.Second, wakeMinSleep, true},
+		{"clock nudged forward", 20 * time.Second, 5 * time.Second, 15 * time.Second, false},
+		{"clock set back", -time.Hour, 5 * time.Second, -time.Hour - 5*time.Second, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slept, ok := sleepGap(tt.wall, tt.elapsed)
+			if slept != tt.slept || ok != tt.ok {
+				t.Errorf("sleepGap(%v, %v) = %v, %v; want %v, %v", tt.wall, tt.elapsed, slept, ok, tt.slept, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParsePowerEvent(t *testing.T) {
+	tests := []struct {
+		line string
+		want powerEvent
+		ok   bool
+	}{
+		{"sleep", powerWillSleep, true},
+		{"wake\n", powerDidWake, true},
+		{"lock", powerLocked, true},
+		{"unlock", powerUnlocked, true},
+		{"ready", "", false},
+		{"undefined", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := parsePowerEvent(tt.line); got != tt.want || ok != tt.ok {
+			t.Errorf("parsePowerEvent(%q) = %q, %v; want %q, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestPipelineSleep(t *testing.T) {
+	p := newPipeline(t, testScript)
+
+	p.pollAt(1)
+	if playing := p.next().Activity; playing == nil || playing.Details != "Airbag" {
+		t.Fatalf("playing activity = %+v", playing)
+	}
+
+	// Cleared before sleeping, and polls publish nothing until the wake
+	p.bridge.powerChanged(powerWillSleep)
+	if cleared := p.next(); cleared.Activity != nil {
+		t.Fatalf("activity before sleep = %+v, want a clear", cleared.Activity)
+	}
+	p.pollAt(5)
+	p.quiet()
+
+	p.bridge.powerChanged(powerDidWake)
+	p.pollAt(6)
+	if woken := p.next().Activity; woken == nil || woken.Details != "Airbag" || woken.Timestamps == nil {
+		t.Fatalf("activity after wake = %+v, want Airbag with timestamps", woken)
+	}
+}
+
+func TestAsleepGrace(t *testing.T) {
+	var b Bridge
+	if b.asleep() {
+		t.Error("asleep without a sleep notification")
+	}
+	b.sleepingSince = time.Now()
+	if !b.asleep() {
+		t.Error("not asleep right after the sleep notification")
+	}
+	// A sleep that never happened stops holding polls back
+	b.sleepingSince = time.Now().Add(-sleepGrace - time.Second)
+	if b.asleep() {
+		t.Error("still asleep after the grace period")
+	}
+	b.sleepingSince = time.Now()
+	b.woke()
+	if b.asleep() || b.wokeAt.IsZero() {
+		t.Errorf("after woke: asleep %v, wokeAt %v", b.asleep(), b.wokeAt)
+	}
+}
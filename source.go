@@ -0,0 +1,193 @@
+// Player sources
+// Each media app the bridge can read from implements Source. A SourceManager asks every
+// enabled source for its state at once and picks the highest-priority one that is playing,
+// else the highest-priority paused one.
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+)
+
+// Source is a media player the bridge reads now-playing data from
+type Source interface {
+	// Name identifies the source in logs and payloads ("Apple Music", "Spotify")
+	Name() string
+	// State reports whether the app is running and its playback state
+	State(ctx context.Context) (PlayerState, error)
+	// CurrentTrack returns the loaded track; only called when State is Playing or Paused
+	CurrentTrack(ctx context.Context) (*Track, error)
+}
+
+// queueSource is a Source that can tell which track plays after the current one
+type queueSource interface {
+	// NextTrack returns the queued track, or nil when the player doesn't know it yet
+	NextTrack(ctx context.Context) (*Track, error)
+}
+
+// sourceFactories maps config names to source constructors
+var sourceFactories = map[string]func(scripts ScriptRunner) Source{
+	"music":    func(scripts ScriptRunner) Source { return musicSource{scripts} },
+	"spotify":  func(scripts ScriptRunner) Source { return spotifySource{scripts} },
+	"podcasts": func(scripts ScriptRunner) Source { return newPodcastsSource(scripts) },
+	"tv":       func(scripts ScriptRunner) Source { return newTVSource(scripts) },
+	"books":    func(scripts ScriptRunner) Source { return newBooksSource(scripts) },
+	"web":      func(ScriptRunner) Source { return webSource{browserFeed} },
+	"cider":    func(ScriptRunner) Source { return ciderSource{} },
+}
+
+// defaultSources - Priority order when none is configured
+var defaultSources = []SourceEntry{{Name: "music", Enabled: true}}
+
+// SourceEntry is one element of "sources": a bare name, or an object that can switch the
+// source off or rank it above the list order
+type SourceEntry struct {
+	Name     string `json:"name"`
+	Enabled  bool   `json:"enabled"`  // Defaults to true
+	Priority int    `json:"priority"` // Higher wins; equal priorities keep the list order
+}
+
+// UnmarshalJSON accepts "spotify" as well as {"name": "spotify", "priority": 1}
+func (e *SourceEntry) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*e = SourceEntry{Name: name, Enabled: true}
+		return nil
+	}
+	type plain SourceEntry // without this method
+	entry := plain{Enabled: true}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return err
+	}
+	*e = SourceEntry(entry)
+	return nil
+}
+
+// validateSources checks the names, that no source is listed twice and that one is enabled
+func validateSources(entries []SourceEntry) error {
+	if len(entries) > 0 && !slices.ContainsFunc(entries, func(e SourceEntry) bool { return e.Enabled }) {
+		return fmt.Errorf("every entry of sources is disabled")
+	}
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		if _, ok := sourceFactories[entry.Name]; !ok {
+			return fmt.Errorf("unknown source %q in sources", entry.Name)
+		}
+		if seen[entry.Name] {
+			return fmt.Errorf("source %q is listed twice in sources", entry.Name)
+		}
+		seen[entry.Name] = true
+	}
+	return nil
+}
+
+// SourceManager polls the enabled sources, highest priority first
+type SourceManager struct {
+	sources []Source
+	active  Source // last source selected, for logging switches (poll goroutine only)
+}
+
+// NewSourceManager builds the enabled sources, scripting players through scripts
+func NewSourceManager(entries []SourceEntry, scripts ScriptRunner) (*SourceManager, error) {
+	if len(entries) == 0 {
+		entries = defaultSources
+	}
+	if err := validateSources(entries); err != nil {
+		return nil, err
+	}
+
+	enabled := slices.DeleteFunc(slices.Clone(entries), func(e SourceEntry) bool { return !e.Enabled })
+	slices.SortStableFunc(enabled, func(a, b SourceEntry) int { return cmp.Compare(b.Priority, a.Priority) })
+
+	m := &SourceManager{}
+	for _, entry := range enabled {
+		m.sources = append(m.sources, sourceFactories[entry.Name](scripts))
+	}
+	return m, nil
+}
+
+// Sources returns the enabled sources, highest priority first
+func (m *SourceManager) Sources() []Source {
+	return m.sources
+}
+
+// Select returns the highest-priority playing source, else the highest-priority paused
+// one, else one running with nothing loaded. Every source is asked in parallel, so a slow
+// player doesn't hold up the others. A nil source means no player is running.
+func (m *SourceManager) Select(ctx context.Context) (Source, PlayerState, error) {
+	states := make([]PlayerState, len(m.sources))
+	errs := make([]error, len(m.sources))
+	var wg sync.WaitGroup
+	for i, source := range m.sources {
+		wg.Add(1)
+		goSafe("source state", func() {
+			defer wg.Done()
+			states[i], errs[i] = source.State(ctx)
+		})
+	}
+	wg.Wait()
+
+	var paused, stopped Source
+	var firstErr error
+	for i, source := range m.sources {
+		switch {
+		case errs[i] != nil:
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %w", source.Name(), errs[i])
+			}
+		case states[i] == StatePlaying:
+			m.switchTo(source)
+			return source, StatePlaying, nil
+		case states[i] == StatePaused && paused == nil:
+			paused = source
+		case states[i] == StateStopped && stopped == nil:
+			stopped = source
+		}
+	}
+
+	switch {
+	case paused != nil:
+		return paused, StatePaused, nil
+	case stopped != nil:
+		return stopped, StateStopped, nil
+	}
+	return nil, StateNotRunning, firstErr
+}
+
+// switchTo logs when playback moves to another player
+func (m *SourceManager) switchTo(source Source) {
+	if m.active != nil && m.active.Name() != source.Name() {
+		log.Printf("🎛️  Now playing from %s (was %s)", source.Name(), m.active.Name())
+	}
+	m.active = source
+}
+
+// isAppRunning asks System Events whether a process with this name exists
+func isAppRunning(ctx context.Context, scripts ScriptRunner, name string) (bool, error) {
+	script := fmt.Sprintf(`tell application "System Events" to (name of processes) contains %s`, appleScriptString(name))
+	result, err := runAppleScript(ctx, scripts, script)
+	if err != nil {
+		return false, err
+	}
+	return result == "true", nil
+}
+
+// parsePlayerState maps an AppleScript "player state" value
+func parsePlayerState(result string) PlayerState {
+	switch result {
+	case "playing":
+		return StatePlaying
+	case "paused":
+		return StatePaused
+	case "stopped":
+		return StateStopped
+	default:
+		return StateNotRunning
+	}
+}
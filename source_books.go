@@ -0,0 +1,189 @@
+// Apple Books source
+// Books has no scripting dictionary, so audiobooks are read from the system Now Playing
+// info (the MediaRemote framework, through JXA's Objective-C bridge) whenever Books is the
+// app that owns it. The cover comes from an iTunes audiobook search, cached per book.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"am-discord-bridge/discord"
+)
+
+// booksBundleID - Now Playing client identifier of Apple Books
+const booksBundleID = "com.apple.iBooksX"
+
+// booksCacheSize - Books whose cover is remembered
+const booksCacheSize = 200
+
+// AudiobookConfig renders the presence for audiobooks
+type AudiobookConfig struct {
+	Details   string `json:"details"`    // e.g. "{name}"
+	State     string `json:"state"`      // e.g. "by {artist} · {chapter}"
+	LargeText string `json:"large_text"` // e.g. "{name}"
+}
+
+// booksSource reads audiobooks playing in Books
+type booksSource struct {
+	scripts ScriptRunner
+	artwork *ArtworkCache // author + book -> artwork URL ("" for books with no match)
+}
+
+// newBooksSource creates the source with an empty cover cache
+func newBooksSource(scripts ScriptRunner) *booksSource {
+	return &booksSource{scripts, NewArtworkCache(booksCacheSize, 0)}
+}
+
+// Name identifies the source
+func (*booksSource) Name() string {
+	return "Books"
+}
+
+// booksNowPlayingScript returns the system Now Playing item as a JSON object
+const booksNowPlayingScript = `
+	ObjC.import("Foundation");
+	let result = { bundle: "" };
+	try {
+		$.NSBundle.bundleWithPath("/System/Library/PrivateFrameworks/MediaRemote.framework/").load;
+		const request = $.NSClassFromString("MRNowPlayingRequest");
+		const info = request.localNowPlayingItem.nowPlayingInfo;
+		const value = (key) => {
+			const v = info.objectForKey("kMRMediaRemoteNowPlayingInfo" + key);
+			return v.isNil() ? null : v.js;
+		};
+		const rate = value("PlaybackRate") || 0;
+		let elapsed = value("ElapsedTime") || 0;
+		const stamp = info.objectForKey("kMRMediaRemoteNowPlayingInfoTimestamp");
+		if (!stamp.isNil()) { elapsed += rate * -stamp.timeIntervalSinceNow; }
+		result = {
+			bundle: request.localNowPlayingPlayerPath.client.bundleIdentifier.js || "",
+			title: value("Title") || "",
+			artist: value("Artist") || "",
+			album: value("Album") || "",
+			duration: value("Duration") || 0,
+			position: elapsed,
+			rate: rate,
+			chapter: value("ChapterNumber") || 0,
+			chapterCount: value("TotalChapterCount") || 0,
+		};
+	} catch (e) {}
+	JSON.stringify(result);
+`
+
+// booksNowPlaying mirrors the JSON produced by booksNowPlayingScript
+type booksNowPlaying struct {
+	Bundle       string  `json:"bundle"`
+	Title        string  `json:"title"`
+	Artist       string  `json:"artist"`
+	Album        string  `json:"album"`
+	Duration     float64 `json:"duration"`
+	Position     float64 `json:"position"`
+	Rate         float64 `json:"rate"`
+	Chapter      int     `json:"chapter"`
+	ChapterCount int     `json:"chapterCount"`
+}
+
+// nowPlaying reads the Now Playing item, reporting ok only when it belongs to Books
+func (s *booksSource) nowPlaying(ctx context.Context) (info booksNowPlaying, ok bool, err error) {
+	running, err := isAppRunning(ctx, s.scripts, "Books")
+	if err != nil || !running {
+		return info, false, err
+	}
+	if err := runJXA(ctx, s.scripts, booksNowPlayingScript, &info); err != nil {
+		return info, false, err
+	}
+	return info, info.Bundle == booksBundleID && info.Title != "", nil
+}
+
+// State reports playing while Books' Now Playing item is advancing
+func (s *booksSource) State(ctx context.Context) (PlayerState, error) {
+	info, ok, err := s.nowPlaying(ctx)
+	switch {
+	case err != nil || !ok:
+		return StateNotRunning, err
+	case info.Rate > 0:
+		return StatePlaying, nil
+	}
+	return StatePaused, nil
+}
+
+// CurrentTrack maps the audiobook onto a Track: the book is both name and album and the
+// author is the artist. Books reports the chapter title, if any, as the item title.
+func (s *booksSource) CurrentTrack(ctx context.Context) (*Track, error) {
+	info, ok, err := s.nowPlaying(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Books info: %w", err)
+	}
+	if !ok {
+		return nil, errors.New("Books is not playing an audiobook")
+	}
+
+	book, chapter := info.Title, ""
+	if info.Album != "" && info.Album != info.Title {
+		book, chapter = info.Album, info.Title
+	}
+	track := &Track{
+		Name:           book,
+		Artist:         info.Artist,
+		Album:          book,
+		Chapter:        chapter,
+		ChapterNumber:  info.Chapter,
+		ChapterCount:   info.ChapterCount,
+		Audiobook:      true,
+		Duration:       info.Duration,
+		PlayerPosition: info.Position,
+		Source:         "Books",
+	}
+	track.normalize()
+	track.ArtworkURL = s.cover(ctx, track)
+	return track, nil
+}
+
+// cover returns the cached cover for a book, searching iTunes on first sight
+func (s *booksSource) cover(ctx context.Context, track *Track) string {
+	if cached, exists := s.artwork.Get(track.Artist, track.Album); exists {
+		return cached.ArtworkURL
+	}
+
+	log.Printf("🔍 Fetching audiobook artwork for: %s - %s", track.Artist, track.Album)
+	url, err := searchITunesEntity(ctx, track.Artist+" "+track.Album, "audiobook", "audiobook")
+	if err != nil {
+		// Only remember real misses; network errors are retried next poll
+		if errors.Is(err, errNoITunesResults) || errors.Is(err, errNoArtwork) {
+			s.artwork.Set(track.Artist, track.Album, ArtworkResult{})
+		}
+		log.Printf("⚠️  Audiobook artwork fetch failed: %v", err)
+		return ""
+	}
+
+	s.artwork.Set(track.Artist, track.Album, ArtworkResult{ArtworkURL: url})
+	return url
+}
+
+// formatChapter renders "Chapter 3 of 12" from the chapter number and count
+func formatChapter(number, count int) string {
+	switch {
+	case number <= 0:
+		return ""
+	case count < number:
+		return fmt.Sprintf("Chapter %d", number)
+	}
+	return fmt.Sprintf("Chapter %d of %d", number, count)
+}
+
+// audiobookActivity lays out an audiobook with the "Listening to" badge
+func (b *Bridge) audiobookActivity(track *Track, artworkURL string) discord.Activity {
+	audiobook := b.config.Presence.Audiobook
+	return discord.Activity{
+		Type:       discord.ActivityTypeListening,
+		Details:    RenderTemplate(audiobook.Details, track),
+		State:      RenderTemplate(audiobook.State, track),
+		LargeImage: b.largeImage(artworkURL),
+		LargeText:  RenderTemplate(audiobook.LargeText, track),
+		Timestamps: buildTimestamps(track, b.config.Presence.Timestamps, b.clock.Now()),
+	}
+}
@@ -0,0 +1,165 @@
+// Cider source
+// Cider, the third-party Apple Music client, serves its playback state over a local REST
+// API (Settings → Connectivity, port 10767). When Cider isn't running nothing listens
+// there, which reads as not running. Cider hands out catalog IDs and Apple Music artwork,
+// so its tracks need no catalog lookup.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ciderTimeout - Cider answers from the same machine, so anything slower means it is stuck
+const ciderTimeout = 2 * time.Second
+
+// CiderConfig locates Cider's local API
+type CiderConfig struct {
+	Addr  string `json:"addr"`  // host:port of the API, e.g. "localhost:10767"
+	Token string `json:"token"` // App token from Cider's Connectivity settings, if it requires one
+}
+
+// cider is the active configuration, set by ConfigureCider; Reload swaps it under requests
+// already running
+var cider atomic.Pointer[CiderConfig]
+
+// ciderClient talks to Cider directly; its replies must never be cached or proxied
+var ciderClient = &http.Client{Timeout: ciderTimeout, Transport: tracingTransport{http.DefaultTransport}}
+
+// ConfigureCider sets the address and token for subsequent requests
+func ConfigureCider(cfg CiderConfig) {
+	cider.Store(&cfg)
+}
+
+// errCiderNotRunning means nothing answered at cider.addr
+var errCiderNotRunning = errors.New("Cider is not running")
+
+// ciderGet fetches a playback endpoint and decodes the reply into v
+func ciderGet(ctx context.Context, endpoint string, v any) error {
+	cfg := cider.Load()
+	if cfg == nil || cfg.Addr == "" {
+		return errCiderNotRunning
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+cfg.Addr+"/api/v1/playback/"+endpoint, nil)
+	if err != nil {
+		return err
+	}
+	if cfg.Token != "" {
+		req.Header.Set("apptoken", cfg.Token)
+	}
+
+	resp, err := ciderClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return errCiderNotRunning
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("Cider rejected cider.token (status %d)", resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("Cider API status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// ciderNowPlaying is the subset of /now-playing we use
+type ciderNowPlaying struct {
+	Info struct {
+		Name             string            `json:"name"`
+		ArtistName       string            `json:"artistName"`
+		AlbumName        string            `json:"albumName"`
+		ComposerName     string            `json:"composerName"`
+		GenreNames       []string          `json:"genreNames"`
+		ReleaseDate      string            `json:"releaseDate"` // "2016-05-08T12:00:00Z"
+		TrackNumber      int               `json:"trackNumber"`
+		DiscNumber       int               `json:"discNumber"`
+		DurationInMillis float64           `json:"durationInMillis"`
+		PlaybackTime     float64           `json:"currentPlaybackTime"` // seconds
+		Artwork          appleMusicArtwork `json:"artwork"`
+		ContentRating    string            `json:"contentRating"` // "explicit", "clean" or absent
+		PlayParams       struct {
+			ID string `json:"id"`
+		} `json:"playParams"`
+		URL struct {
+			AppleMusic string `json:"appleMusic"`
+		} `json:"url"`
+	} `json:"info"`
+}
+
+// ciderSource reads Cider through its local API
+type ciderSource struct{}
+
+// Name identifies the source
+func (ciderSource) Name() string {
+	return "Cider"
+}
+
+// State reports not running when Cider doesn't answer, and stopped when it has nothing loaded
+func (ciderSource) State(ctx context.Context) (PlayerState, error) {
+	var nowPlaying ciderNowPlaying
+	if err := ciderGet(ctx, "now-playing", &nowPlaying); err != nil {
+		if errors.Is(err, errCiderNotRunning) {
+			return StateNotRunning, nil
+		}
+		return StateNotRunning, err
+	}
+	if nowPlaying.Info.Name == "" {
+		return StateStopped, nil
+	}
+
+	var playing struct {
+		IsPlaying bool `json:"is_playing"`
+	}
+	if err := ciderGet(ctx, "is-playing", &playing); err != nil {
+		return StateNotRunning, err
+	}
+	if playing.IsPlaying {
+		return StatePlaying, nil
+	}
+	return StatePaused, nil
+}
+
+// CurrentTrack maps Cider's now-playing item, a catalog song, onto a Track
+func (ciderSource) CurrentTrack(ctx context.Context) (*Track, error) {
+	var nowPlaying ciderNowPlaying
+	if err := ciderGet(ctx, "now-playing", &nowPlaying); err != nil {
+		return nil, fmt.Errorf("failed to get Cider track info: %w", err)
+	}
+	info := nowPlaying.Info
+
+	track := &Track{
+		Name:           info.Name,
+		Artist:         info.ArtistName,
+		Album:          info.AlbumName,
+		Composer:       info.ComposerName,
+		TrackNumber:    info.TrackNumber,
+		DiscNumber:     info.DiscNumber,
+		Duration:       info.DurationInMillis / 1000,
+		PlayerPosition: info.PlaybackTime,
+		Source:         "Cider",
+		ArtworkURL:     info.Artwork.sized(),
+		AppleMusicURL:  info.URL.AppleMusic,
+		Explicit:       info.ContentRating == "explicit",
+	}
+	if len(info.GenreNames) > 0 {
+		track.Genre = info.GenreNames[0]
+	}
+	if year, _, ok := strings.Cut(info.ReleaseDate, "-"); ok {
+		track.Year, _ = strconv.Atoi(year)
+	}
+	track.StoreID, _ = strconv.ParseInt(info.PlayParams.ID, 10, 64)
+	track.Rated = track.StoreID != 0 // catalog items come with their content rating
+	return track.normalize(), nil
+}
@@ -0,0 +1,247 @@
+// Fake player source
+// `--fake-player "Artist|Album|Track|240"` replaces every real player with a track that
+// plays on a loop, so the pipeline can be exercised without Music.app (or macOS).
+// `--fake-script file` plays a timeline instead, one step per line:
+//
+//	# seconds  action  [Artist|Album|Track|seconds]
+//	0          play    Radiohead|OK Computer|Airbag|284
+//	20         pause
+//	30         play
+//	45         play    Massive Attack|Mezzanine|Teardrop|330
+//	60         stop
+//	70         quit
+//
+// play without a track resumes the current one; stop rewinds it and quit leaves the
+// player not running. The last step holds until the bridge exits.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fakeSourceName - Source name registered for --fake-player
+const fakeSourceName = "fake"
+
+// fakeStep is one step of the timeline: from at on, the player is in state, with track
+// replacing the current one if set
+type fakeStep struct {
+	at    time.Duration
+	state PlayerState
+	track *Track
+}
+
+// fakeActions maps script actions to the state they leave the player in
+var fakeActions = map[string]PlayerState{
+	"play":  StatePlaying,
+	"pause": StatePaused,
+	"stop":  StateStopped,
+	"quit":  StateNotRunning,
+}
+
+// fakeSource plays a timeline of steps, measured from when it was created
+type fakeSource struct {
+	steps   []fakeStep
+	started time.Time
+}
+
+// newFakeSource parses "Artist|Album|Track|seconds" into a track that plays on a loop;
+// a duration of 0 plays as a stream
+func newFakeSource(spec string) (*fakeSource, error) {
+	track, err := parseFakeTrack(spec)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeSource{steps: []fakeStep{{state: StatePlaying, track: track}}, started: time.Now()}, nil
+}
+
+// loadFakeScript reads a timeline from a script file
+func loadFakeScript(path string) (*fakeSource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return parseFakeScript(path, file)
+}
+
+// parseFakeScript reads a timeline; name prefixes the line numbers of errors
+func parseFakeScript(name string, r io.Reader) (*fakeSource, error) {
+	var steps []fakeStep
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		step, err := parseFakeStep(text)
+		if err == nil && len(steps) > 0 && step.at < steps[len(steps)-1].at {
+			err = errors.New("steps must be in time order")
+		}
+		if err == nil && step.track == nil && (step.state == StatePlaying || step.state == StatePaused) && !loadsTrack(steps) {
+			err = errors.New("nothing is loaded yet; the first play needs a track")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, line, err)
+		}
+		steps = append(steps, step)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("%s has no steps", name)
+	}
+	return &fakeSource{steps: steps, started: time.Now()}, nil
+}
+
+// loadsTrack reports whether any of the steps loads a track
+func loadsTrack(steps []fakeStep) bool {
+	for _, step := range steps {
+		if step.track != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// parseFakeStep parses "seconds action [track]"
+func parseFakeStep(text string) (fakeStep, error) {
+	fields := strings.Fields(text)
+	if len(fields) < 2 {
+		return fakeStep{}, fmt.Errorf("expected \"seconds action [Artist|Album|Track|seconds]\", got %q", text)
+	}
+	seconds, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil || seconds < 0 {
+		return fakeStep{}, fmt.Errorf("invalid step time %q", fields[0])
+	}
+	state, ok := fakeActions[fields[1]]
+	if !ok {
+		return fakeStep{}, fmt.Errorf("unknown action %q (play, pause, stop or quit)", fields[1])
+	}
+
+	step := fakeStep{at: time.Duration(seconds * float64(time.Second)), state: state}
+	rest := strings.TrimSpace(text[len(fields[0]):])
+	if spec := strings.TrimSpace(rest[len(fields[1]):]); spec != "" {
+		if state != StatePlaying {
+			return fakeStep{}, fmt.Errorf("only play takes a track")
+		}
+		if step.track, err = parseFakeTrack(spec); err != nil {
+			return fakeStep{}, err
+		}
+	}
+	return step, nil
+}
+
+// parseFakeTrack parses "Artist|Album|Track|seconds"
+func parseFakeTrack(spec string) (*Track, error) {
+	parts := strings.Split(spec, "|")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("fake player must be \"Artist|Album|Track|seconds\", got %q", spec)
+	}
+	duration, err := strconv.ParseFloat(strings.TrimSpace(parts[3]), 64)
+	if err != nil || duration < 0 {
+		return nil, fmt.Errorf("invalid fake player duration %q", parts[3])
+	}
+
+	return &Track{
+		Artist:   strings.TrimSpace(parts[0]),
+		Album:    strings.TrimSpace(parts[1]),
+		Name:     strings.TrimSpace(parts[2]),
+		Duration: duration,
+		Source:   "Fake Player",
+	}, nil
+}
+
+// useFakePlayer reads only the fake player, keeping its plays out of scrobbles, history
+// and hooks
+func (c *Config) useFakePlayer() {
+	c.Sources = []SourceEntry{{Name: fakeSourceName, Enabled: true}}
+	c.ListenBrainz = ListenBrainzConfig{}
+	c.History.Enabled = false
+	c.Hooks = HookConfig{}
+	c.Slack = SlackConfig{}
+	c.MQTT.Broker = ""
+}
+
+// Length is when the last step starts
+func (f *fakeSource) Length() time.Duration {
+	return f.steps[len(f.steps)-1].at
+}
+
+// at replays the timeline up to elapsed, returning the state then and the loaded track
+// with its position. A track that reaches its end starts over.
+func (f *fakeSource) at(elapsed time.Duration) (PlayerState, *Track) {
+	state := StateNotRunning
+	var track *Track
+	var position, since time.Duration // playback before the current step, and when it began
+	for _, step := range f.steps {
+		if step.at > elapsed {
+			break
+		}
+		if state == StatePlaying {
+			position += step.at - since
+		}
+		if step.track != nil {
+			track, position = step.track, 0
+		}
+		if step.state == StateStopped {
+			position = 0
+		}
+		state, since = step.state, step.at
+	}
+	if track == nil {
+		return state, nil
+	}
+	if state == StatePlaying {
+		position += elapsed - since
+	}
+
+	current := *track
+	current.PlayerPosition = position.Seconds()
+	if current.Duration > 0 {
+		current.PlayerPosition = math.Mod(current.PlayerPosition, current.Duration)
+	}
+	return state, &current
+}
+
+// Name identifies the source
+func (*fakeSource) Name() string {
+	return "Fake Player"
+}
+
+// State follows the timeline
+func (f *fakeSource) State(context.Context) (PlayerState, error) {
+	state, _ := f.at(time.Since(f.started))
+	return state, nil
+}
+
+// NextTrack returns the track the timeline loads next, if any
+func (f *fakeSource) NextTrack(context.Context) (*Track, error) {
+	elapsed := time.Since(f.started)
+	for _, step := range f.steps {
+		if step.at > elapsed && step.track != nil {
+			next := *step.track
+			return next.normalize(), nil
+		}
+	}
+	return nil, nil
+}
+
+// CurrentTrack returns the loaded track with a position that advances in real time
+func (f *fakeSource) CurrentTrack(context.Context) (*Track, error) {
+	_, track := f.at(time.Since(f.started))
+	if track == nil {
+		return nil, errors.New("the fake player has nothing loaded")
+	}
+	return track.normalize(), nil
+}
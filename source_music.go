@@ -0,0 +1,233 @@
+// Apple Music source
+// Reads the macOS Music app through AppleScript. The scripting dictionary has no store ID,
+// so the current track's comes from the system Now Playing info, as for Books.
+
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// catalogCloudStates are the "cloud status" values of tracks backed by the Apple Music catalog
+var catalogCloudStates = map[string]bool{
+	"subscription": true,
+	"purchased":    true,
+	"matched":      true,
+	"prerelease":   true,
+}
+
+// musicSource reads the Music app
+type musicSource struct {
+	scripts ScriptRunner
+}
+
+// Name identifies the source
+func (musicSource) Name() string {
+	return "Apple Music"
+}
+
+// State checks if Music app is running and its playback state
+func (s musicSource) State(ctx context.Context) (PlayerState, error) {
+	// Check if Music app is running
+	running, err := isAppRunning(ctx, s.scripts, "Music")
+	if err != nil || !running {
+		return StateNotRunning, err
+	}
+
+	// Get player state
+	result, err := runAppleScript(ctx, s.scripts, `tell application "Music" to player state as string`)
+	if err != nil {
+		return StateNotRunning, err
+	}
+	return parsePlayerState(result), nil
+}
+
+// musicDescribeScript defines describe(music, track), which reads a track into the object
+// musicTrackInfo mirrors. JXA keeps names containing delimiters or newlines intact, unlike
+// concatenated AppleScript output.
+const musicDescribeScript = `
+function describe(music, track) {
+	let cloud = "";
+	try { cloud = String(track.cloudStatus()); } catch (e) {}
+	let playlist = "";
+	try { playlist = music.currentPlaylist.name(); } catch (e) {}
+	let streamTitle = "";
+	try { streamTitle = music.currentStreamTitle() || ""; } catch (e) {}
+	let duration = 0;
+	try { duration = track.duration() || 0; } catch (e) {}
+	let genre = "";
+	let year = 0;
+	try { genre = track.genre() || ""; year = track.year() || 0; } catch (e) {}
+	let albumArtist = "", compilation = false;
+	try { albumArtist = track.albumArtist() || ""; compilation = track.compilation(); } catch (e) {}
+	let trackNumber = 0, trackCount = 0, discNumber = 0, discCount = 0;
+	try {
+		trackNumber = track.trackNumber() || 0; trackCount = track.trackCount() || 0;
+		discNumber = track.discNumber() || 0; discCount = track.discCount() || 0;
+	} catch (e) {}
+	let loved = false;
+	// Music 1.3+ renamed "loved" to "favorited"
+	try { loved = track.favorited(); } catch (e) { try { loved = track.loved(); } catch (e) {} }
+	let persistentID = "";
+	try { persistentID = track.persistentID() || ""; } catch (e) {}
+	let composer = "", work = "", movement = "";
+	try { composer = track.composer() || ""; work = track.work() || ""; movement = track.movement() || ""; } catch (e) {}
+	return {
+		name: track.name(),
+		artist: track.artist() || "",
+		album: track.album() || "",
+		albumArtist: albumArtist,
+		compilation: compilation,
+		trackNumber: trackNumber,
+		trackCount: trackCount,
+		discNumber: discNumber,
+		discCount: discCount,
+		loved: loved,
+		duration: duration,
+		position: music.playerPosition() || 0,
+		cloud: cloud,
+		playlist: playlist,
+		streamTitle: streamTitle,
+		genre: genre,
+		year: year,
+		composer: composer,
+		work: work,
+		movement: movement,
+		persistentID: persistentID,
+	};
+}
+`
+
+// musicTrackScript returns the current track as a JSON object
+const musicTrackScript = musicDescribeScript + `
+	const music = Application("Music");
+	let shuffle = false, repeat = "off";
+	try { shuffle = music.shuffleEnabled(); repeat = String(music.songRepeat()); } catch (e) {}
+	let outputs = [];
+	try {
+		outputs = music.currentAirPlayDevices().filter(d => String(d.kind()) !== "computer").map(d => d.name());
+	} catch (e) {}
+	const track = describe(music, music.currentTrack);
+	// Music publishes the catalog ID of what it plays to Now Playing; checking the bundle
+	// and title keeps another player's item, or a stale one, from being used
+	let storeID = 0;
+	try {
+		ObjC.import("Foundation");
+		$.NSBundle.bundleWithPath("/System/Library/PrivateFrameworks/MediaRemote.framework/").load;
+		const request = $.NSClassFromString("MRNowPlayingRequest");
+		const info = request.localNowPlayingItem.nowPlayingInfo;
+		const title = info.objectForKey("kMRMediaRemoteNowPlayingInfoTitle");
+		const id = info.objectForKey("kMRMediaRemoteNowPlayingInfoiTunesStoreIdentifier");
+		if (request.localNowPlayingPlayerPath.client.bundleIdentifier.js === "com.apple.Music" &&
+			!title.isNil() && title.js === track.name && !id.isNil()) {
+			storeID = Number(id.js) || 0;
+		}
+	} catch (e) {}
+	JSON.stringify(Object.assign(track, {storeID: storeID, shuffle: shuffle, repeat: repeat, outputs: outputs}));
+`
+
+// musicNextTrackScript returns the track after the current one in the current playlist as
+// a JSON object, or {} when the order isn't known (shuffle) or the playlist ends there.
+// Songs added with "Play Next" aren't visible to scripts, so the playlist order is used.
+const musicNextTrackScript = musicDescribeScript + `
+	const music = Application("Music");
+	let next = null;
+	try {
+		if (!music.shuffleEnabled()) {
+			const tracks = music.currentPlaylist.tracks;
+			const index = music.currentTrack.index(); // 1-based, so tracks[index] is the next one
+			if (index < tracks.length) {
+				next = tracks[index];
+			} else if (music.songRepeat() === "all") {
+				next = tracks[0];
+			}
+		}
+	} catch (e) {}
+	JSON.stringify(next ? Object.assign(describe(music, next), {position: 0, streamTitle: ""}) : {});
+`
+
+// musicTrackInfo mirrors the JSON produced by musicTrackScript
+type musicTrackInfo struct {
+	Name         string   `json:"name"`
+	Artist       string   `json:"artist"`
+	Album        string   `json:"album"`
+	AlbumArtist  string   `json:"albumArtist"`
+	Compilation  bool     `json:"compilation"`
+	TrackNumber  int      `json:"trackNumber"`
+	TrackCount   int      `json:"trackCount"`
+	DiscNumber   int      `json:"discNumber"`
+	DiscCount    int      `json:"discCount"`
+	Loved        bool     `json:"loved"`
+	Duration     float64  `json:"duration"`
+	Position     float64  `json:"position"`
+	Cloud        string   `json:"cloud"`
+	Playlist     string   `json:"playlist"`
+	StreamTitle  string   `json:"streamTitle"`
+	Genre        string   `json:"genre"`
+	Year         int      `json:"year"`
+	Composer     string   `json:"composer"`
+	Work         string   `json:"work"`
+	Movement     string   `json:"movement"`
+	PersistentID string   `json:"persistentID"`
+	StoreID      int64    `json:"storeID"` // current track only, 0 for local files
+	Shuffle      bool     `json:"shuffle"`
+	Repeat       string   `json:"repeat"`  // "off", "one" or "all"
+	Outputs      []string `json:"outputs"` // AirPlay devices other than the Mac
+}
+
+// CurrentTrack extracts metadata from the currently playing track
+func (s musicSource) CurrentTrack(ctx context.Context) (*Track, error) {
+	var info musicTrackInfo
+	if err := runJXA(ctx, s.scripts, musicTrackScript, &info); err != nil {
+		return nil, fmt.Errorf("failed to get track info: %w", err)
+	}
+	return info.track(), nil
+}
+
+// NextTrack returns the track queued after the current one, or nil when it isn't known
+func (s musicSource) NextTrack(ctx context.Context) (*Track, error) {
+	var info musicTrackInfo
+	if err := runJXA(ctx, s.scripts, musicNextTrackScript, &info); err != nil {
+		return nil, fmt.Errorf("failed to get next track: %w", err)
+	}
+	if info.Name == "" {
+		return nil, nil
+	}
+	return info.track(), nil
+}
+
+// track converts the script output into a Track
+func (info musicTrackInfo) track() *Track {
+	track := &Track{
+		Name:           info.Name,
+		Artist:         info.Artist,
+		Album:          info.Album,
+		AlbumArtist:    info.AlbumArtist,
+		Compilation:    info.Compilation,
+		TrackNumber:    info.TrackNumber,
+		TrackCount:     info.TrackCount,
+		DiscNumber:     info.DiscNumber,
+		DiscCount:      info.DiscCount,
+		Loved:          info.Loved,
+		Duration:       info.Duration,
+		PlayerPosition: info.Position,
+		Source:         "Apple Music",
+		Playlist:       info.Playlist,
+		StreamTitle:    info.StreamTitle,
+		Local:          !catalogCloudStates[info.Cloud],
+		Genre:          info.Genre,
+		Year:           info.Year,
+		Composer:       info.Composer,
+		Work:           info.Work,
+		Movement:       info.Movement,
+		PersistentID:   info.PersistentID,
+		StoreID:        info.StoreID,
+		Shuffle:        info.Shuffle,
+		Outputs:        info.Outputs,
+	}
+	if info.Repeat == RepeatOne || info.Repeat == RepeatAll {
+		track.Repeat = info.Repeat
+	}
+	return track.normalize()
+}
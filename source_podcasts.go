@@ -0,0 +1,109 @@
+// Apple Podcasts source
+// Reads the Podcasts app through JXA. Podcasts has no artwork URL of its own, so the
+// show cover is looked up with an iTunes podcast search and cached per show.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// podcastCacheSize - Shows whose cover is remembered; few people follow more
+const podcastCacheSize = 500
+
+// podcastsSource reads the Podcasts app
+type podcastsSource struct {
+	scripts ScriptRunner
+	artwork *ArtworkCache // show -> artwork URL ("" for shows with no match)
+}
+
+// newPodcastsSource creates the source with an empty show cover cache
+func newPodcastsSource(scripts ScriptRunner) *podcastsSource {
+	return &podcastsSource{scripts, NewArtworkCache(podcastCacheSize, 0)}
+}
+
+// Name identifies the source
+func (*podcastsSource) Name() string {
+	return "Podcasts"
+}
+
+// State checks if Podcasts is running and its playback state
+func (p *podcastsSource) State(ctx context.Context) (PlayerState, error) {
+	running, err := isAppRunning(ctx, p.scripts, "Podcasts")
+	if err != nil || !running {
+		return StateNotRunning, err
+	}
+
+	result, err := runAppleScript(ctx, p.scripts, `tell application "Podcasts" to player state as string`)
+	if err != nil {
+		return StateNotRunning, err
+	}
+	return parsePlayerState(result), nil
+}
+
+// podcastsEpisodeScript returns the current episode as a JSON object
+const podcastsEpisodeScript = `
+	const podcasts = Application("Podcasts");
+	const episode = podcasts.currentEpisode;
+	let duration = 0;
+	try { duration = episode.duration(); } catch (e) {}
+	JSON.stringify({
+		title: episode.name(),
+		show: episode.podcast.name(),
+		duration: duration,
+		position: podcasts.playerPosition(),
+	});
+`
+
+// podcastsEpisodeInfo mirrors the JSON produced by podcastsEpisodeScript
+type podcastsEpisodeInfo struct {
+	Title    string  `json:"title"`
+	Show     string  `json:"show"`
+	Duration float64 `json:"duration"`
+	Position float64 `json:"position"`
+}
+
+// CurrentTrack maps the playing episode onto a Track: the episode title is the name
+// and the show stands in for both artist and album
+func (p *podcastsSource) CurrentTrack(ctx context.Context) (*Track, error) {
+	var info podcastsEpisodeInfo
+	if err := runJXA(ctx, p.scripts, podcastsEpisodeScript, &info); err != nil {
+		return nil, fmt.Errorf("failed to get Podcasts episode info: %w", err)
+	}
+
+	track := &Track{
+		Name:           info.Title,
+		Artist:         info.Show,
+		Album:          info.Show,
+		Duration:       info.Duration,
+		PlayerPosition: info.Position,
+		Source:         "Podcasts",
+	}
+	track.normalize()
+	track.ArtworkURL = p.showArtwork(ctx, track.Artist)
+	return track, nil
+}
+
+// showArtwork returns the cached cover for a show, searching iTunes on first sight
+func (p *podcastsSource) showArtwork(ctx context.Context, show string) string {
+	if cached, exists := p.artwork.Get(show, ""); exists {
+		return cached.ArtworkURL
+	}
+
+	log.Printf("🔍 Fetching podcast artwork for: %s", show)
+	url, err := searchITunesEntity(ctx, show, "podcast", "podcast")
+	if err != nil {
+		// Only remember real misses; network errors are retried next poll
+		if errors.Is(err, errNoITunesResults) {
+			p.artwork.Set(show, "", ArtworkResult{})
+		}
+		log.Printf("⚠️  Podcast artwork fetch failed: %v", err)
+		return ""
+	}
+
+	p.artwork.Set(show, "", ArtworkResult{ArtworkURL: url})
+	return url
+}
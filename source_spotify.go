@@ -0,0 +1,94 @@
+// Spotify source
+// Reads the Spotify desktop app through its AppleScript dictionary. Spotify reports
+// duration in milliseconds and provides its own artwork URL, so no iTunes lookup is needed.
+
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// spotifySource reads the Spotify desktop app
+type spotifySource struct {
+	scripts ScriptRunner
+}
+
+// Name identifies the source
+func (spotifySource) Name() string {
+	return "Spotify"
+}
+
+// State checks if Spotify is running and its playback state
+func (s spotifySource) State(ctx context.Context) (PlayerState, error) {
+	running, err := isAppRunning(ctx, s.scripts, "Spotify")
+	if err != nil || !running {
+		return StateNotRunning, err
+	}
+
+	result, err := runAppleScript(ctx, s.scripts, `tell application "Spotify" to player state as string`)
+	if err != nil {
+		return StateNotRunning, err
+	}
+	return parsePlayerState(result), nil
+}
+
+// spotifyTrackScript returns the current track as a JSON object
+const spotifyTrackScript = `
+	const spotify = Application("Spotify");
+	const track = spotify.currentTrack;
+	JSON.stringify({
+		name: track.name(),
+		artist: track.artist(),
+		album: track.album(),
+		albumArtist: track.albumArtist() || "",
+		trackNumber: track.trackNumber() || 0,
+		discNumber: track.discNumber() || 0,
+		duration: track.duration(),
+		position: spotify.playerPosition(),
+		artwork: track.artworkUrl(),
+		shuffle: spotify.shuffling(),
+		repeat: spotify.repeating(),
+	});
+`
+
+// spotifyTrackInfo mirrors the JSON produced by spotifyTrackScript
+type spotifyTrackInfo struct {
+	Name        string  `json:"name"`
+	Artist      string  `json:"artist"`
+	Album       string  `json:"album"`
+	AlbumArtist string  `json:"albumArtist"`
+	TrackNumber int     `json:"trackNumber"`
+	DiscNumber  int     `json:"discNumber"`
+	Duration    float64 `json:"duration"` // milliseconds
+	Position    float64 `json:"position"` // seconds
+	Artwork     string  `json:"artwork"`
+	Shuffle     bool    `json:"shuffle"`
+	Repeat      bool    `json:"repeat"` // Spotify's repeat covers the context, not one track
+}
+
+// CurrentTrack extracts metadata from Spotify's current track
+func (s spotifySource) CurrentTrack(ctx context.Context) (*Track, error) {
+	var info spotifyTrackInfo
+	if err := runJXA(ctx, s.scripts, spotifyTrackScript, &info); err != nil {
+		return nil, fmt.Errorf("failed to get Spotify track info: %w", err)
+	}
+
+	track := &Track{
+		Name:           info.Name,
+		Artist:         info.Artist,
+		Album:          info.Album,
+		AlbumArtist:    info.AlbumArtist,
+		TrackNumber:    info.TrackNumber,
+		DiscNumber:     info.DiscNumber,
+		Duration:       info.Duration / 1000,
+		PlayerPosition: info.Position,
+		Source:         "Spotify",
+		ArtworkURL:     info.Artwork,
+		Shuffle:        info.Shuffle,
+	}
+	if info.Repeat {
+		track.Repeat = RepeatAll
+	}
+	return track.normalize(), nil
+}
@@ -0,0 +1,156 @@
+// Apple TV source
+// Reads the TV app through JXA and publishes a "Watching" presence. Shows and movies have
+// no album to search for, so their cover comes from an iTunes TV season or movie search
+// and is cached per title.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"am-discord-bridge/discord"
+)
+
+// tvCacheSize - Shows and movies whose cover is remembered
+const tvCacheSize = 500
+
+// WatchingConfig renders the presence for shows and movies
+type WatchingConfig struct {
+	Details   string `json:"details"`    // e.g. "{name}"
+	State     string `json:"state"`      // e.g. "{show} · {episode_code}"
+	LargeText string `json:"large_text"` // e.g. "{show}"
+}
+
+// tvSource reads the TV app
+type tvSource struct {
+	scripts ScriptRunner
+	artwork *ArtworkCache // show or movie -> artwork URL ("" for titles with no match)
+}
+
+// newTVSource creates the source with an empty cover cache
+func newTVSource(scripts ScriptRunner) *tvSource {
+	return &tvSource{scripts, NewArtworkCache(tvCacheSize, 0)}
+}
+
+// Name identifies the source
+func (*tvSource) Name() string {
+	return "TV"
+}
+
+// State checks if the TV app is running and its playback state
+func (s *tvSource) State(ctx context.Context) (PlayerState, error) {
+	running, err := isAppRunning(ctx, s.scripts, "TV")
+	if err != nil || !running {
+		return StateNotRunning, err
+	}
+
+	result, err := runAppleScript(ctx, s.scripts, `tell application "TV" to player state as string`)
+	if err != nil {
+		return StateNotRunning, err
+	}
+	return parsePlayerState(result), nil
+}
+
+// tvTrackScript returns the current video as a JSON object
+const tvTrackScript = `
+	const tv = Application("TV");
+	const track = tv.currentTrack;
+	let show = "", season = 0, episode = 0;
+	try { show = track.show() || ""; season = track.seasonNumber() || 0; episode = track.episodeNumber() || 0; } catch (e) {}
+	let genre = "", year = 0;
+	try { genre = track.genre() || ""; year = track.year() || 0; } catch (e) {}
+	let duration = 0;
+	try { duration = track.duration() || 0; } catch (e) {}
+	JSON.stringify({
+		name: track.name(),
+		artist: track.artist() || "",
+		show: show,
+		season: season,
+		episode: episode,
+		genre: genre,
+		year: year,
+		duration: duration,
+		position: tv.playerPosition() || 0,
+	});
+`
+
+// tvTrackInfo mirrors the JSON produced by tvTrackScript
+type tvTrackInfo struct {
+	Name     string  `json:"name"`
+	Artist   string  `json:"artist"`
+	Show     string  `json:"show"`
+	Season   int     `json:"season"`
+	Episode  int     `json:"episode"`
+	Genre    string  `json:"genre"`
+	Year     int     `json:"year"`
+	Duration float64 `json:"duration"`
+	Position float64 `json:"position"`
+}
+
+// CurrentTrack maps the playing video onto a Track: episodes keep their show in Show
+// (and Album, for album-based templates), movies have neither
+func (s *tvSource) CurrentTrack(ctx context.Context) (*Track, error) {
+	var info tvTrackInfo
+	if err := runJXA(ctx, s.scripts, tvTrackScript, &info); err != nil {
+		return nil, fmt.Errorf("failed to get TV info: %w", err)
+	}
+
+	track := &Track{
+		Name:           info.Name,
+		Artist:         info.Artist,
+		Album:          info.Show,
+		Show:           info.Show,
+		Season:         info.Season,
+		Episode:        info.Episode,
+		Video:          true,
+		Genre:          info.Genre,
+		Year:           info.Year,
+		Duration:       info.Duration,
+		PlayerPosition: info.Position,
+		Source:         "TV",
+	}
+	track.normalize()
+	track.ArtworkURL = s.cover(ctx, track)
+	return track, nil
+}
+
+// cover returns the cached poster for a show or movie, searching iTunes on first sight
+func (s *tvSource) cover(ctx context.Context, track *Track) string {
+	title, media, entity, kind := track.Show, "tvShow", "tvSeason", "show"
+	if title == "" {
+		title, media, entity, kind = track.Name, "movie", "movie", "movie"
+	}
+	if cached, exists := s.artwork.Get(title, media); exists {
+		return cached.ArtworkURL
+	}
+
+	log.Printf("🔍 Fetching %s artwork for: %s", kind, title)
+	url, err := searchITunesEntity(ctx, title, media, entity)
+	if err != nil {
+		// Only remember real misses; network errors are retried next poll
+		if errors.Is(err, errNoITunesResults) || errors.Is(err, errNoArtwork) {
+			s.artwork.Set(title, media, ArtworkResult{})
+		}
+		log.Printf("⚠️  TV artwork fetch failed: %v", err)
+		return ""
+	}
+
+	s.artwork.Set(title, media, ArtworkResult{ArtworkURL: url})
+	return url
+}
+
+// watchingActivity lays out a show or movie with the "Watching" badge
+func (b *Bridge) watchingActivity(track *Track, artworkURL string) discord.Activity {
+	watching := b.config.Presence.Watching
+	return discord.Activity{
+		Type:       discord.ActivityTypeWatching,
+		Details:    RenderTemplate(watching.Details, track),
+		State:      RenderTemplate(watching.State, track),
+		LargeImage: b.largeImage(artworkURL),
+		LargeText:  RenderTemplate(watching.LargeText, track),
+		Timestamps: buildTimestamps(track, b.config.Presence.Timestamps, b.clock.Now()),
+	}
+}
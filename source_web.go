@@ -0,0 +1,237 @@
+// Apple Music web player source
+// For listening on music.apple.com instead of the Music app. The companion browser
+// extension (`am-bridge extension`) posts the web player's now-playing state to a
+// localhost endpoint whenever it changes and every few seconds while a tab is open;
+// the "web" source reads the latest report and treats it as gone once reports stop.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// webStaleAfter - A report older than this means the tab was closed or the browser quit
+	webStaleAfter = 15 * time.Second
+
+	// webMaxReport - Largest report body accepted
+	webMaxReport = 64 << 10
+)
+
+// BrowserConfig enables the endpoint the browser extension reports to
+type BrowserConfig struct {
+	Addr string `json:"addr"` // e.g. "127.0.0.1:7880"; empty disables it
+}
+
+// validate accepts only a loopback address: the endpoint takes reports without
+// authentication, so it must not be reachable from other machines
+func (c BrowserConfig) validate() error {
+	if c.Addr == "" {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(c.Addr)
+	if err != nil {
+		return fmt.Errorf("browser.addr %q: %w", c.Addr, err)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("browser.addr %q must be a loopback address such as 127.0.0.1:7880", c.Addr)
+	}
+	return nil
+}
+
+// webReport is what the extension posts to /now-playing
+type webReport struct {
+	Title    string  `json:"title"`
+	Artist   string  `json:"artist"`
+	Album    string  `json:"album"`
+	Artwork  string  `json:"artwork"`
+	Duration float64 `json:"duration"` // seconds
+	Position float64 `json:"position"` // seconds
+	Playing  bool    `json:"playing"`
+}
+
+// webFeed holds the latest report from the extension
+type webFeed struct {
+	mu       sync.Mutex
+	report   *webReport
+	received time.Time
+}
+
+// browserFeed is shared by the endpoint and every web source built on (re)load
+var browserFeed = &webFeed{}
+
+// set replaces the latest report; nil means the player went away
+func (f *webFeed) set(report *webReport) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.report, f.received = report, time.Now()
+}
+
+// latest returns the report, if it is recent, with the position advanced to now
+func (f *webFeed) latest() (webReport, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	age := time.Since(f.received)
+	if f.report == nil || age > webStaleAfter {
+		return webReport{}, false
+	}
+	report := *f.report
+	if report.Playing {
+		report.Position += age.Seconds()
+		if report.Duration > 0 {
+			report.Position = min(report.Position, report.Duration)
+		}
+	}
+	return report, true
+}
+
+// webSource reads the Apple Music web player through browserFeed
+type webSource struct {
+	feed *webFeed
+}
+
+// Name identifies the source
+func (webSource) Name() string {
+	return "Apple Music Web"
+}
+
+// State reports the web player's state, or not running when no tab has reported lately
+func (s webSource) State(context.Context) (PlayerState, error) {
+	report, ok := s.feed.latest()
+	switch {
+	case !ok:
+		return StateNotRunning, nil
+	case report.Playing:
+		return StatePlaying, nil
+	}
+	return StatePaused, nil
+}
+
+// CurrentTrack maps the latest report onto a Track
+func (s webSource) CurrentTrack(context.Context) (*Track, error) {
+	report, ok := s.feed.latest()
+	if !ok {
+		return nil, errors.New("no browser tab is reporting")
+	}
+
+	track := &Track{
+		Name:           report.Title,
+		Artist:         report.Artist,
+		Album:          report.Album,
+		Duration:       report.Duration,
+		PlayerPosition: report.Position,
+		Source:         "Apple Music Web",
+	}
+	if isWebURL(report.Artwork) {
+		track.ArtworkURL = report.Artwork
+	}
+	return track.normalize(), nil
+}
+
+// BrowserServer receives now-playing reports from the browser extension
+type BrowserServer struct {
+	server *http.Server
+	feed   *webFeed
+}
+
+// StartBrowserServer listens on cfg.Addr; it returns nil when the endpoint is disabled
+func StartBrowserServer(cfg BrowserConfig) (*BrowserServer, error) {
+	if cfg.Addr == "" {
+		return nil, nil
+	}
+
+	listener, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start browser endpoint: %w", err)
+	}
+
+	s := &BrowserServer{feed: browserFeed}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /now-playing", s.handleReport)
+	mux.HandleFunc("DELETE /now-playing", s.handleGone)
+	mux.HandleFunc("OPTIONS /now-playing", s.handlePreflight)
+	s.server = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("⚠️  Browser endpoint stopped: %v", err)
+		}
+	}()
+	log.Printf("🌐 Listening for the browser extension on http://%s/now-playing", listener.Addr())
+	return s, nil
+}
+
+// Close stops the endpoint
+func (s *BrowserServer) Close() {
+	if s == nil {
+		return
+	}
+	s.server.Close()
+}
+
+// extensionOrigin reports whether a request comes from a browser extension rather than a
+// web page or a local program. Pages can reach localhost too, but the browser always
+// tells us their origin; requests without one didn't come from a browser at all.
+func extensionOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	return strings.HasPrefix(origin, "chrome-extension://") ||
+		strings.HasPrefix(origin, "moz-extension://") ||
+		strings.HasPrefix(origin, "safari-web-extension://")
+}
+
+// allow rejects requests from web pages, answering CORS for extensions
+func (s *BrowserServer) allow(w http.ResponseWriter, r *http.Request) bool {
+	if !extensionOrigin(r) {
+		http.Error(w, "only the am-bridge extension may report", http.StatusForbidden)
+		return false
+	}
+	w.Header().Set("Access-Control-Allow-Origin", r.Header.Get("Origin"))
+	return true
+}
+
+// handlePreflight answers CORS preflights from extensions
+func (s *BrowserServer) handlePreflight(w http.ResponseWriter, r *http.Request) {
+	if !s.allow(w, r) {
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Methods", "POST, DELETE")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleReport stores a now-playing report
+func (s *BrowserServer) handleReport(w http.ResponseWriter, r *http.Request) {
+	if !s.allow(w, r) {
+		return
+	}
+
+	var report webReport
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, webMaxReport)).Decode(&report); err != nil {
+		http.Error(w, "malformed report", http.StatusBadRequest)
+		return
+	}
+	if report.Title == "" {
+		s.feed.set(nil) // the tab is open but nothing is loaded
+	} else {
+		s.feed.set(&report)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGone forgets the report when the web player tab is closed
+func (s *BrowserServer) handleGone(w http.ResponseWriter, r *http.Request) {
+	if !s.allow(w, r) {
+		return
+	}
+	s.feed.set(nil)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,148 @@
+// Presence templates
+// Details, State and LargeText are rendered from templates such as "{album} · {genre} · {year}".
+// Segments separated by " · " whose placeholders are all empty are dropped, so a track
+// without a genre renders as "Album · 2023" rather than "Album ·  · 2023".
+
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// templateSeparator - Segment separator for dropping empty parts
+const templateSeparator = " · "
+
+// appendSegment adds part to the end of a line of separated segments
+func appendSegment(line, part string) string {
+	if line == "" {
+		return part
+	}
+	return line + templateSeparator + part
+}
+
+// templatePlaceholder matches {field}
+var templatePlaceholder = regexp.MustCompile(`\{(\w+)\}`)
+
+// templateFields lists every placeholder and how to read it from a track
+var templateFields = map[string]func(t *Track) string{
+	"name":           func(t *Track) string { return t.Name },
+	"artist":         func(t *Track) string { return t.Artist },
+	"primary_artist": func(t *Track) string { return primaryArtist(t) },
+	"artists":        func(t *Track) string { return strings.Join(artistList(t), ", ") },
+	"album":          func(t *Track) string { return t.Album },
+	"album_artist": func(t *Track) string {
+		if t.AlbumArtist == "" && t.Compilation {
+			return variousArtists
+		}
+		return t.AlbumArtist
+	},
+	"featured":        func(t *Track) string { return cmp.Or(t.Featured, strings.Join(featuredArtists(t), ", ")) },
+	"genre":           func(t *Track) string { return t.Genre },
+	"composer":        func(t *Track) string { return t.Composer },
+	"work":            func(t *Track) string { return cmp.Or(t.Work, t.Name) },
+	"movement":        func(t *Track) string { return t.Movement },
+	"year":            func(t *Track) string { return formatYear(t.Year) },
+	"playlist":        func(t *Track) string { return t.Playlist },
+	"apple_music_url": func(t *Track) string { return t.AppleMusicURL },
+	"song_link":       func(t *Track) string { return t.SongLink },
+	"loved": func(t *Track) string {
+		if t.Loved {
+			return lovedMark
+		}
+		return ""
+	},
+	"shuffle": func(t *Track) string {
+		if t.Shuffle {
+			return shuffleMark
+		}
+		return ""
+	},
+	"repeat":       func(t *Track) string { return t.repeatMark() },
+	"source":       func(t *Track) string { return t.Source },
+	"output":       func(t *Track) string { return strings.Join(t.Outputs, ", ") },
+	"track_number": func(t *Track) string { return formatCount(t.TrackNumber) },
+	"track_count":  func(t *Track) string { return formatCount(t.TrackCount) },
+	"disc_number":  func(t *Track) string { return formatCount(t.DiscNumber) },
+	"disc_count":   func(t *Track) string { return formatCount(t.DiscCount) },
+	"origin":       func(t *Track) string { return t.Origin() },
+	"show":         func(t *Track) string { return t.Show },
+	"season":       func(t *Track) string { return formatCount(t.Season) },
+	"episode":      func(t *Track) string { return formatCount(t.Episode) },
+	"episode_code": func(t *Track) string { return formatEpisode(t.Season, t.Episode) },
+	"chapter": func(t *Track) string {
+		return cmp.Or(t.Chapter, formatChapter(t.ChapterNumber, t.ChapterCount))
+	},
+	"chapter_number": func(t *Track) string { return formatCount(t.ChapterNumber) },
+	"chapter_count":  func(t *Track) string { return formatCount(t.ChapterCount) },
+}
+
+// RenderTemplate fills a template's placeholders from the track
+func RenderTemplate(tmpl string, track *Track) string {
+	var rendered []string
+	for segment := range strings.SplitSeq(tmpl, templateSeparator) {
+		if text := renderSegment(segment, track); text != "" {
+			rendered = append(rendered, text)
+		}
+	}
+	return strings.Join(rendered, templateSeparator)
+}
+
+// renderSegment substitutes placeholders; a segment whose placeholders are all empty renders as ""
+func renderSegment(segment string, track *Track) string {
+	placeholders, filled := 0, 0
+	text := templatePlaceholder.ReplaceAllStringFunc(segment, func(match string) string {
+		field, ok := templateFields[match[1:len(match)-1]]
+		if !ok {
+			return match
+		}
+		placeholders++
+		value := field(track)
+		if value != "" {
+			filled++
+		}
+		return value
+	})
+
+	if placeholders > 0 && filled == 0 {
+		return ""
+	}
+	return strings.TrimSpace(text)
+}
+
+// validateTemplate rejects unknown placeholders
+func validateTemplate(tmpl string) error {
+	for _, match := range templatePlaceholder.FindAllStringSubmatch(tmpl, -1) {
+		if _, ok := templateFields[match[1]]; !ok {
+			return fmt.Errorf("unknown placeholder {%s} in %q", match[1], tmpl)
+		}
+	}
+	return nil
+}
+
+// formatYear renders a release year, leaving unknown years empty
+func formatYear(year int) string {
+	return formatCount(year)
+}
+
+// formatCount renders a track or disc number, leaving unknown (zero) ones empty
+func formatCount(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// formatEpisode renders "S2E5", "E5" without a season, or "" without an episode number
+func formatEpisode(season, episode int) string {
+	switch {
+	case episode <= 0:
+		return ""
+	case season <= 0:
+		return fmt.Sprintf("E%d", episode)
+	}
+	return fmt.Sprintf("S%dE%d", season, episode)
+}
@@ -0,0 +1,52 @@
+package main
+
+import "testing"
+
+func TestRenderTemplate(t *testing.T) {
+	full := &Track{Name: "Airbag", Artist: "Radiohead", Album: "OK Computer", Genre: "Alternative", Year: 1997}
+	bare := &Track{Name: "Demo", Artist: "Me"}
+
+	tests := []struct {
+		name  string
+		tmpl  string
+		track *Track
+		want  string
+	}{
+		{"all filled", "{album} · {genre} · {year}", full, "OK Computer · Alternative · 1997"},
+		{"empty middle dropped", "{album} · {genre} · {year}", &Track{Album: "Kid A", Year: 2000}, "Kid A · 2000"},
+		{"empty first dropped", "{genre} · {album}", &Track{Album: "Kid A"}, "Kid A"},
+		{"every segment empty", "{genre} · {year}", bare, ""},
+		{"literal text kept", "by {artist} · Track {track_number}", bare, "by Me"},
+		{"segment without placeholders", "{genre} · Now playing", bare, "Now playing"},
+		{"partly filled segment kept", "{genre}{year} · x", &Track{Year: 1997}, "1997 · x"},
+		{"zero counts are empty", "Disc {disc_number} of {disc_count}", bare, ""},
+		{"spaces trimmed", "  {name}  ", bare, "Demo"},
+		{"unknown placeholder left", "{nope} · {name}", bare, "{nope} · Demo"},
+		{"unspaced dot is one segment", "{album}·{genre}", &Track{Album: "Kid A"}, "Kid A·"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderTemplate(tt.tmpl, tt.track); got != tt.want {
+				t.Errorf("RenderTemplate(%q) = %q, want %q", tt.tmpl, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppendSegment(t *testing.T) {
+	if got := appendSegment("", "A"); got != "A" {
+		t.Errorf("appendSegment to an empty line = %q", got)
+	}
+	if got := appendSegment("A", "B"); got != "A · B" {
+		t.Errorf("appendSegment = %q, want A · B", got)
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	if err := validateTemplate("{name} · {primary_artist} · {episode_code}"); err != nil {
+		t.Errorf("valid template rejected: %v", err)
+	}
+	if err := validateTemplate("{name} · {albm}"); err == nil {
+		t.Error("unknown placeholder {albm} accepted")
+	}
+}
@@ -0,0 +1,154 @@
+// Tracing
+// `am-bridge run --trace` logs what the bridge does under the hood, for bug reports: every
+// AppleScript call with its output, a summary of every HTTP request and response, and
+// every frame exchanged with Discord. Configured credentials, token-like URL parameters
+// and webhook paths come out as [redacted]. A token bucket caps the rate so a tight
+// loop can't flood the log; lines over the cap are counted and the count is logged
+// once tracing resumes.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// traceRate - Trace lines per second allowed on average
+	traceRate = 20
+
+	// traceBurst - Trace lines allowed at once before the rate applies
+	traceBurst = 100
+
+	// traceMaxValue - Bytes of one script, output or payload logged before it is cut
+	traceMaxValue = 4000
+
+	// traceRedacted - Stands in for a secret
+	traceRedacted = "[redacted]"
+)
+
+var (
+	// traceQuerySecret matches URL parameters that carry credentials
+	traceQuerySecret = regexp.MustCompile(`(?i)([?&](?:api_?key|key|token|access_token|secret|password|sig|auth)=)[^&\s"]+`)
+	// traceWebhookPath matches the secret part of webhook URLs (Discord, Slack, Home Assistant)
+	traceWebhookPath = regexp.MustCompile(`(/(?:webhooks?|services)/)[^\s"?]+`)
+	// traceBearer matches bearer tokens in headers that end up in error messages
+	traceBearer = regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/=-]+`)
+)
+
+// tracer rate-limits and redacts trace lines
+type tracer struct {
+	mu      sync.Mutex
+	tokens  float64
+	last    time.Time
+	dropped int
+	secrets []string // configured credentials, replaced wherever they appear
+}
+
+// activeTracer is set by EnableTrace; nil means tracing is off
+var activeTracer *tracer
+
+// EnableTrace turns tracing on; call before any goroutine that traces starts
+func EnableTrace() {
+	activeTracer = &tracer{tokens: traceBurst, last: time.Now()}
+	log.Printf("🔬 Tracing on (at most %d lines a second, credentials redacted)", traceRate)
+}
+
+// ConfigureTrace records the configured credentials so they are redacted from traces
+func ConfigureTrace(config *Config) {
+	t := activeTracer
+	if t == nil {
+		return
+	}
+	var secrets []string
+	for _, slot := range secretSlots {
+		if secret := *slot.field(config); len(secret) >= 4 {
+			secrets = append(secrets, secret)
+		}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.secrets = secrets
+}
+
+// tracef logs a trace line when tracing is on and the rate allows
+func tracef(format string, args ...any) {
+	t := activeTracer
+	if t == nil {
+		return
+	}
+	line := fmt.Sprintf(format, args...)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	t.tokens = min(traceBurst, t.tokens+now.Sub(t.last).Seconds()*traceRate)
+	t.last = now
+	if t.tokens < 1 {
+		t.dropped++
+		return
+	}
+	t.tokens--
+	if t.dropped > 0 {
+		log.Printf("🔬 (%d trace lines dropped over the rate limit)", t.dropped)
+		t.dropped = 0
+	}
+	log.Printf("🔬 %s", t.redact(line))
+}
+
+// redact hides credentials in a line; call with t.mu held
+func (t *tracer) redact(line string) string {
+	for _, secret := range t.secrets {
+		line = strings.ReplaceAll(line, secret, traceRedacted)
+	}
+	line = traceQuerySecret.ReplaceAllString(line, "${1}"+traceRedacted)
+	line = traceWebhookPath.ReplaceAllString(line, "${1}"+traceRedacted)
+	return traceBearer.ReplaceAllString(line, "${1}"+traceRedacted)
+}
+
+// traceValue cuts a long script, output or payload down to traceMaxValue bytes
+func traceValue(value string) string {
+	if len(value) <= traceMaxValue {
+		return value
+	}
+	return strings.ToValidUTF8(value[:traceMaxValue], "") + fmt.Sprintf("… (%d bytes)", len(value))
+}
+
+// traceScript logs an osascript call and what it returned
+func traceScript(language, script, output string, err error, took time.Duration) {
+	if activeTracer == nil {
+		return
+	}
+	if err != nil {
+		tracef("osascript (%s, %v) failed: %v\n%s", language, took.Round(time.Millisecond), err, traceValue(script))
+		return
+	}
+	tracef("osascript (%s, %v) returned %q\n%s", language, took.Round(time.Millisecond), traceValue(output), traceValue(script))
+}
+
+// tracingTransport logs a summary of each request sent through it
+type tracingTransport struct {
+	next http.RoundTripper
+}
+
+// RoundTrip sends the request and traces its outcome
+func (t tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if activeTracer == nil {
+		return t.next.RoundTrip(req)
+	}
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	took := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		tracef("HTTP %s %s failed after %v: %v", req.Method, req.URL, took, err)
+		return resp, err
+	}
+	tracef("HTTP %s %s → %s (%s, %d bytes) in %v", req.Method, req.URL, resp.Status,
+		resp.Header.Get("Content-Type"), resp.ContentLength, took)
+	return resp, err
+}
@@ -0,0 +1,232 @@
+// Self-update
+// `am-bridge update` replaces the running binary with the latest GitHub release for this
+// architecture after verifying it against the release's checksums.txt. Homebrew installs
+// are left to `brew upgrade` so the Cellar stays consistent.
+
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	releasesURL    = "https://api.github.com/repos/ahammednibras8/applemusicdiscord/releases/latest"
+	checksumsAsset = "checksums.txt"
+	updateTimeout  = 5 * time.Minute
+)
+
+// githubRelease is the subset of the GitHub release API response we use
+type githubRelease struct {
+	TagName string `json:"tag_name"`
+	HTMLURL string `json:"html_url"`
+	Assets  []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// assetURL returns the download URL of the named asset
+func (r *githubRelease) assetURL(name string) (string, bool) {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset.URL, true
+		}
+	}
+	return "", false
+}
+
+// updateClient allows for slow downloads of the full binary
+var updateClient = &http.Client{Timeout: updateTimeout}
+
+// cmdUpdate checks for and installs a newer release
+func cmdUpdate(args []string) error {
+	fs := flag.NewFlagSet("update", flag.ExitOnError)
+	checkOnly := fs.Bool("check", false, "only report whether an update is available")
+	force := fs.Bool("force", false, "reinstall even if already up to date")
+	fs.Parse(args)
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+	if strings.Contains(exe, "/Cellar/") {
+		return fmt.Errorf("installed with Homebrew; run 'brew upgrade am-bridge' instead")
+	}
+
+	release, err := latestRelease()
+	if err != nil {
+		return err
+	}
+
+	newer := compareVersions(release.TagName, version) > 0
+	if !newer && !*force {
+		fmt.Printf("am-bridge %s is up to date (latest %s)\n", version, release.TagName)
+		return nil
+	}
+	if *checkOnly {
+		fmt.Printf("Update available: %s → %s (%s)\n", version, release.TagName, release.HTMLURL)
+		return nil
+	}
+
+	asset := fmt.Sprintf("am-bridge_%s_%s", runtime.GOOS, runtime.GOARCH)
+	binaryURL, ok := release.assetURL(asset)
+	if !ok {
+		return fmt.Errorf("release %s has no %s binary", release.TagName, asset)
+	}
+	checksumsURL, ok := release.assetURL(checksumsAsset)
+	if !ok {
+		return fmt.Errorf("release %s has no %s; refusing to install unverified binary", release.TagName, checksumsAsset)
+	}
+
+	want, err := expectedChecksum(checksumsURL, asset)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Downloading %s %s...\n", asset, release.TagName)
+	if err := replaceExecutable(exe, binaryURL, want); err != nil {
+		return err
+	}
+	fmt.Printf("Updated %s to %s; restart the daemon with 'am-bridge run --replace'\n", exe, release.TagName)
+	return nil
+}
+
+// latestRelease fetches the newest published release
+func latestRelease() (*githubRelease, error) {
+	req, err := http.NewRequest(http.MethodGet, releasesURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for updates: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to check for updates: status %d", resp.StatusCode)
+	}
+
+	var release githubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, err
+	}
+	return &release, nil
+}
+
+// expectedChecksum finds the SHA-256 for asset in a "<hex>  <name>" checksums file
+func expectedChecksum(checksumsURL, asset string) (string, error) {
+	resp, err := updateClient.Get(checksumsURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to download checksums: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download checksums: status %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == asset {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no checksum listed for %s", asset)
+}
+
+// replaceExecutable downloads next to exe, verifies the checksum and renames over exe,
+// so the swap is atomic and a failed download never leaves a broken binary
+func replaceExecutable(exe, binaryURL, wantSum string) error {
+	resp, err := updateClient.Get(binaryURL)
+	if err != nil {
+		return fmt.Errorf("download failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download failed: status %d", resp.StatusCode)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".am-bridge-update-*")
+	if err != nil {
+		return fmt.Errorf("cannot write to %s: %w", filepath.Dir(exe), err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hash), resp.Body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("download failed: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if got := hex.EncodeToString(hash.Sum(nil)); got != wantSum {
+		return fmt.Errorf("checksum mismatch: got %s, want %s", got, wantSum)
+	}
+	if err := os.Chmod(tmp.Name(), 0o755); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), exe)
+}
+
+// compareVersions compares "v1.2.3"-style versions numerically; unparseable versions such
+// as "dev" sort before everything
+func compareVersions(a, b string) int {
+	pa, pb := parseVersion(a), parseVersion(b)
+	for i := 0; i < max(len(pa), len(pb)); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// parseVersion splits "v1.2.3-rc1" into [1 2 3], returning nil if it isn't numeric
+func parseVersion(v string) []int {
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+
+	var parts []int
+	for part := range strings.SplitSeq(v, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil
+		}
+		parts = append(parts, n)
+	}
+	return parts
+}
@@ -0,0 +1,127 @@
+// Poll watchdog
+// A poll that never returns (a wedged Apple Event, a deadlock) used to leave the daemon
+// running but silent. The watchdog expects each poll to finish, and the next one to start,
+// within watchdogStall. On a stall it writes a goroutine dump to ~/.cache/am-bridge/stall.txt
+// and re-executes the daemon. A second poll loop in the same process would share the
+// bridge's poll-goroutine state with the stuck one should it ever return.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// watchdogStall - How far past its deadline the poll loop may fall before it counts as stalled
+	watchdogStall = 2 * time.Minute
+
+	// watchdogCheck - How often the watchdog looks at the loop
+	watchdogCheck = 15 * time.Second
+)
+
+// Watchdog tracks the poll loop's progress. Times use the monotonic clock, which stands
+// still while the Mac sleeps, so sleeping never looks like a stall.
+type Watchdog struct {
+	mu       sync.Mutex
+	busy     bool      // a poll (or reload) is running
+	deadline time.Time // when the running poll should have finished, or the next one started
+}
+
+// newWatchdog starts watching the poll loop, which is due to poll within watchdogStall
+func newWatchdog(stop <-chan struct{}) *Watchdog {
+	w := &Watchdog{deadline: time.Now().Add(watchdogStall)}
+	go w.watch(stop)
+	return w
+}
+
+// Begin marks the start of a poll
+func (w *Watchdog) Begin() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.busy = true
+	w.deadline = time.Now().Add(watchdogStall)
+}
+
+// End marks the end of a poll; next is when the loop will poll again
+func (w *Watchdog) End(next time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.busy = false
+	w.deadline = next.Add(watchdogStall)
+}
+
+// watch checks the deadline until stop is closed
+func (w *Watchdog) watch(stop <-chan struct{}) {
+	ticker := time.NewTicker(watchdogCheck)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.check()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// check restarts the daemon once the loop misses its deadline
+func (w *Watchdog) check() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if time.Now().Before(w.deadline) {
+		return
+	}
+	what := "waiting for the next poll"
+	if w.busy {
+		what = "inside a poll"
+	}
+	log.Printf("🐕 Poll loop stalled %s (%v past its deadline)", what, time.Since(w.deadline).Round(time.Second))
+	if path, err := writeStallDump(); err != nil {
+		log.Printf("⚠️  Failed to write goroutine dump: %v", err)
+	} else {
+		log.Printf("🐕 Goroutine dump written to %s", path)
+	}
+
+	restartDaemon("Poll loop stalled")
+}
+
+// writeStallDump saves every goroutine's stack for the bug report
+func writeStallDump() (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "stall.txt")
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	fmt.Fprintf(file, "am-bridge %s stalled at %s\n\n", version, time.Now().Format(time.RFC3339))
+	if err := pprof.Lookup("goroutine").WriteTo(file, 2); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// restartDaemon replaces the process with a fresh copy of itself. The pidfile lock and
+// sockets are close-on-exec, so the new instance starts as if launched anew; if exec
+// fails, exiting lets launchd's KeepAlive start it again.
+func restartDaemon(reason string) {
+	log.Printf("🔁 %s, restarting am-bridge", reason)
+	executable, err := os.Executable()
+	if err == nil {
+		err = syscall.Exec(executable, os.Args, os.Environ())
+	}
+	log.Printf("❌ Restart failed, exiting: %v", err)
+	os.Exit(1)
+}
@@ -0,0 +1,224 @@
+// Setup wizard
+// `am-bridge config init` asks a few first-run questions and writes the config file with
+// just the answers; everything else keeps its default. It finishes by talking to System
+// Events and the configured players so macOS asks for Automation permission right away
+// rather than the first time the daemon polls.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// cmdConfig dispatches the config subcommands
+func cmdConfig(args []string) error {
+	if len(args) == 0 || args[0] != "init" {
+		return errors.New("usage: am-bridge config init [-config path] [-force]")
+	}
+	return cmdConfigInit(args[1:])
+}
+
+// cmdConfigInit runs the interactive setup and writes the config file
+func cmdConfigInit(args []string) error {
+	fs := flag.NewFlagSet("config init", flag.ExitOnError)
+	configPath := fs.String("config", DefaultConfigPath(), "path to the JSON config file")
+	force := fs.Bool("force", false, "replace an existing config without asking")
+	fs.Parse(args)
+
+	p := prompter{bufio.NewReader(os.Stdin)}
+	if _, err := os.Stat(*configPath); err == nil && !*force {
+		replace, err := p.confirm(fmt.Sprintf("%s already exists. Replace it?", *configPath), false)
+		if err != nil {
+			return err
+		}
+		if !replace {
+			fmt.Println("Kept the existing config")
+			return nil
+		}
+	}
+
+	fmt.Println("Setting up am-bridge. Press Enter to keep the value in brackets.")
+	fmt.Println()
+	defaults := DefaultConfig()
+	file := map[string]any{}
+
+	for {
+		appID, err := p.ask("Discord application ID (empty for the built-in one)", "")
+		if err != nil {
+			return err
+		}
+		if appID == "" {
+			break
+		}
+		if _, err := strconv.ParseUint(appID, 10, 64); err == nil {
+			file["discord"] = map[string]any{"app_id": appID}
+			break
+		}
+		fmt.Println("  That isn't an application ID; copy the number from General Information in the Discord developer portal")
+	}
+
+	poll, err := p.number("Seconds between player checks", defaults.PollSeconds, 1)
+	if err != nil {
+		return err
+	}
+	file["poll_seconds"] = poll
+
+	grace, err := p.number("Minutes a paused track stays on your profile (0 clears at once)",
+		defaults.Presence.PauseGraceMinutes, 0)
+	if err != nil {
+		return err
+	}
+	file["presence"] = map[string]any{"pause_grace_minutes": grace}
+
+	scrobble, err := p.confirm("Scrobble to ListenBrainz?", false)
+	if err != nil {
+		return err
+	}
+	if scrobble {
+		if err := askListenBrainzToken(p, file); err != nil {
+			return err
+		}
+	}
+
+	if err := writeWizardConfig(*configPath, file); err != nil {
+		return err
+	}
+	fmt.Printf("\nWrote %s\n\n", *configPath)
+
+	// osascript triggers the Automation prompts; the config is already saved if it fails
+	config, err := LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Checking Automation permission (macOS may ask you to allow access):")
+	checks := checkOSAScript()
+	if checks[len(checks)-1].fix == "" {
+		checks = append(checks, checkSources(config)...)
+	}
+	if printChecks(checks) > 0 {
+		fmt.Println("\nFix the items above, then run 'am-bridge doctor' to check again.")
+		return nil
+	}
+	fmt.Println("\nAll set. Start the bridge with 'am-bridge run'.")
+	return nil
+}
+
+// askListenBrainzToken stores the user token in the Keychain when available, otherwise
+// in the config file
+func askListenBrainzToken(p prompter, file map[string]any) error {
+	fmt.Println("  Your token is at https://listenbrainz.org/settings/")
+	if _, err := exec.LookPath("security"); err == nil {
+		useKeychain, err := p.confirm("Keep the token in the Keychain instead of the config file?", true)
+		if err != nil {
+			return err
+		}
+		if useKeychain {
+			fmt.Println("  Enter your ListenBrainz user token.")
+			return keychainPrompt("listenbrainz", "ListenBrainz user token")
+		}
+	}
+
+	token, err := p.ask("ListenBrainz user token", "")
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		file["listenbrainz"] = map[string]any{"token": token}
+	}
+	return nil
+}
+
+// writeWizardConfig checks the answers against the full config and writes them. The file
+// may hold a token, so it is only readable by the user.
+func writeWizardConfig(path string, file map[string]any) error {
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	check := DefaultConfig()
+	if err := json.Unmarshal(data, check); err != nil {
+		return err
+	}
+	if err := check.Validate(); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
+
+// prompter reads answers from the terminal
+type prompter struct {
+	in *bufio.Reader
+}
+
+// ask prints the question and returns the trimmed answer, or def for an empty line
+func (p prompter) ask(question, def string) (string, error) {
+	if def != "" {
+		fmt.Printf("%s [%s]: ", question, def)
+	} else {
+		fmt.Printf("%s: ", question)
+	}
+	line, err := p.in.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		return "", fmt.Errorf("setup cancelled: %w", err)
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, nil
+	}
+	return def, nil
+}
+
+// confirm asks a yes/no question
+func (p prompter) confirm(question string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	for {
+		answer, err := p.ask(question, hint)
+		if err != nil {
+			return false, err
+		}
+		if answer == hint {
+			return def, nil // Enter keeps the default
+		}
+		switch strings.ToLower(answer) {
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		fmt.Println("  Please answer y or n")
+	}
+}
+
+// number asks for a number of at least minimum
+func (p prompter) number(question string, def, minimum float64) (float64, error) {
+	for {
+		answer, err := p.ask(question, strconv.FormatFloat(def, 'f', -1, 64))
+		if err != nil {
+			return 0, err
+		}
+		value, err := strconv.ParseFloat(answer, 64)
+		if err == nil && value >= minimum {
+			return value, nil
+		}
+		fmt.Printf("  Please enter a number of at least %v\n", minimum)
+	}
+}